@@ -1,20 +1,37 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"log/slog"
+	"os"
 
-	"lang-portal/internal/handlers"
-	"lang-portal/internal/middleware"
+	"lang-portal/internal/config"
+	"lang-portal/internal/logging"
+	"lang-portal/internal/notify"
 	"lang-portal/internal/service"
 
-	"github.com/gin-gonic/gin"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
-	// Initialize database
-	db, err := sql.Open("sqlite3", "words.db")
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
+	// Initialize structured logging
+	logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatal("Failed to configure logging:", err)
+	}
+	slog.SetDefault(logger)
+
+	// Initialize database. SQLite only enforces foreign keys when asked to,
+	// per connection, so the pragma is set through the DSN.
+	db, err := sql.Open("sqlite3", "words.db?_foreign_keys=on")
 	if err != nil {
 		log.Fatal("Failed to open database:", err)
 	}
@@ -24,70 +41,41 @@ func main() {
 		log.Fatal("Failed to ping database:", err)
 	}
 
-	// Initialize services
-	studyService := service.NewStudyService(db)
-	wordService := service.NewWordService(db)
-	groupService := service.NewGroupService(db)
-
-	// Initialize handlers
-	dashboardHandler := handlers.NewDashboardHandler(studyService)
-	wordHandler := handlers.NewWordHandler(wordService)
-	groupHandler := handlers.NewGroupHandler(groupService)
-	studyHandler := handlers.NewStudyHandler(studyService)
-
-	// Initialize Gin
-	r := gin.New()
-
-	// Global middleware
-	r.Use(middleware.Recovery())
-	r.Use(middleware.Logger())
-	r.Use(middleware.CORS())
-	r.Use(middleware.ErrorHandler())
-
-	// API routes will be grouped under /api
-	api := r.Group("/api")
+	// Purge expired items from the trash in the background. An interval of
+	// zero disables purging.
+	if cfg.TrashPurgeInterval > 0 {
+		purger := service.NewTrashService(db)
+		purger.SetRetention(cfg.TrashRetention)
+		go purger.RunPurger(context.Background(), cfg.TrashPurgeInterval, logger)
+	}
 
-	// Dashboard routes
-	api.GET("/dashboard/last_study_session", dashboardHandler.GetLastStudySession)
-	api.GET("/dashboard/study_progress", dashboardHandler.GetStudyProgress)
-	api.GET("/dashboard/quick-stats", dashboardHandler.GetQuickStats)
+	// Study and word events are streamed to clients through /api/events and
+	// delivered to webhooks in the background. A retry interval of zero
+	// disables webhook delivery.
+	events := service.NewEventBus()
+	if cfg.WebhookRetryInterval > 0 {
+		dispatcher := service.NewWebhookService(db)
+		dispatcher.SetDeliveryPolicy(cfg.WebhookMaxAttempts, cfg.WebhookTimeout)
+		go dispatcher.RunDispatcher(context.Background(), events, cfg.WebhookRetryInterval, logger)
+	}
 
-	// Words routes - with pagination validation
-	api.GET("/words", middleware.ValidatePagination(), wordHandler.GetWords)
-	api.GET("/words/:id", middleware.ValidateID("id"), wordHandler.GetWordByID)
+	// Award achievements as learners review words and complete sessions
+	evaluator := service.NewAchievementService(db, cfg.Achievements)
+	evaluator.SetMasteryConfig(cfg.Mastery)
+	evaluator.SetEventBus(events)
+	go evaluator.RunEvaluator(context.Background(), events, logger)
 
-	// Groups routes - with validation
-	api.GET("/groups", groupHandler.GetGroups)
-	api.GET("/groups/:id", middleware.ValidateID("id"), groupHandler.GetGroupByID)
-	api.POST("/groups",
-		middleware.ValidateContentType("application/json"),
-		groupHandler.CreateGroup,
-	)
-	api.POST("/groups/:id/words",
-		middleware.ValidateID("id"),
-		middleware.ValidateContentType("application/json"),
-		groupHandler.AddWordToGroup,
-	)
-	api.DELETE("/groups/:groupId/words/:wordId",
-		middleware.ValidateID("groupId"),
-		middleware.ValidateID("wordId"),
-		groupHandler.RemoveWordFromGroup,
-	)
+	// Remind learners of goals at risk in the background. An interval of zero
+	// disables reminders.
+	if cfg.ReminderInterval > 0 {
+		reminders := service.NewGoalService(db)
+		reminders.SetChannel(service.ChannelWebhook, notify.NewWebhook(cfg.NotifyTimeout))
+		reminders.SetChannel(service.ChannelEmail, notify.NewEmail(cfg.SMTPAddr, cfg.SMTPFrom))
+		go reminders.RunReminders(context.Background(), cfg.ReminderInterval, logger)
+	}
 
-	// Study routes - with validation
-	api.POST("/study/sessions",
-		middleware.ValidateContentType("application/json"),
-		studyHandler.CreateStudySession,
-	)
-	api.POST("/study/sessions/:id/reviews",
-		middleware.ValidateID("id"),
-		middleware.ValidateContentType("application/json"),
-		studyHandler.AddWordReview,
-	)
-	api.GET("/study/sessions/:id/reviews",
-		middleware.ValidateID("id"),
-		studyHandler.GetSessionReviews,
-	)
+	// Build the router
+	r := newRouter(db, cfg, logger, events)
 
 	// Start the server
 	if err := r.Run(":8081"); err != nil {
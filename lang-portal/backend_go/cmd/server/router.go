@@ -0,0 +1,351 @@
+package main
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"lang-portal/internal/config"
+	"lang-portal/internal/handlers"
+	"lang-portal/internal/llm"
+	"lang-portal/internal/media"
+	"lang-portal/internal/metrics"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/openapi"
+	"lang-portal/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter wires services, handlers and middleware together and registers
+// every route. Routes added here must also be documented in handlers.Operations.
+// Study and word events are published on events.
+func newRouter(db *sql.DB, cfg *config.Config, logger *slog.Logger, events *service.EventBus) *gin.Engine {
+	// Initialize metrics
+	appMetrics := metrics.New(db)
+
+	// Initialize services
+	studyService := service.NewStudyService(db)
+	wordService := service.NewWordService(db)
+	groupService := service.NewGroupService(db)
+	trashService := service.NewTrashService(db)
+	userService := service.NewUserService(db)
+	auditService := service.NewAuditService(db)
+	languageService := service.NewLanguageService(db)
+	tagService := service.NewTagService(db)
+	webhookService := service.NewWebhookService(db)
+	model := newProvider(cfg)
+	sentenceService := service.NewSentenceService(db, model)
+	gradingService := service.NewGradingService(db, studyService, model)
+	textService := service.NewTextService(db, wordService)
+	goalService := service.NewGoalService(db)
+	achievementService := service.NewAchievementService(db, cfg.Achievements)
+	classService := service.NewClassService(db)
+	mediaService := service.NewMediaService(db, media.NewFileStore(cfg.MediaDir))
+	mediaService.SetMaxAudioSize(int64(cfg.MaxAudioSize))
+	mediaService.SetMaxImageSize(int64(cfg.MaxImageSize))
+	trashService.SetRetention(cfg.TrashRetention)
+	studyService.SetMasteryConfig(cfg.Mastery)
+	studyService.SetEventBus(events)
+	wordService.SetMasteryConfig(cfg.Mastery)
+	wordService.SetEventBus(events)
+	studyService.SetObserver(appMetrics)
+	wordService.SetObserver(appMetrics)
+	groupService.SetObserver(appMetrics)
+	trashService.SetObserver(appMetrics)
+	userService.SetObserver(appMetrics)
+	auditService.SetObserver(appMetrics)
+	languageService.SetObserver(appMetrics)
+	tagService.SetObserver(appMetrics)
+	mediaService.SetObserver(appMetrics)
+	webhookService.SetObserver(appMetrics)
+	sentenceService.SetObserver(appMetrics)
+	gradingService.SetObserver(appMetrics)
+	textService.SetObserver(appMetrics)
+	textService.SetMasteryConfig(cfg.Mastery)
+	goalService.SetObserver(appMetrics)
+	achievementService.SetObserver(appMetrics)
+	achievementService.SetMasteryConfig(cfg.Mastery)
+	achievementService.SetEventBus(events)
+	classService.SetObserver(appMetrics)
+	classService.SetMasteryConfig(cfg.Mastery)
+
+	// Initialize handlers
+	dashboardHandler := handlers.NewDashboardHandler(studyService)
+	wordHandler := handlers.NewWordHandler(wordService)
+	groupHandler := handlers.NewGroupHandler(groupService)
+	studyHandler := handlers.NewStudyHandler(studyService)
+	trashHandler := handlers.NewTrashHandler(trashService)
+	userHandler := handlers.NewUserHandler(userService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	languageHandler := handlers.NewLanguageHandler(languageService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	mediaHandler := handlers.NewMediaHandler(mediaService)
+	eventHandler := handlers.NewEventHandler(events)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	sentenceHandler := handlers.NewSentenceHandler(sentenceService)
+	gradingHandler := handlers.NewGradingHandler(gradingService)
+	textHandler := handlers.NewTextHandler(textService)
+	goalHandler := handlers.NewGoalHandler(goalService)
+	achievementHandler := handlers.NewAchievementHandler(achievementService)
+	classHandler := handlers.NewClassHandler(classService, achievementService)
+
+	// Initialize Gin
+	r := gin.New()
+
+	// Global middleware. The error handler wraps Recovery so that panics are
+	// rendered with the same envelope as every other error.
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Logger(logger, cfg.Logger))
+	r.Use(middleware.Metrics(appMetrics))
+	r.Use(middleware.ErrorHandler(cfg.ErrorFormat))
+	r.Use(middleware.Recovery())
+	r.Use(middleware.CORS())
+	r.Use(middleware.Identity(userService))
+	r.NoRoute(middleware.NoRoute())
+
+	// Prometheus metrics
+	r.GET("/metrics", gin.WrapH(appMetrics.Handler()))
+
+	// API routes will be grouped under /api
+	api := r.Group("/api")
+
+	// Permission checks. Reading the vocabulary stays open to anonymous
+	// requests; the roles granting each permission are listed in the service
+	// package.
+	signedIn := middleware.Authenticated()
+	study := middleware.Require(service.PermissionStudy)
+	curate := middleware.Require(service.PermissionCurate)
+	teach := middleware.Require(service.PermissionTeach)
+	administer := middleware.Require(service.PermissionAdminister)
+
+	// API documentation
+	api.GET("/openapi.json", openapi.Handler(handlers.APIInfo, r, handlers.Operations()))
+	api.GET("/docs", openapi.DocsHandler("/api/openapi.json"))
+	api.GET("/errors", handlers.GetErrorCatalog)
+
+	// Dashboard routes
+	api.GET("/dashboard/last_study_session",
+		middleware.Validate[handlers.DashboardQuery](),
+		dashboardHandler.GetLastStudySession,
+	)
+	api.GET("/dashboard/study_progress",
+		middleware.Validate[handlers.DashboardQuery](),
+		dashboardHandler.GetStudyProgress,
+	)
+	api.GET("/dashboard/quick-stats",
+		middleware.Validate[handlers.DashboardQuery](),
+		dashboardHandler.GetQuickStats,
+	)
+
+	// Languages routes
+	api.GET("/languages", languageHandler.GetLanguages)
+	api.POST("/languages",
+		curate,
+		middleware.Validate[handlers.CreateLanguageRequest](),
+		languageHandler.CreateLanguage,
+	)
+
+	// Words routes
+	api.GET("/words", middleware.Validate[handlers.WordListQuery](), wordHandler.GetWords)
+	api.GET("/words/:id", middleware.Validate[handlers.WordIDParams](), wordHandler.GetWordByID)
+	api.POST("/words", curate, middleware.Validate[handlers.CreateWordRequest](), wordHandler.CreateWord)
+	api.PUT("/words/:id", curate, middleware.Validate[handlers.UpdateWordRequest](), wordHandler.UpdateWord)
+	api.DELETE("/words/:id", curate, middleware.Validate[handlers.WordIDParams](), wordHandler.DeleteWord)
+	api.POST("/words/:id/audio", curate, middleware.Validate[handlers.AudioParams](), mediaHandler.UploadAudio)
+	api.GET("/words/:id/audio", middleware.Validate[handlers.AudioParams](), mediaHandler.GetAudio)
+	api.POST("/words/:id/images", curate, middleware.Validate[handlers.ImageParams](), mediaHandler.UploadImage)
+	api.DELETE("/words/:id/images/:imageId",
+		curate,
+		middleware.Validate[handlers.ImageParams](),
+		mediaHandler.DeleteImage,
+	)
+	api.POST("/words/:id/sentences",
+		curate,
+		middleware.Validate[handlers.GenerateSentencesRequest](),
+		sentenceHandler.GenerateSentences,
+	)
+
+	// Media routes
+	api.GET("/media/:hash", middleware.Validate[handlers.MediaParams](), mediaHandler.GetMedia)
+
+	// Tags routes
+	api.GET("/tags", middleware.Validate[handlers.TagSearchQuery](), tagHandler.SearchTags)
+
+	// Groups routes. Changes to a group are further limited to its owner.
+	api.GET("/groups", middleware.Validate[handlers.GroupListQuery](), groupHandler.GetGroups)
+	api.GET("/groups/:id", middleware.Validate[handlers.GroupIDParams](), groupHandler.GetGroupByID)
+	api.POST("/groups", study, middleware.Validate[handlers.CreateGroupRequest](), groupHandler.CreateGroup)
+	api.DELETE("/groups/:id", study, middleware.Validate[handlers.GroupIDParams](), groupHandler.DeleteGroup)
+	api.POST("/groups/:id/words",
+		study,
+		middleware.Validate[handlers.AddWordToGroupRequest](),
+		groupHandler.AddWordToGroup,
+	)
+	api.DELETE("/groups/:id/words/:wordId",
+		study,
+		middleware.Validate[handlers.GroupWordParams](),
+		groupHandler.RemoveWordFromGroup,
+	)
+
+	// Study routes
+	api.POST("/study/sessions",
+		study,
+		middleware.Validate[handlers.CreateStudySessionRequest](),
+		studyHandler.CreateStudySession,
+	)
+	api.POST("/study/sessions/:id/reviews",
+		study,
+		middleware.Validate[handlers.AddWordReviewRequest](),
+		studyHandler.AddWordReview,
+	)
+	api.GET("/study/sessions/:id/reviews",
+		study,
+		middleware.Validate[handlers.SessionIDParams](),
+		studyHandler.GetSessionReviews,
+	)
+	api.POST("/study/sessions/:id/complete",
+		study,
+		middleware.Validate[handlers.SessionIDParams](),
+		studyHandler.CompleteStudySession,
+	)
+	api.GET("/study/sessions/:id/words",
+		study,
+		middleware.Validate[handlers.SessionIDParams](),
+		studyHandler.GetSessionWords,
+	)
+
+	// Texts routes
+	api.GET("/texts", middleware.Validate[handlers.TextListQuery](), textHandler.GetTexts)
+	api.POST("/texts", curate, middleware.Validate[handlers.CreateTextRequest](), textHandler.CreateText)
+	api.GET("/texts/recommendation",
+		middleware.Validate[handlers.TextRecommendationQuery](),
+		textHandler.RecommendText,
+	)
+	api.GET("/texts/:id", middleware.Validate[handlers.TextIDParams](), textHandler.GetText)
+	api.DELETE("/texts/:id", curate, middleware.Validate[handlers.TextIDParams](), textHandler.DeleteText)
+	api.POST("/texts/analyze", middleware.Validate[handlers.AnalyzeTextRequest](), textHandler.AnalyzeText)
+
+	// Grading routes
+	api.POST("/grade", middleware.Validate[handlers.GradeRequest](), gradingHandler.Grade)
+
+	// Goals routes
+	api.GET("/goals", study, goalHandler.GetGoals)
+	api.POST("/goals", study, middleware.Validate[handlers.CreateGoalRequest](), goalHandler.CreateGoal)
+	api.DELETE("/goals/:id", study, middleware.Validate[handlers.GoalIDParams](), goalHandler.DeleteGoal)
+	api.GET("/notifications",
+		study,
+		middleware.Validate[handlers.NotificationListQuery](),
+		goalHandler.GetNotifications,
+	)
+
+	// Achievements routes
+	api.GET("/achievements", study, achievementHandler.GetAchievements)
+	api.GET("/leaderboard", middleware.Validate[handlers.LeaderboardQuery](), achievementHandler.GetLeaderboard)
+
+	// Classes routes. Students may only read the classes they attend.
+	api.GET("/classes", signedIn, classHandler.GetClasses)
+	api.POST("/classes", teach, middleware.Validate[handlers.CreateClassRequest](), classHandler.CreateClass)
+	api.GET("/classes/:id", signedIn, middleware.Validate[handlers.ClassIDParams](), classHandler.GetClass)
+	api.DELETE("/classes/:id", teach, middleware.Validate[handlers.ClassIDParams](), classHandler.DeleteClass)
+	api.POST("/classes/:id/students",
+		teach,
+		middleware.Validate[handlers.AddStudentRequest](),
+		classHandler.AddStudent,
+	)
+	api.DELETE("/classes/:id/students/:userId",
+		teach,
+		middleware.Validate[handlers.ClassStudentParams](),
+		classHandler.RemoveStudent,
+	)
+	api.GET("/classes/:id/assignments",
+		signedIn,
+		middleware.Validate[handlers.ClassIDParams](),
+		classHandler.GetAssignments,
+	)
+	api.POST("/classes/:id/assignments",
+		teach,
+		middleware.Validate[handlers.CreateAssignmentRequest](),
+		classHandler.CreateAssignment,
+	)
+	api.DELETE("/classes/:id/assignments/:assignmentId",
+		teach,
+		middleware.Validate[handlers.AssignmentParams](),
+		classHandler.DeleteAssignment,
+	)
+	api.GET("/classes/:id/reports/students",
+		teach,
+		middleware.Validate[handlers.ClassIDParams](),
+		classHandler.GetStudentReports,
+	)
+	api.GET("/classes/:id/reports/words",
+		teach,
+		middleware.Validate[handlers.WordReportQuery](),
+		classHandler.GetWordReports,
+	)
+	api.GET("/classes/:id/leaderboard",
+		signedIn,
+		middleware.Validate[handlers.ClassLeaderboardQuery](),
+		classHandler.GetLeaderboard,
+	)
+
+	// Events
+	api.GET("/events",
+		middleware.QueryToken(userService),
+		signedIn,
+		middleware.Validate[handlers.EventStreamQuery](),
+		eventHandler.Stream,
+	)
+
+	// Webhook routes
+	api.GET("/webhooks", administer, webhookHandler.GetWebhooks)
+	api.POST("/webhooks",
+		administer,
+		middleware.Validate[handlers.CreateWebhookRequest](),
+		webhookHandler.CreateWebhook,
+	)
+	api.DELETE("/webhooks/:id",
+		administer,
+		middleware.Validate[handlers.WebhookParams](),
+		webhookHandler.DeleteWebhook,
+	)
+	api.GET("/webhooks/:id/deliveries",
+		administer,
+		middleware.Validate[handlers.DeliveryListQuery](),
+		webhookHandler.GetDeliveries,
+	)
+
+	// Trash routes
+	api.GET("/trash", curate, trashHandler.GetTrash)
+	api.POST("/trash/:type/:id/restore",
+		curate,
+		middleware.Validate[handlers.TrashItemParams](),
+		trashHandler.RestoreItem,
+	)
+
+	// User routes
+	api.GET("/users", teach, userHandler.GetUsers)
+	api.GET("/users/me", signedIn, userHandler.GetCurrentUser)
+	api.POST("/users/me/token", signedIn, userHandler.IssueOwnToken)
+	api.POST("/users", administer, middleware.Validate[handlers.CreateUserRequest](), userHandler.CreateUser)
+	api.PUT("/users/:id/role", administer, middleware.Validate[handlers.SetRoleRequest](), userHandler.SetRole)
+	api.POST("/users/:id/token", administer, middleware.Validate[handlers.UserIDParams](), userHandler.IssueToken)
+
+	// Audit routes
+	api.GET("/audit", curate, middleware.Validate[handlers.AuditListQuery](), auditHandler.GetAuditLog)
+	api.POST("/audit/:id/revert",
+		curate,
+		middleware.Validate[handlers.AuditEntryParams](),
+		auditHandler.RevertChange,
+	)
+
+	return r
+}
+
+// newProvider returns the language model client selected by the
+// configuration
+func newProvider(cfg *config.Config) llm.Provider {
+	if cfg.LLMProvider == config.LLMProviderOpenAI {
+		return llm.NewClient(cfg.LLMBaseURL, cfg.LLMModel, cfg.LLMAPIKey, cfg.LLMTimeout)
+	}
+	return llm.Fake{}
+}
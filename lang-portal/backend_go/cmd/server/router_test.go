@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"lang-portal/internal/config"
+	"lang-portal/internal/handlers"
+	"lang-portal/internal/openapi"
+	"lang-portal/internal/service"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTestRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return newRouter(db, cfg, logger, service.NewEventBus())
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	r := setupTestRouter(t)
+
+	missing, stale := openapi.Undocumented(r.Routes(), handlers.Operations())
+	assert.Empty(t, missing, "routes without an entry in handlers.Operations")
+	assert.Empty(t, stale, "entries in handlers.Operations without a registered route")
+}
+
+func TestOpenAPIDocumentIsServed(t *testing.T) {
+	r := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc openapi.Document
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	op := doc.Paths["/api/groups/{id}/words"]["post"]
+	if assert.NotNil(t, op) {
+		assert.Equal(t, "id", op.Parameters[0].Name)
+		assert.NotNil(t, op.RequestBody)
+		assert.Contains(t, op.Responses, "204")
+	}
+	assert.Contains(t, doc.Components["schemas"], "service.Word")
+	assert.Equal(t, []string{"name"}, doc.Components["schemas"]["handlers.CreateGroupRequest"].Required)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/api/openapi.json")
+}
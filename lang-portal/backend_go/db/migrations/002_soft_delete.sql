@@ -0,0 +1,7 @@
+-- Soft deletion: rows with a deleted_at timestamp are in the trash. They are
+-- hidden from the API until restored, and purged after the retention period.
+ALTER TABLE words ADD COLUMN deleted_at DATETIME;
+ALTER TABLE groups ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_words_deleted_at ON words(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_groups_deleted_at ON groups(deleted_at);
@@ -0,0 +1,26 @@
+-- Users identify who performs a change. Requests carry the user id in the
+-- X-User-ID header; requests without it are anonymous.
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Audit log of every write to words and groups. before and after hold JSON
+-- snapshots of the entity; before is null for creations.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entity TEXT NOT NULL CHECK (entity IN ('word', 'group')),
+    entity_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    user_id INTEGER,
+    before TEXT CHECK (before IS NULL OR json_valid(before)),
+    after TEXT NOT NULL CHECK (json_valid(after)),
+    reverts_id INTEGER,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (reverts_id) REFERENCES audit_log(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity, entity_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_log_reverts ON audit_log(reverts_id);
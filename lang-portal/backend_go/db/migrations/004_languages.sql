@@ -0,0 +1,76 @@
+-- Languages taught through the portal. Words and groups belong to exactly one
+-- language; existing rows are moved to Latin.
+CREATE TABLE IF NOT EXISTS languages (
+    code TEXT PRIMARY KEY, -- BCP 47 code, e.g. 'la', 'grc', 'ja'
+    name TEXT NOT NULL,
+    script TEXT NOT NULL, -- ISO 15924 code of the usual script
+    has_reading BOOLEAN NOT NULL DEFAULT 0 -- words carry a reading (e.g. kana)
+);
+
+INSERT OR IGNORE INTO languages (code, name, script, has_reading) VALUES
+    ('la', 'Latin', 'Latn', 0),
+    ('grc', 'Ancient Greek', 'Grek', 0),
+    ('ja', 'Japanese', 'Jpan', 1);
+
+-- Rebuild words with generic term/translation columns, unique per language.
+-- reading holds a pronunciation in a native script (furigana), romanization a
+-- transliteration into Latin script.
+CREATE TABLE words_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    language_code TEXT NOT NULL REFERENCES languages(code),
+    term TEXT NOT NULL,
+    translation TEXT NOT NULL,
+    reading TEXT,
+    romanization TEXT,
+    parts TEXT NOT NULL CHECK (json_valid(parts)),
+    deleted_at DATETIME,
+    UNIQUE (language_code, term)
+);
+
+INSERT INTO words_new (id, language_code, term, translation, parts, deleted_at)
+SELECT id, 'la', latin_word, english_translation, parts, deleted_at FROM words;
+
+DROP TABLE words;
+ALTER TABLE words_new RENAME TO words;
+
+CREATE INDEX IF NOT EXISTS idx_words_deleted_at ON words(deleted_at);
+
+-- Rebuild groups so that names are unique per language
+CREATE TABLE groups_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    language_code TEXT NOT NULL REFERENCES languages(code),
+    name TEXT NOT NULL,
+    deleted_at DATETIME,
+    UNIQUE (language_code, name)
+);
+
+INSERT INTO groups_new (id, language_code, name, deleted_at)
+SELECT id, 'la', name, deleted_at FROM groups;
+
+DROP TABLE groups;
+ALTER TABLE groups_new RENAME TO groups;
+
+CREATE INDEX IF NOT EXISTS idx_groups_deleted_at ON groups(deleted_at);
+
+-- Carry audited word snapshots over to the new field names
+UPDATE audit_log SET
+    before = CASE WHEN before IS NULL THEN NULL ELSE json_remove(json_set(before,
+        '$.language', 'la',
+        '$.term', json_extract(before, '$.latin_word'),
+        '$.translation', json_extract(before, '$.english_translation'),
+        '$.reading', NULL,
+        '$.romanization', NULL
+    ), '$.latin_word', '$.english_translation') END,
+    after = json_remove(json_set(after,
+        '$.language', 'la',
+        '$.term', json_extract(after, '$.latin_word'),
+        '$.translation', json_extract(after, '$.english_translation'),
+        '$.reading', NULL,
+        '$.romanization', NULL
+    ), '$.latin_word', '$.english_translation')
+WHERE entity = 'word';
+
+UPDATE audit_log SET
+    before = CASE WHEN before IS NULL THEN NULL ELSE json_set(before, '$.language', 'la') END,
+    after = json_set(after, '$.language', 'la')
+WHERE entity = 'group';
@@ -0,0 +1,43 @@
+-- A word has one or more senses, each a gloss accepted as its translation.
+-- Senses are ordered by priority, lowest first; words.translation always
+-- holds the gloss of the first one.
+CREATE TABLE IF NOT EXISTS word_senses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    word_id INTEGER NOT NULL,
+    gloss TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    UNIQUE (word_id, gloss)
+);
+
+-- Example sentences using a word, with their translation and the work they
+-- are quoted from
+CREATE TABLE IF NOT EXISTS word_examples (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    word_id INTEGER NOT NULL,
+    sentence TEXT NOT NULL,
+    translation TEXT,
+    source TEXT,
+    position INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_word_senses_word ON word_senses(word_id, priority);
+CREATE INDEX IF NOT EXISTS idx_word_examples_word ON word_examples(word_id, position);
+
+-- Every existing word gets its translation as its only sense
+INSERT INTO word_senses (word_id, gloss, priority)
+SELECT id, translation, 0 FROM words;
+
+-- Audited word snapshots carry the senses and examples, so that reverting a
+-- change restores them
+UPDATE audit_log SET
+    before = CASE WHEN before IS NULL THEN NULL ELSE json_set(before,
+        '$.senses', json_array(json_object('gloss', json_extract(before, '$.translation'), 'priority', 0)),
+        '$.examples', json_array()
+    ) END,
+    after = json_set(after,
+        '$.senses', json_array(json_object('gloss', json_extract(after, '$.translation'), 'priority', 0)),
+        '$.examples', json_array()
+    )
+WHERE entity = 'word';
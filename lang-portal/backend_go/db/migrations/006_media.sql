@@ -0,0 +1,23 @@
+-- Uploaded media files, addressed by the SHA-256 of their content. The hash
+-- is also the file's key in the media store, so identical uploads share one
+-- row and one file.
+CREATE TABLE IF NOT EXISTS media (
+    hash TEXT PRIMARY KEY,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Pronunciation audio of a word, or of one of its example sentences when
+-- example holds the sentence's position. Each has at most one clip.
+CREATE TABLE IF NOT EXISTS word_audio (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    word_id INTEGER NOT NULL,
+    example INTEGER,
+    media_hash TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    FOREIGN KEY (media_hash) REFERENCES media(hash)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_word_audio_target ON word_audio(word_id, IFNULL(example, -1));
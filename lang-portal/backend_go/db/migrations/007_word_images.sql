@@ -0,0 +1,22 @@
+-- Pictures of a word, shown to learners in their upload order. Each image is
+-- stored as several variants resized from the upload, so clients can fetch a
+-- thumbnail without downloading the original.
+CREATE TABLE IF NOT EXISTS word_images (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    word_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_word_images_word ON word_images(word_id);
+
+CREATE TABLE IF NOT EXISTS word_image_variants (
+    image_id INTEGER NOT NULL,
+    name TEXT NOT NULL CHECK (name IN ('original', 'large', 'medium', 'thumb')),
+    media_hash TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    PRIMARY KEY (image_id, name),
+    FOREIGN KEY (image_id) REFERENCES word_images(id) ON DELETE CASCADE,
+    FOREIGN KEY (media_hash) REFERENCES media(hash)
+);
@@ -0,0 +1,45 @@
+-- Free-form tags on words, such as "needs audio" or "Caesar, Book 1". Names
+-- are unique regardless of case.
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL COLLATE NOCASE UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS word_tags (
+    word_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (word_id, tag_id),
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_word_tags_tag ON word_tags(tag_id);
+
+-- Rebuild study_sessions so that a session studies either a group or the
+-- words carrying all of a set of tags, given as a JSON array of names and
+-- optionally limited to one language
+CREATE TABLE study_sessions_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id INTEGER,
+    tags TEXT CHECK (tags IS NULL OR json_valid(tags)),
+    language_code TEXT REFERENCES languages(code),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    study_activity_id INTEGER,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    CHECK ((group_id IS NULL) <> (tags IS NULL))
+);
+
+INSERT INTO study_sessions_new (id, group_id, created_at, study_activity_id)
+SELECT id, group_id, created_at, study_activity_id FROM study_sessions;
+
+DROP TABLE study_sessions;
+ALTER TABLE study_sessions_new RENAME TO study_sessions;
+
+CREATE INDEX IF NOT EXISTS idx_study_sessions_created ON study_sessions(created_at);
+
+-- Audited word snapshots carry the tags, so that reverting a change restores
+-- them
+UPDATE audit_log SET
+    before = CASE WHEN before IS NULL THEN NULL ELSE json_set(before, '$.tags', json_array()) END,
+    after = json_set(after, '$.tags', json_array())
+WHERE entity = 'word';
@@ -0,0 +1,10 @@
+-- Smart groups store a query, as JSON, instead of a list of words in
+-- words_groups. Their members are evaluated when the group is read.
+ALTER TABLE groups ADD COLUMN query TEXT CHECK (query IS NULL OR json_valid(query));
+
+-- Audited group snapshots carry the query, so that reverting a change
+-- restores it
+UPDATE audit_log SET
+    before = CASE WHEN before IS NULL THEN NULL ELSE json_set(before, '$.query', json('null')) END,
+    after = json_set(after, '$.query', json('null'))
+WHERE entity = 'group';
@@ -0,0 +1,6 @@
+-- Study sessions record the learner who started them, so that events and
+-- statistics can be filtered per user, and when they were completed
+ALTER TABLE study_sessions ADD COLUMN user_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE study_sessions ADD COLUMN completed_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_study_sessions_user ON study_sessions(user_id);
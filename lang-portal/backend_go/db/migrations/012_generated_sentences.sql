@@ -0,0 +1,14 @@
+-- Sentences generated by a language model, cached by a hash of the model and
+-- the request, which includes the group's vocabulary: changing the group
+-- invalidates its cached sentences.
+CREATE TABLE IF NOT EXISTS generated_sentences (
+    cache_key TEXT PRIMARY KEY,
+    word_id INTEGER NOT NULL REFERENCES words(id) ON DELETE CASCADE,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    model TEXT NOT NULL,
+    difficulty TEXT NOT NULL,
+    sentences TEXT NOT NULL CHECK (json_valid(sentences)),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_generated_sentences_word ON generated_sentences(word_id);
@@ -0,0 +1,76 @@
+-- Dictionary used to analyse texts. Regular forms are derived from a lemma's
+-- parts like those of words; forms lists the ones that cannot be, such as
+-- those of irregular verbs and pronouns. Lemmas that are not yet words can be
+-- turned into words with their translation and parts.
+CREATE TABLE IF NOT EXISTS lemmas (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    language_code TEXT NOT NULL REFERENCES languages(code),
+    lemma TEXT NOT NULL,
+    translation TEXT NOT NULL,
+    parts TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(parts)),
+    forms TEXT NOT NULL DEFAULT '[]' CHECK (json_valid(forms)),
+    UNIQUE (language_code, lemma)
+);
+
+INSERT OR IGNORE INTO lemmas (language_code, lemma, translation, parts, forms) VALUES
+    ('la', 'esse', 'to be',
+     '{"type": "verb", "irregular": true, "principal_parts": ["sum", "esse", "fui", "futurus"]}',
+     '["es", "est", "sumus", "estis", "sunt", "eram", "eras", "erat", "eramus", "eratis", "erant",
+       "ero", "eris", "erit", "erimus", "eritis", "erunt", "sim", "sis", "sit", "simus", "sitis",
+       "sint", "essem", "esses", "esset", "essemus", "essetis", "essent", "fore", "este", "esto"]'),
+    ('la', 'posse', 'to be able',
+     '{"type": "verb", "irregular": true, "principal_parts": ["possum", "posse", "potui"]}',
+     '["potes", "potest", "possumus", "potestis", "possunt", "poteram", "poteras", "poterat",
+       "poteramus", "poteratis", "poterant", "potero", "poteris", "poterit", "poterimus",
+       "poteritis", "poterunt", "possim", "possis", "possit", "possimus", "possitis", "possint",
+       "possem", "posses", "posset", "possemus", "possetis", "possent"]'),
+    ('la', 'ire', 'to go',
+     '{"type": "verb", "irregular": true, "principal_parts": ["eo", "ire", "ii", "itum"]}',
+     '["imus", "itis", "eunt", "ibam", "ibas", "ibat", "ibamus", "ibatis", "ibant", "ibo", "ibis",
+       "ibit", "ibimus", "ibitis", "ibunt", "eam", "eas", "eat", "eamus", "eatis", "eant", "irem",
+       "ires", "iret", "iremus", "iretis", "irent", "ite", "iens", "euntis", "eundum", "iit", "ierunt"]'),
+    ('la', 'ferre', 'to carry',
+     '{"type": "verb", "irregular": true, "principal_parts": ["fero", "ferre", "tuli", "latus"]}',
+     '["fers", "fert", "ferimus", "fertis", "ferunt", "ferebam", "ferebas", "ferebat", "ferebamus",
+       "ferebatis", "ferebant", "feram", "feres", "feret", "feremus", "feretis", "ferent", "ferrem",
+       "ferres", "ferret", "ferremus", "ferretis", "ferrent", "fer", "ferte", "fertur", "feruntur"]'),
+    ('la', 'velle', 'to want',
+     '{"type": "verb", "irregular": true, "principal_parts": ["volo", "velle", "volui"]}',
+     '["vis", "vult", "volumus", "vultis", "volunt", "volebam", "volebas", "volebat", "volebamus",
+       "volebatis", "volebant", "volam", "voles", "volet", "volemus", "voletis", "volent", "velim",
+       "velis", "velit", "velimus", "velitis", "velint", "vellem", "velles", "vellet", "vellemus",
+       "velletis", "vellent"]'),
+    ('la', 'nolle', 'to be unwilling',
+     '{"type": "verb", "irregular": true, "principal_parts": ["nolo", "nolle", "nolui"]}',
+     '["nolumus", "nolunt", "nolebam", "nolebat", "nolebant", "nolim", "nolit", "nollem", "nollet",
+       "noli", "nolite"]'),
+    ('la', 'ego', 'I', '{"type": "pronoun"}', '["mei", "mihi", "me", "mi"]'),
+    ('la', 'tu', 'you', '{"type": "pronoun"}', '["tui", "tibi", "te"]'),
+    ('la', 'nos', 'we', '{"type": "pronoun"}', '["nostri", "nostrum", "nobis"]'),
+    ('la', 'vos', 'you (plural)', '{"type": "pronoun"}', '["vestri", "vestrum", "vobis"]'),
+    ('la', 'is', 'he, she, it; that', '{"type": "pronoun"}',
+     '["ea", "id", "eius", "ei", "eum", "eam", "eo", "ii", "eae", "eorum", "earum", "eis", "iis",
+       "eos", "eas"]'),
+    ('la', 'hic', 'this', '{"type": "pronoun"}',
+     '["haec", "hoc", "huius", "huic", "hunc", "hanc", "hac", "hi", "hae", "horum", "harum", "his",
+       "hos", "has"]'),
+    ('la', 'ille', 'that', '{"type": "pronoun"}',
+     '["illa", "illud", "illius", "illi", "illum", "illam", "illo", "illae", "illorum", "illarum",
+       "illis", "illos", "illas"]'),
+    ('la', 'qui', 'who, which', '{"type": "pronoun"}',
+     '["quae", "quod", "cuius", "cui", "quem", "quam", "quo", "qua", "quorum", "quarum", "quibus",
+       "quos", "quas"]'),
+    ('la', 'deus', 'god', '{"type": "noun", "declension": 2}', '["di", "dii", "dis", "diis"]'),
+    ('la', 'vir', 'man', '{"type": "noun", "declension": 2}', '[]'),
+    ('la', 'rex', 'king', '{"type": "noun", "declension": 3, "genitive": "regis"}', '[]'),
+    ('la', 'res', 'thing', '{"type": "noun", "declension": 5}', '[]'),
+    ('la', 'et', 'and', '{"type": "conjunction"}', '[]'),
+    ('la', 'sed', 'but', '{"type": "conjunction"}', '[]'),
+    ('la', 'atque', 'and also', '{"type": "conjunction"}', '["ac"]'),
+    ('la', 'non', 'not', '{"type": "adverb"}', '[]'),
+    ('la', 'in', 'in, into', '{"type": "preposition"}', '[]'),
+    ('la', 'ad', 'to, towards', '{"type": "preposition"}', '[]'),
+    ('la', 'cum', 'with; when', '{"type": "preposition"}', '[]'),
+    ('la', 'de', 'about, down from', '{"type": "preposition"}', '[]'),
+    ('la', 'ex', 'out of', '{"type": "preposition"}', '["e"]'),
+    ('la', 'ab', 'from, by', '{"type": "preposition"}', '["a", "abs"]');
@@ -0,0 +1,23 @@
+-- Reading texts. Their tokens are stored with the lemma found when the text
+-- was added and link to the words of the text's language with that term, so
+-- that words added later are picked up.
+CREATE TABLE IF NOT EXISTS texts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    language_code TEXT NOT NULL REFERENCES languages(code),
+    title TEXT NOT NULL,
+    author TEXT,
+    difficulty TEXT NOT NULL CHECK (difficulty IN ('easy', 'medium', 'hard')),
+    body TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS text_tokens (
+    text_id INTEGER NOT NULL REFERENCES texts(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    token TEXT NOT NULL,
+    lemma TEXT NOT NULL,
+    PRIMARY KEY (text_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_text_tokens_lemma ON text_tokens(lemma);
+CREATE INDEX IF NOT EXISTS idx_texts_language ON texts(language_code);
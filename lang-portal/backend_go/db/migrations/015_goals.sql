@@ -0,0 +1,32 @@
+-- Learner goals, one per metric. A goal with a reminder notifies its learner
+-- through the reminder's channel once the time of day remind_at (UTC, HH:MM)
+-- has passed on the last day of a period in which the target is not met.
+CREATE TABLE IF NOT EXISTS goals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    metric TEXT NOT NULL CHECK (metric IN ('reviews_per_day', 'new_words_per_week', 'minutes_per_day')),
+    target INTEGER NOT NULL CHECK (target > 0),
+    reminder_channel TEXT CHECK (reminder_channel IN ('webhook', 'email')),
+    reminder_address TEXT,
+    remind_at TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, metric)
+);
+
+-- Reminders sent for goals, at most one per goal and period
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    goal_id INTEGER NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    period TEXT NOT NULL, -- first day of the goal's period, YYYY-MM-DD
+    channel TEXT NOT NULL,
+    address TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('sent', 'failed')),
+    error TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (goal_id, period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at);
@@ -0,0 +1,33 @@
+-- Classes are taught by a teacher to enrolled students
+CREATE TABLE IF NOT EXISTS classes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    teacher_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (teacher_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS class_students (
+    class_id INTEGER NOT NULL REFERENCES classes(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (class_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_class_students_user ON class_students(user_id);
+
+-- Assignments ask the students of a class to study a group, optionally in a
+-- given study activity, by a due date. A student completes an assignment
+-- with a completed session on the group, started after the assignment was
+-- made, whose share of correct reviews reaches required_accuracy.
+CREATE TABLE IF NOT EXISTS assignments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    class_id INTEGER NOT NULL REFERENCES classes(id) ON DELETE CASCADE,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    study_activity_id INTEGER,
+    due_at DATETIME NOT NULL,
+    required_accuracy REAL NOT NULL DEFAULT 0 CHECK (required_accuracy BETWEEN 0 AND 1),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_assignments_class ON assignments(class_id);
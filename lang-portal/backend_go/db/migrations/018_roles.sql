@@ -0,0 +1,13 @@
+-- Users have a role deciding what they may do: admins manage users and
+-- webhooks, teachers curate the shared vocabulary and teach classes, and
+-- learners study. The first user administers the portal.
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'learner'
+    CHECK (role IN ('admin', 'teacher', 'learner'));
+
+UPDATE users SET role = 'admin' WHERE id = (SELECT MIN(id) FROM users);
+
+-- Groups created by a user belong to them; groups without an owner, such as
+-- seeded ones, are shared vocabulary
+ALTER TABLE groups ADD COLUMN owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_groups_owner ON groups(owner_id);
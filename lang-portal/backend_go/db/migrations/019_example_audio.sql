@@ -0,0 +1,27 @@
+-- Example audio refers to the example sentence's row rather than its
+-- position, so that a clip follows its sentence when the examples are
+-- reordered and is deleted with it. Clips of positions that no longer hold a
+-- sentence are dropped.
+CREATE TABLE word_audio_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    word_id INTEGER NOT NULL,
+    example_id INTEGER,
+    media_hash TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    FOREIGN KEY (example_id) REFERENCES word_examples(id) ON DELETE CASCADE,
+    FOREIGN KEY (media_hash) REFERENCES media(hash)
+);
+
+INSERT INTO word_audio_new (id, word_id, example_id, media_hash, created_at)
+SELECT a.id, a.word_id, e.id, a.media_hash, a.created_at
+FROM word_audio a
+LEFT JOIN word_examples e ON e.id = (
+    SELECT MIN(id) FROM word_examples WHERE word_id = a.word_id AND position = a.example
+)
+WHERE a.example IS NULL OR e.id IS NOT NULL;
+
+DROP TABLE word_audio;
+ALTER TABLE word_audio_new RENAME TO word_audio;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_word_audio_target ON word_audio(word_id, IFNULL(example_id, -1));
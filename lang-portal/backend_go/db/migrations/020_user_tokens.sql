@@ -0,0 +1,6 @@
+-- Requests authenticate with a bearer token issued to their user. Only the
+-- SHA-256 of each token is stored. Users created before tokens have none
+-- until one is issued to them, e.g. with `mage token <id>`.
+ALTER TABLE users ADD COLUMN token_hash TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_token ON users(token_hash);
@@ -0,0 +1,42 @@
+-- Terms and group names only need to be unique among the rows that are not
+-- in the trash, so that a trashed word or group can be recreated before it
+-- is purged. The tables are rebuilt without their UNIQUE constraints, which
+-- cannot be dropped, and partial unique indexes take their place.
+CREATE TABLE words_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    language_code TEXT NOT NULL REFERENCES languages(code),
+    term TEXT NOT NULL,
+    translation TEXT NOT NULL,
+    reading TEXT,
+    romanization TEXT,
+    parts TEXT NOT NULL CHECK (json_valid(parts)),
+    deleted_at DATETIME
+);
+
+INSERT INTO words_new (id, language_code, term, translation, reading, romanization, parts, deleted_at)
+SELECT id, language_code, term, translation, reading, romanization, parts, deleted_at FROM words;
+
+DROP TABLE words;
+ALTER TABLE words_new RENAME TO words;
+
+CREATE INDEX IF NOT EXISTS idx_words_deleted_at ON words(deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_words_active_term ON words(language_code, term) WHERE deleted_at IS NULL;
+
+CREATE TABLE groups_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    language_code TEXT NOT NULL REFERENCES languages(code),
+    name TEXT NOT NULL,
+    deleted_at DATETIME,
+    query TEXT CHECK (query IS NULL OR json_valid(query)),
+    owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL
+);
+
+INSERT INTO groups_new (id, language_code, name, deleted_at, query, owner_id)
+SELECT id, language_code, name, deleted_at, query, owner_id FROM groups;
+
+DROP TABLE groups;
+ALTER TABLE groups_new RENAME TO groups;
+
+CREATE INDEX IF NOT EXISTS idx_groups_deleted_at ON groups(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_groups_owner ON groups(owner_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_active_name ON groups(language_code, name) WHERE deleted_at IS NULL;
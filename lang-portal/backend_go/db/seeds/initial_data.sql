@@ -1,16 +1,29 @@
 -- Insert sample groups
-INSERT INTO groups (name) VALUES
-    ('Basic Latin Vocabulary'),
-    ('Common Verbs'),
-    ('Nouns and Adjectives');
+INSERT INTO groups (language_code, name) VALUES
+    ('la', 'Basic Latin Vocabulary'),
+    ('la', 'Common Verbs'),
+    ('la', 'Nouns and Adjectives');
 
 -- Insert sample words
-INSERT INTO words (latin_word, english_translation, parts) VALUES
-    ('amare', 'to love', '{"type": "verb", "conjugation": 1}'),
-    ('videre', 'to see', '{"type": "verb", "conjugation": 2}'),
-    ('puer', 'boy', '{"type": "noun", "declension": 2}'),
-    ('puella', 'girl', '{"type": "noun", "declension": 1}'),
-    ('bonus', 'good', '{"type": "adjective", "declension": "1st/2nd"}');
+INSERT INTO words (language_code, term, translation, parts) VALUES
+    ('la', 'amare', 'to love', '{"type": "verb", "conjugation": 1}'),
+    ('la', 'videre', 'to see', '{"type": "verb", "conjugation": 2}'),
+    ('la', 'puer', 'boy', '{"type": "noun", "declension": 2}'),
+    ('la', 'puella', 'girl', '{"type": "noun", "declension": 1}'),
+    ('la', 'bonus', 'good', '{"type": "adjective", "declension": "1st/2nd"}');
+
+-- Give each word its translation as its first sense, plus synonyms
+INSERT INTO word_senses (word_id, gloss, priority)
+SELECT id, translation, 0 FROM words;
+
+INSERT INTO word_senses (word_id, gloss, priority) VALUES
+    (1, 'to like', 1),
+    (2, 'to look at', 1),
+    (2, 'to understand', 2);
+
+INSERT INTO word_examples (word_id, sentence, translation, source) VALUES
+    (1, 'Vivamus, mea Lesbia, atque amemus', 'Let us live, my Lesbia, and let us love', 'Catullus 5.1'),
+    (2, 'Veni, vidi, vici', 'I came, I saw, I conquered', 'Suetonius, Divus Iulius 37');
 
 -- Link words to groups
 INSERT INTO words_groups (word_id, group_id) VALUES
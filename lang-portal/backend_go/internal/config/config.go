@@ -0,0 +1,268 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+// defaultAchievements declares the achievements used unless
+// ACHIEVEMENTS_FILE names another file
+//
+//go:embed achievements.yaml
+var defaultAchievements []byte
+
+// Config holds the runtime settings of the server
+type Config struct {
+	Mastery      service.MasteryConfig
+	Achievements service.AchievementRules
+	LogLevel     string
+	LogFormat    string
+	Logger       middleware.LoggerConfig
+
+	ErrorFormat string
+
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	MediaDir     string
+	MaxAudioSize int
+	MaxImageSize int
+
+	WebhookRetryInterval time.Duration
+	WebhookMaxAttempts   int
+	WebhookTimeout       time.Duration
+
+	ReminderInterval time.Duration
+	NotifyTimeout    time.Duration
+	SMTPAddr         string
+	SMTPFrom         string
+
+	LLMProvider string
+	LLMBaseURL  string
+	LLMModel    string
+	LLMAPIKey   string
+	LLMTimeout  time.Duration
+}
+
+// LLM providers
+const (
+	LLMProviderFake   = "fake"
+	LLMProviderOpenAI = "openai"
+)
+
+// Load reads the configuration from the environment, falling back to defaults
+// for any variable that is not set
+func Load() (*Config, error) {
+	cfg := &Config{
+		Mastery:   service.DefaultMasteryConfig(),
+		LogLevel:  "info",
+		LogFormat: "json",
+		Logger:    middleware.DefaultLoggerConfig(),
+
+		ErrorFormat: middleware.ErrorFormatJSON,
+
+		TrashRetention:     service.DefaultTrashRetention,
+		TrashPurgeInterval: time.Hour,
+
+		MediaDir:     "media",
+		MaxAudioSize: service.DefaultMaxAudioSize,
+		MaxImageSize: service.DefaultMaxImageSize,
+
+		WebhookRetryInterval: 10 * time.Second,
+		WebhookMaxAttempts:   service.DefaultWebhookMaxAttempts,
+		WebhookTimeout:       service.DefaultWebhookTimeout,
+
+		ReminderInterval: time.Minute,
+		NotifyTimeout:    10 * time.Second,
+		SMTPAddr:         "localhost:25",
+		SMTPFrom:         "lang-portal@localhost",
+
+		LLMProvider: LLMProviderFake,
+		LLMBaseURL:  "http://localhost:11434/v1",
+		LLMModel:    "llama3.1",
+		LLMTimeout:  time.Minute,
+	}
+
+	if err := envInt("MASTERY_REVIEWING_STREAK", &cfg.Mastery.ReviewingStreak); err != nil {
+		return nil, err
+	}
+	if err := envInt("MASTERY_MASTERED_STREAK", &cfg.Mastery.MasteredStreak); err != nil {
+		return nil, err
+	}
+	if err := envFloat("MASTERY_MASTERED_ACCURACY", &cfg.Mastery.MasteredAccuracy); err != nil {
+		return nil, err
+	}
+	if err := envInt("MASTERY_WINDOW", &cfg.Mastery.Window); err != nil {
+		return nil, err
+	}
+
+	envString("LOG_LEVEL", &cfg.LogLevel)
+	envString("LOG_FORMAT", &cfg.LogFormat)
+	if err := envBool("LOG_BODIES", &cfg.Logger.LogBodies); err != nil {
+		return nil, err
+	}
+	if err := envInt("LOG_MAX_BODY_BYTES", &cfg.Logger.MaxBodyBytes); err != nil {
+		return nil, err
+	}
+	if fields, ok := os.LookupEnv("LOG_REDACT_FIELDS"); ok && fields != "" {
+		cfg.Logger.RedactFields = splitList(fields)
+	}
+
+	envString("ERROR_FORMAT", &cfg.ErrorFormat)
+	if cfg.ErrorFormat != middleware.ErrorFormatJSON && cfg.ErrorFormat != middleware.ErrorFormatProblem {
+		return nil, fmt.Errorf("ERROR_FORMAT must be %q or %q", middleware.ErrorFormatJSON, middleware.ErrorFormatProblem)
+	}
+
+	if err := envDuration("TRASH_RETENTION", &cfg.TrashRetention); err != nil {
+		return nil, err
+	}
+	if err := envDuration("TRASH_PURGE_INTERVAL", &cfg.TrashPurgeInterval); err != nil {
+		return nil, err
+	}
+
+	envString("MEDIA_DIR", &cfg.MediaDir)
+	if err := envInt("MEDIA_MAX_AUDIO_SIZE", &cfg.MaxAudioSize); err != nil {
+		return nil, err
+	}
+	if err := envInt("MEDIA_MAX_IMAGE_SIZE", &cfg.MaxImageSize); err != nil {
+		return nil, err
+	}
+
+	if err := envDuration("WEBHOOK_RETRY_INTERVAL", &cfg.WebhookRetryInterval); err != nil {
+		return nil, err
+	}
+	if err := envInt("WEBHOOK_MAX_ATTEMPTS", &cfg.WebhookMaxAttempts); err != nil {
+		return nil, err
+	}
+	if err := envDuration("WEBHOOK_TIMEOUT", &cfg.WebhookTimeout); err != nil {
+		return nil, err
+	}
+
+	if err := envDuration("REMINDER_INTERVAL", &cfg.ReminderInterval); err != nil {
+		return nil, err
+	}
+	if err := envDuration("NOTIFY_TIMEOUT", &cfg.NotifyTimeout); err != nil {
+		return nil, err
+	}
+	envString("SMTP_ADDR", &cfg.SMTPAddr)
+	envString("SMTP_FROM", &cfg.SMTPFrom)
+
+	achievements := defaultAchievements
+	if path, ok := os.LookupEnv("ACHIEVEMENTS_FILE"); ok && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading ACHIEVEMENTS_FILE: %w", err)
+		}
+		achievements = data
+	}
+	rules, err := service.ParseAchievementRules(achievements)
+	if err != nil {
+		return nil, fmt.Errorf("invalid achievements: %w", err)
+	}
+	cfg.Achievements = rules
+
+	envString("LLM_PROVIDER", &cfg.LLMProvider)
+	if cfg.LLMProvider != LLMProviderFake && cfg.LLMProvider != LLMProviderOpenAI {
+		return nil, fmt.Errorf("LLM_PROVIDER must be %q or %q", LLMProviderFake, LLMProviderOpenAI)
+	}
+	envString("LLM_BASE_URL", &cfg.LLMBaseURL)
+	envString("LLM_MODEL", &cfg.LLMModel)
+	envString("LLM_API_KEY", &cfg.LLMAPIKey)
+	if err := envDuration("LLM_TIMEOUT", &cfg.LLMTimeout); err != nil {
+		return nil, err
+	}
+
+	if cfg.Mastery.MasteredStreak < cfg.Mastery.ReviewingStreak {
+		return nil, fmt.Errorf("MASTERY_MASTERED_STREAK must not be lower than MASTERY_REVIEWING_STREAK")
+	}
+	if cfg.Mastery.MasteredAccuracy < 0 || cfg.Mastery.MasteredAccuracy > 1 {
+		return nil, fmt.Errorf("MASTERY_MASTERED_ACCURACY must be between 0 and 1")
+	}
+	if cfg.WebhookMaxAttempts < 1 {
+		return nil, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be at least 1")
+	}
+
+	return cfg, nil
+}
+
+// envInt overwrites dst with the integer value of the environment variable, if set
+func envInt(name string, dst *int) error {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid value for %s: %q", name, value)
+	}
+	*dst = n
+	return nil
+}
+
+// envFloat overwrites dst with the float value of the environment variable, if set
+func envFloat(name string, dst *float64) error {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %q", name, value)
+	}
+	*dst = f
+	return nil
+}
+
+// envString overwrites dst with the environment variable, if set
+func envString(name string, dst *string) {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		*dst = value
+	}
+}
+
+// envBool overwrites dst with the boolean value of the environment variable, if set
+func envBool(name string, dst *bool) error {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %q", name, value)
+	}
+	*dst = b
+	return nil
+}
+
+// envDuration overwrites dst with the duration value (e.g. "720h") of the
+// environment variable, if set
+func envDuration(name string, dst *time.Duration) error {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return fmt.Errorf("invalid value for %s: %q", name, value)
+	}
+	*dst = d
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
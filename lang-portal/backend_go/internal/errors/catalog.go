@@ -0,0 +1,567 @@
+package errors
+
+import (
+	"net/http"
+	"sort"
+
+	"golang.org/x/text/language"
+)
+
+// Stable error codes. Clients should branch on these rather than on messages,
+// which are localized and may change.
+const (
+	// Generic
+	CodeInternal            = "INTERNAL_ERROR"
+	CodeDatabase            = "DATABASE_ERROR"
+	CodeResourceNotFound    = "RESOURCE_NOT_FOUND"
+	CodeResourceConflict    = "RESOURCE_CONFLICT"
+	CodeConstraintViolation = "CONSTRAINT_VIOLATION"
+	CodeRouteNotFound       = "ROUTE_NOT_FOUND"
+	CodeValidationFailed    = "VALIDATION_FAILED"
+	CodeInvalidContentType  = "INVALID_CONTENT_TYPE"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeForbidden           = "FORBIDDEN"
+
+	// Words
+	CodeWordNotFound      = "WORD_NOT_FOUND"
+	CodeWordAlreadyExists = "WORD_ALREADY_EXISTS"
+	CodePageOutOfRange    = "PAGE_OUT_OF_RANGE"
+
+	// Groups
+	CodeGroupNotFound      = "GROUP_NOT_FOUND"
+	CodeGroupNameTaken     = "GROUP_NAME_TAKEN"
+	CodeWordAlreadyInGroup = "WORD_ALREADY_IN_GROUP"
+	CodeSmartGroupReadOnly = "SMART_GROUP_READ_ONLY"
+
+	// Study
+	CodeSessionNotFound  = "SESSION_NOT_FOUND"
+	CodeSessionCompleted = "SESSION_COMPLETED"
+	CodeNoStudySessions  = "NO_STUDY_SESSIONS"
+	CodeNoStudyProgress  = "NO_STUDY_PROGRESS"
+	CodeNoStatistics     = "NO_STATISTICS"
+	CodeNoSessionReviews = "NO_SESSION_REVIEWS"
+
+	// Trash
+	CodeTrashItemNotFound = "TRASH_ITEM_NOT_FOUND"
+
+	// Languages
+	CodeLanguageNotFound      = "LANGUAGE_NOT_FOUND"
+	CodeLanguageAlreadyExists = "LANGUAGE_ALREADY_EXISTS"
+	CodeLanguageMismatch      = "LANGUAGE_MISMATCH"
+
+	// Media
+	CodeMediaTooLarge          = "MEDIA_TOO_LARGE"
+	CodeUnsupportedMediaFormat = "UNSUPPORTED_MEDIA_FORMAT"
+	CodeAudioNotFound          = "AUDIO_NOT_FOUND"
+	CodeExampleNotFound        = "EXAMPLE_NOT_FOUND"
+	CodeImageTooLarge          = "IMAGE_TOO_LARGE"
+	CodeImageNotFound          = "IMAGE_NOT_FOUND"
+	CodeMediaNotFound          = "MEDIA_NOT_FOUND"
+
+	// Users
+	CodeInvalidToken  = "INVALID_TOKEN"
+	CodeUserNameTaken = "USER_NAME_TAKEN"
+
+	// Audit
+	CodeAuditEntryNotFound   = "AUDIT_ENTRY_NOT_FOUND"
+	CodeAuditAlreadyReverted = "AUDIT_ALREADY_REVERTED"
+	CodeAuditRevertConflict  = "AUDIT_REVERT_CONFLICT"
+
+	// Webhooks
+	CodeWebhookNotFound = "WEBHOOK_NOT_FOUND"
+
+	// Generation
+	CodeGenerationFailed = "GENERATION_FAILED"
+
+	// Texts
+	CodeTextNotFound         = "TEXT_NOT_FOUND"
+	CodeNoTextRecommendation = "NO_TEXT_RECOMMENDATION"
+
+	// Goals
+	CodeGoalNotFound      = "GOAL_NOT_FOUND"
+	CodeGoalAlreadyExists = "GOAL_ALREADY_EXISTS"
+
+	// Classes
+	CodeClassNotFound      = "CLASS_NOT_FOUND"
+	CodeClassNameTaken     = "CLASS_NAME_TAKEN"
+	CodeStudentNotFound    = "STUDENT_NOT_FOUND"
+	CodeStudentNotEnrolled = "STUDENT_NOT_ENROLLED"
+	CodeAssignmentNotFound = "ASSIGNMENT_NOT_FOUND"
+)
+
+// Message is the localized text of a catalog entry
+type Message struct {
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+// Entry describes how an error code is reported
+type Entry struct {
+	Status   int                `json:"status"`
+	Type     string             `json:"type"`
+	Messages map[string]Message `json:"-"`
+}
+
+// defaultLanguage is used when no supported language matches the request
+const defaultLanguage = "en"
+
+// supportedLanguages lists the catalog languages, the default first
+var supportedLanguages = []language.Tag{language.English, language.Spanish}
+
+var languageMatcher = language.NewMatcher(supportedLanguages)
+
+var catalog = map[string]Entry{
+	CodeInternal: {
+		Status: http.StatusInternalServerError,
+		Type:   TypeInternal,
+		Messages: map[string]Message{
+			"en": {"An unexpected error occurred", "An internal server error occurred"},
+			"es": {"Se produjo un error inesperado", "Se produjo un error interno del servidor"},
+		},
+	},
+	CodeDatabase: {
+		Status: http.StatusInternalServerError,
+		Type:   TypeDatabase,
+		Messages: map[string]Message{
+			"en": {"Database operation failed", "The request could not be completed because of a database error"},
+			"es": {"La operación de base de datos falló", "No se pudo completar la solicitud debido a un error de base de datos"},
+		},
+	},
+	CodeResourceNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Resource not found", "The requested resource could not be found"},
+			"es": {"Recurso no encontrado", "No se pudo encontrar el recurso solicitado"},
+		},
+	},
+	CodeResourceConflict: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"Resource already exists", "The request conflicts with an existing resource"},
+			"es": {"El recurso ya existe", "La solicitud entra en conflicto con un recurso existente"},
+		},
+	},
+	CodeConstraintViolation: {
+		Status: http.StatusUnprocessableEntity,
+		Type:   TypeInvalidInput,
+		Messages: map[string]Message{
+			"en": {"Constraint violation", "The request violates a data integrity rule"},
+			"es": {"Violación de restricción", "La solicitud infringe una regla de integridad de los datos"},
+		},
+	},
+	CodeRouteNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Route not found", "No endpoint matches the requested method and path"},
+			"es": {"Ruta no encontrada", "Ningún endpoint coincide con el método y la ruta solicitados"},
+		},
+	},
+	CodeValidationFailed: {
+		Status: http.StatusBadRequest,
+		Type:   TypeValidation,
+		Messages: map[string]Message{
+			"en": {"Validation failed", "One or more fields failed validation"},
+			"es": {"La validación falló", "Uno o más campos no superaron la validación"},
+		},
+	},
+	CodeInvalidContentType: {
+		Status: http.StatusUnsupportedMediaType,
+		Type:   TypeInvalidInput,
+		Messages: map[string]Message{
+			"en": {"Invalid Content-Type", "Request must include correct Content-Type header"},
+			"es": {"Content-Type no válido", "La solicitud debe incluir la cabecera Content-Type correcta"},
+		},
+	},
+	CodeUnauthorized: {
+		Status: http.StatusUnauthorized,
+		Type:   TypeUnauthorized,
+		Messages: map[string]Message{
+			"en": {"Authentication required", "The request requires a valid identity"},
+			"es": {"Se requiere autenticación", "La solicitud requiere una identidad válida"},
+		},
+	},
+	CodeForbidden: {
+		Status: http.StatusForbidden,
+		Type:   TypeForbidden,
+		Messages: map[string]Message{
+			"en": {"Forbidden", "You do not have permission to perform this action"},
+			"es": {"Prohibido", "No tiene permiso para realizar esta acción"},
+		},
+	},
+	CodeWordNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Word not found", "The requested word does not exist"},
+			"es": {"Palabra no encontrada", "La palabra solicitada no existe"},
+		},
+	},
+	CodeWordAlreadyExists: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"Word already exists", "Another word already uses this spelling"},
+			"es": {"La palabra ya existe", "Otra palabra ya usa esta escritura"},
+		},
+	},
+	CodePageOutOfRange: {
+		Status: http.StatusBadRequest,
+		Type:   TypeInvalidInput,
+		Messages: map[string]Message{
+			"en": {"Invalid page number", "The requested page number exceeds the total number of pages"},
+			"es": {"Número de página no válido", "El número de página solicitado supera el total de páginas"},
+		},
+	},
+	CodeGroupNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Group not found", "The requested group does not exist"},
+			"es": {"Grupo no encontrado", "El grupo solicitado no existe"},
+		},
+	},
+	CodeGroupNameTaken: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"Group name already taken", "Another group already uses this name"},
+			"es": {"El nombre del grupo ya existe", "Otro grupo ya usa este nombre"},
+		},
+	},
+	CodeWordAlreadyInGroup: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"Word already in group", "The word is already a member of this group"},
+			"es": {"La palabra ya está en el grupo", "La palabra ya forma parte de este grupo"},
+		},
+	},
+	CodeSmartGroupReadOnly: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"Smart group is read-only", "The words of a smart group are selected by its query and cannot be added or removed"},
+			"es": {"El grupo inteligente es de solo lectura", "Las palabras de un grupo inteligente las selecciona su consulta y no se pueden añadir ni quitar"},
+		},
+	},
+	CodeSessionNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Study session not found", "The requested study session does not exist"},
+			"es": {"Sesión de estudio no encontrada", "La sesión de estudio solicitada no existe"},
+		},
+	},
+	CodeSessionCompleted: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"Study session completed", "The study session has been completed and accepts no more reviews"},
+			"es": {"Sesión de estudio finalizada", "La sesión de estudio ha finalizado y no admite más repasos"},
+		},
+	},
+	CodeNoStudySessions: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"No study sessions found", "There are no recorded study sessions in the system"},
+			"es": {"No se encontraron sesiones de estudio", "No hay sesiones de estudio registradas en el sistema"},
+		},
+	},
+	CodeNoStudyProgress: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"No study progress found", "There is no recorded study progress in the system"},
+			"es": {"No se encontró progreso de estudio", "No hay progreso de estudio registrado en el sistema"},
+		},
+	},
+	CodeNoStatistics: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"No statistics available", "There are no recorded study sessions to generate statistics"},
+			"es": {"No hay estadísticas disponibles", "No hay sesiones de estudio registradas para generar estadísticas"},
+		},
+	},
+	CodeNoSessionReviews: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"No reviews found", "The study session has no recorded reviews"},
+			"es": {"No se encontraron repasos", "La sesión de estudio no tiene repasos registrados"},
+		},
+	},
+	CodeTrashItemNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Item not in trash", "No deleted item of this type and id is in the trash"},
+			"es": {"El elemento no está en la papelera", "No hay ningún elemento eliminado de este tipo e id en la papelera"},
+		},
+	},
+	CodeLanguageNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Language not found", "The requested language is not taught in this portal"},
+			"es": {"Idioma no encontrado", "El idioma solicitado no se enseña en este portal"},
+		},
+	},
+	CodeLanguageAlreadyExists: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"Language already exists", "A language with this code already exists"},
+			"es": {"El idioma ya existe", "Ya existe un idioma con este código"},
+		},
+	},
+	CodeLanguageMismatch: {
+		Status: http.StatusUnprocessableEntity,
+		Type:   TypeInvalidInput,
+		Messages: map[string]Message{
+			"en": {"Language mismatch", "A word can only belong to groups of its own language"},
+			"es": {"Idiomas distintos", "Una palabra solo puede pertenecer a grupos de su propio idioma"},
+		},
+	},
+	CodeMediaTooLarge: {
+		Status: http.StatusRequestEntityTooLarge,
+		Type:   TypeInvalidInput,
+		Messages: map[string]Message{
+			"en": {"File too large", "The uploaded file exceeds the maximum size"},
+			"es": {"Archivo demasiado grande", "El archivo subido supera el tamaño máximo"},
+		},
+	},
+	CodeUnsupportedMediaFormat: {
+		Status: http.StatusUnsupportedMediaType,
+		Type:   TypeInvalidInput,
+		Messages: map[string]Message{
+			"en": {"Unsupported file format", "The uploaded file is not in a supported format"},
+			"es": {"Formato de archivo no admitido", "El archivo subido no tiene un formato admitido"},
+		},
+	},
+	CodeAudioNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Audio not found", "No audio has been uploaded for this word or example"},
+			"es": {"Audio no encontrado", "No se ha subido audio para esta palabra o ejemplo"},
+		},
+	},
+	CodeExampleNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Example not found", "The word has no example sentence at this position"},
+			"es": {"Ejemplo no encontrado", "La palabra no tiene una oración de ejemplo en esta posición"},
+		},
+	},
+	CodeImageTooLarge: {
+		Status: http.StatusUnprocessableEntity,
+		Type:   TypeInvalidInput,
+		Messages: map[string]Message{
+			"en": {"Image too large", "The image dimensions exceed the maximum supported"},
+			"es": {"Imagen demasiado grande", "Las dimensiones de la imagen superan el máximo admitido"},
+		},
+	},
+	CodeImageNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Image not found", "The word has no image with this ID"},
+			"es": {"Imagen no encontrada", "La palabra no tiene ninguna imagen con este ID"},
+		},
+	},
+	CodeMediaNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"File not found", "No media file has this hash"},
+			"es": {"Archivo no encontrado", "Ningún archivo multimedia tiene este hash"},
+		},
+	},
+	CodeInvalidToken: {
+		Status: http.StatusUnauthorized,
+		Type:   TypeUnauthorized,
+		Messages: map[string]Message{
+			"en": {"Invalid token", "The Authorization header does not carry a valid bearer token"},
+			"es": {"Token no válido", "La cabecera Authorization no contiene un token bearer válido"},
+		},
+	},
+	CodeUserNameTaken: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"User name already taken", "Another user already uses this name"},
+			"es": {"El nombre de usuario ya existe", "Otro usuario ya usa este nombre"},
+		},
+	},
+	CodeAuditEntryNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Audit entry not found", "The requested audit entry does not exist"},
+			"es": {"Entrada de auditoría no encontrada", "La entrada de auditoría solicitada no existe"},
+		},
+	},
+	CodeAuditAlreadyReverted: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"Change already reverted", "This change has already been reverted"},
+			"es": {"Cambio ya revertido", "Este cambio ya se ha revertido"},
+		},
+	},
+	CodeAuditRevertConflict: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"Cannot revert change", "The entity was modified after this change; revert the later changes first"},
+			"es": {"No se puede revertir el cambio", "La entidad se modificó después de este cambio; revierta primero los cambios posteriores"},
+		},
+	},
+	CodeWebhookNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Webhook not found", "The requested webhook does not exist"},
+			"es": {"Webhook no encontrado", "El webhook solicitado no existe"},
+		},
+	},
+	CodeGenerationFailed: {
+		Status: http.StatusBadGateway,
+		Type:   TypeUpstream,
+		Messages: map[string]Message{
+			"en": {"Generation failed", "The language model could not be reached or gave an unusable answer; try again later"},
+			"es": {"La generación falló", "No se pudo contactar con el modelo de lenguaje o su respuesta no era utilizable; inténtelo más tarde"},
+		},
+	},
+	CodeTextNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Text not found", "The requested text does not exist"},
+			"es": {"Texto no encontrado", "El texto solicitado no existe"},
+		},
+	},
+	CodeNoTextRecommendation: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"No text to recommend", "No text has a number of unknown words within the requested range"},
+			"es": {"Ningún texto para recomendar", "Ningún texto tiene un número de palabras desconocidas dentro del rango solicitado"},
+		},
+	},
+	CodeGoalNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Goal not found", "The requested goal does not exist"},
+			"es": {"Objetivo no encontrado", "El objetivo solicitado no existe"},
+		},
+	},
+	CodeGoalAlreadyExists: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"Goal already exists", "You already have a goal for this metric; delete it first"},
+			"es": {"El objetivo ya existe", "Ya tiene un objetivo para esta métrica; elimínelo primero"},
+		},
+	},
+	CodeClassNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Class not found", "The requested class does not exist"},
+			"es": {"Clase no encontrada", "La clase solicitada no existe"},
+		},
+	},
+	CodeClassNameTaken: {
+		Status: http.StatusConflict,
+		Type:   TypeConflict,
+		Messages: map[string]Message{
+			"en": {"Class name taken", "You already teach a class with this name"},
+			"es": {"Nombre de clase en uso", "Ya imparte una clase con este nombre"},
+		},
+	},
+	CodeStudentNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Student not found", "There is no user to enrol with this ID"},
+			"es": {"Alumno no encontrado", "No hay ningún usuario con este ID para inscribir"},
+		},
+	},
+	CodeStudentNotEnrolled: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Student not enrolled", "The user is not a student of this class"},
+			"es": {"Alumno no inscrito", "El usuario no es alumno de esta clase"},
+		},
+	},
+	CodeAssignmentNotFound: {
+		Status: http.StatusNotFound,
+		Type:   TypeNotFound,
+		Messages: map[string]Message{
+			"en": {"Assignment not found", "The requested assignment does not exist in this class"},
+			"es": {"Tarea no encontrada", "La tarea solicitada no existe en esta clase"},
+		},
+	},
+}
+
+// Localize returns the message for a code in the language that best matches
+// an Accept-Language header, together with the chosen language
+func Localize(code, acceptLanguage string) (Message, string) {
+	lang := MatchLanguage(acceptLanguage)
+	entry, ok := catalog[code]
+	if !ok {
+		entry = catalog[CodeInternal]
+	}
+	if msg, ok := entry.Messages[lang]; ok {
+		return msg, lang
+	}
+	return entry.Messages[defaultLanguage], defaultLanguage
+}
+
+// MatchLanguage picks the supported language that best matches an
+// Accept-Language header
+func MatchLanguage(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return defaultLanguage
+	}
+	_, index, confidence := languageMatcher.Match(tags...)
+	if confidence == language.No {
+		return defaultLanguage
+	}
+	base, _ := supportedLanguages[index].Base()
+	return base.String()
+}
+
+// CatalogEntry is the public description of an error code
+type CatalogEntry struct {
+	Code   string `json:"code"`
+	Status int    `json:"status"`
+	Type   string `json:"type"`
+	Message
+}
+
+// Catalog lists every error code with messages in the given language
+func Catalog(acceptLanguage string) []CatalogEntry {
+	entries := make([]CatalogEntry, 0, len(catalog))
+	for code, entry := range catalog {
+		msg, _ := Localize(code, acceptLanguage)
+		entries = append(entries, CatalogEntry{
+			Code:    code,
+			Status:  entry.Status,
+			Type:    entry.Type,
+			Message: msg,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Code < entries[j].Code })
+	return entries
+}
@@ -1,91 +1,94 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
-	"net/http"
 )
 
 // AppError represents an application error with additional context
 type AppError struct {
-	Code       int         `json:"-"`          // HTTP status code
-	Message    string      `json:"message"`    // User-facing error message
-	Detail     string      `json:"detail"`     // Detailed error message
-	Type       string      `json:"type"`       // Error type for categorization
-	Data       interface{} `json:"data"`       // Additional error data
-	Internal   error       `json:"-"`          // Internal error (not exposed to user)
+	Status    int         `json:"-"`                    // HTTP status code
+	Code      string      `json:"code"`                 // Stable machine-readable code from the catalog
+	Type      string      `json:"type"`                 // Error type for categorization
+	Message   string      `json:"message"`              // User-facing error message
+	Detail    string      `json:"detail"`               // Detailed error message
+	Data      interface{} `json:"data,omitempty"`       // Additional error data
+	RequestID string      `json:"request_id,omitempty"` // ID of the request that failed
+	Internal  error       `json:"-"`                    // Internal error (not exposed to user)
 }
 
 // Error implements the error interface
 func (e *AppError) Error() string {
 	if e.Internal != nil {
-		return fmt.Sprintf("%s: %v", e.Message, e.Internal)
+		return fmt.Sprintf("%s: %v", e.Code, e.Internal)
 	}
-	return e.Message
+	return e.Code
+}
+
+// Unwrap exposes the internal error to errors.Is and errors.As
+func (e *AppError) Unwrap() error {
+	return e.Internal
 }
 
 // Common error types
 const (
-	TypeNotFound          = "NOT_FOUND"
-	TypeValidation        = "VALIDATION_ERROR"
-	TypeDatabase          = "DATABASE_ERROR"
-	TypeInternal         = "INTERNAL_ERROR"
-	TypeInvalidInput     = "INVALID_INPUT"
-	TypeUnauthorized     = "UNAUTHORIZED"
-	TypeForbidden        = "FORBIDDEN"
+	TypeNotFound     = "NOT_FOUND"
+	TypeValidation   = "VALIDATION_ERROR"
+	TypeDatabase     = "DATABASE_ERROR"
+	TypeInternal     = "INTERNAL_ERROR"
+	TypeInvalidInput = "INVALID_INPUT"
+	TypeConflict     = "CONFLICT"
+	TypeUnauthorized = "UNAUTHORIZED"
+	TypeForbidden    = "FORBIDDEN"
+	TypeUpstream     = "UPSTREAM_ERROR"
 )
 
-// NewNotFoundError creates a new not found error
-func NewNotFoundError(message string, detail string) *AppError {
+// New creates an error for a catalog code, with English messages. Codes
+// missing from the catalog are treated as internal errors.
+func New(code string) *AppError {
+	entry, ok := catalog[code]
+	if !ok {
+		entry = catalog[CodeInternal]
+	}
+	msg := entry.Messages[defaultLanguage]
 	return &AppError{
-		Code:    http.StatusNotFound,
-		Message: message,
-		Detail:  detail,
-		Type:    TypeNotFound,
+		Status:  entry.Status,
+		Code:    code,
+		Type:    entry.Type,
+		Message: msg.Title,
+		Detail:  msg.Detail,
 	}
 }
 
-// NewValidationError creates a new validation error
-func NewValidationError(message string, detail string, data interface{}) *AppError {
-	return &AppError{
-		Code:    http.StatusBadRequest,
-		Message: message,
-		Detail:  detail,
-		Type:    TypeValidation,
-		Data:    data,
-	}
+// WithData attaches additional error data, such as the offending fields
+func (e *AppError) WithData(data interface{}) *AppError {
+	e.Data = data
+	return e
+}
+
+// WithInternal attaches the underlying cause, which is logged but never exposed
+func (e *AppError) WithInternal(err error) *AppError {
+	e.Internal = err
+	return e
 }
 
-// NewDatabaseError creates a new database error
+// NewDatabaseError creates a new database error. Errors that the service
+// layer already translated into an AppError, such as constraint violations,
+// are returned unchanged.
 func NewDatabaseError(message string, err error) *AppError {
-	return &AppError{
-		Code:     http.StatusInternalServerError,
-		Message:  "Database operation failed",
-		Detail:   message,
-		Type:     TypeDatabase,
-		Internal: err,
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
 	}
+	return New(CodeDatabase).WithInternal(fmt.Errorf("%s: %w", message, err))
 }
 
 // NewInternalError creates a new internal server error
 func NewInternalError(message string, err error) *AppError {
-	return &AppError{
-		Code:     http.StatusInternalServerError,
-		Message:  message,
-		Detail:   "An internal server error occurred",
-		Type:     TypeInternal,
-		Internal: err,
-	}
-}
-
-// NewInvalidInputError creates a new invalid input error
-func NewInvalidInputError(message string, detail string, data interface{}) *AppError {
-	return &AppError{
-		Code:    http.StatusBadRequest,
-		Message: message,
-		Detail:  detail,
-		Type:    TypeInvalidInput,
-		Data:    data,
+	if err == nil {
+		return New(CodeInternal).WithInternal(fmt.Errorf("%s", message))
 	}
+	return New(CodeInternal).WithInternal(fmt.Errorf("%s: %w", message, err))
 }
 
 // IsAppError checks if an error is an AppError
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+type AchievementHandler struct {
+	service *service.AchievementService
+}
+
+// LeaderboardQuery holds the query parameters of GET /api/leaderboard
+type LeaderboardQuery struct {
+	Limit int `form:"limit,default=10" binding:"min=1,max=100"`
+}
+
+func NewAchievementHandler(service *service.AchievementService) *AchievementHandler {
+	return &AchievementHandler{service: service}
+}
+
+// GetAchievements handles GET /api/achievements
+func (h *AchievementHandler) GetAchievements(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	summary, err := h.service.GetAchievements(user)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch achievements", err))
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetLeaderboard handles GET /api/leaderboard
+func (h *AchievementHandler) GetLeaderboard(c *gin.Context) {
+	query := middleware.Input[LeaderboardQuery](c)
+
+	entries, err := h.service.GetLeaderboard(query.Limit)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch leaderboard", err))
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+type AuditHandler struct {
+	service *service.AuditService
+}
+
+// AuditListQuery holds the query parameters of GET /api/audit
+type AuditListQuery struct {
+	Entity string `form:"entity" binding:"omitempty,oneof=word group"`
+	ID     int    `form:"id" binding:"omitempty,min=1"`
+	Limit  int    `form:"limit,default=50" binding:"min=1,max=500"`
+}
+
+// AuditEntryParams holds the path parameters of /api/audit/:id
+type AuditEntryParams struct {
+	ID int `uri:"id" json:"-" binding:"required,min=1"`
+}
+
+func NewAuditHandler(service *service.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// GetAuditLog handles GET /api/audit
+func (h *AuditHandler) GetAuditLog(c *gin.Context) {
+	query := middleware.Input[AuditListQuery](c)
+
+	entries, err := h.service.ListEntries(service.AuditFilter{
+		Entity:   query.Entity,
+		EntityID: query.ID,
+		Limit:    query.Limit,
+	})
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch audit log", err))
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// RevertChange handles POST /api/audit/:id/revert
+func (h *AuditHandler) RevertChange(c *gin.Context) {
+	params := middleware.Input[AuditEntryParams](c)
+
+	entry, err := h.service.Revert(middleware.CurrentUser(c), params.ID)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to revert change", err))
+		return
+	}
+	if entry == nil {
+		_ = c.Error(errors.New(errors.CodeAuditEntryNotFound))
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
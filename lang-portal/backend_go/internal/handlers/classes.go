@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+type ClassHandler struct {
+	service      *service.ClassService
+	achievements *service.AchievementService
+}
+
+// CreateClassRequest is the body of POST /api/classes
+type CreateClassRequest struct {
+	Name string `json:"name" binding:"required,notblank,max=100"`
+}
+
+// ClassIDParams holds the path parameters of /api/classes/:id
+type ClassIDParams struct {
+	ID int `uri:"id" json:"-" binding:"required,min=1"`
+}
+
+// AddStudentRequest is the body of POST /api/classes/:id/students
+type AddStudentRequest struct {
+	ID     int `uri:"id" json:"-" binding:"required,min=1"`
+	UserID int `json:"user_id" binding:"required,min=1"`
+}
+
+// ClassStudentParams holds the path parameters of
+// /api/classes/:id/students/:userId
+type ClassStudentParams struct {
+	ID     int `uri:"id" json:"-" binding:"required,min=1"`
+	UserID int `uri:"userId" json:"-" binding:"required,min=1"`
+}
+
+// CreateAssignmentRequest is the body of POST /api/classes/:id/assignments.
+// Without a RequiredAccuracy any completed session counts.
+type CreateAssignmentRequest struct {
+	ID               int       `uri:"id" json:"-" binding:"required,min=1"`
+	GroupID          int       `json:"group_id" binding:"required,min=1"`
+	StudyActivityID  *int      `json:"study_activity_id" binding:"omitempty,min=1"`
+	DueAt            time.Time `json:"due_at" binding:"required"`
+	RequiredAccuracy float64   `json:"required_accuracy" binding:"min=0,max=1"`
+}
+
+// AssignmentParams holds the path parameters of
+// /api/classes/:id/assignments/:assignmentId
+type AssignmentParams struct {
+	ID           int `uri:"id" json:"-" binding:"required,min=1"`
+	AssignmentID int `uri:"assignmentId" json:"-" binding:"required,min=1"`
+}
+
+// WordReportQuery holds the parameters of GET /api/classes/:id/reports/words.
+// An AssignmentID limits the report to the group of that assignment.
+type WordReportQuery struct {
+	ID           int `uri:"id" json:"-" binding:"required,min=1"`
+	AssignmentID int `form:"assignment_id" binding:"omitempty,min=1"`
+}
+
+// ClassLeaderboardQuery holds the parameters of
+// GET /api/classes/:id/leaderboard
+type ClassLeaderboardQuery struct {
+	ID    int `uri:"id" json:"-" binding:"required,min=1"`
+	Limit int `form:"limit,default=10" binding:"min=1,max=100"`
+}
+
+func NewClassHandler(service *service.ClassService, achievements *service.AchievementService) *ClassHandler {
+	return &ClassHandler{service: service, achievements: achievements}
+}
+
+// GetClasses handles GET /api/classes
+func (h *ClassHandler) GetClasses(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	classes, err := h.service.GetClasses(user)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch classes", err))
+		return
+	}
+	c.JSON(http.StatusOK, classes)
+}
+
+// CreateClass handles POST /api/classes. The current user teaches the class.
+func (h *ClassHandler) CreateClass(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	input := middleware.Input[CreateClassRequest](c)
+
+	class, err := h.service.CreateClass(user, input.Name)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to create class", err))
+		return
+	}
+	c.JSON(http.StatusCreated, class)
+}
+
+// GetClass handles GET /api/classes/:id
+func (h *ClassHandler) GetClass(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	params := middleware.Input[ClassIDParams](c)
+
+	class, err := h.service.GetClass(user, params.ID)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch class", err))
+		return
+	}
+	c.JSON(http.StatusOK, class)
+}
+
+// DeleteClass handles DELETE /api/classes/:id
+func (h *ClassHandler) DeleteClass(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	params := middleware.Input[ClassIDParams](c)
+
+	if err := h.service.DeleteClass(user, params.ID); err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to delete class", err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AddStudent handles POST /api/classes/:id/students
+func (h *ClassHandler) AddStudent(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	input := middleware.Input[AddStudentRequest](c)
+
+	if err := h.service.AddStudent(user, input.ID, input.UserID); err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to add student", err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveStudent handles DELETE /api/classes/:id/students/:userId
+func (h *ClassHandler) RemoveStudent(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	params := middleware.Input[ClassStudentParams](c)
+
+	if err := h.service.RemoveStudent(user, params.ID, params.UserID); err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to remove student", err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetAssignments handles GET /api/classes/:id/assignments
+func (h *ClassHandler) GetAssignments(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	params := middleware.Input[ClassIDParams](c)
+
+	assignments, err := h.service.GetAssignments(user, params.ID, time.Now())
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch assignments", err))
+		return
+	}
+	c.JSON(http.StatusOK, assignments)
+}
+
+// CreateAssignment handles POST /api/classes/:id/assignments
+func (h *ClassHandler) CreateAssignment(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	input := middleware.Input[CreateAssignmentRequest](c)
+
+	assignment, err := h.service.CreateAssignment(user, input.ID, service.AssignmentInput{
+		GroupID:          input.GroupID,
+		StudyActivityID:  input.StudyActivityID,
+		DueAt:            input.DueAt,
+		RequiredAccuracy: input.RequiredAccuracy,
+	}, time.Now())
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to create assignment", err))
+		return
+	}
+	c.JSON(http.StatusCreated, assignment)
+}
+
+// DeleteAssignment handles DELETE /api/classes/:id/assignments/:assignmentId
+func (h *ClassHandler) DeleteAssignment(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	params := middleware.Input[AssignmentParams](c)
+
+	if err := h.service.DeleteAssignment(user, params.ID, params.AssignmentID); err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to delete assignment", err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetStudentReports handles GET /api/classes/:id/reports/students
+func (h *ClassHandler) GetStudentReports(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	params := middleware.Input[ClassIDParams](c)
+
+	reports, err := h.service.GetStudentReports(user, params.ID, time.Now())
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch student reports", err))
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetWordReports handles GET /api/classes/:id/reports/words
+func (h *ClassHandler) GetWordReports(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	query := middleware.Input[WordReportQuery](c)
+
+	reports, err := h.service.GetWordReports(user, query.ID, query.AssignmentID)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch word reports", err))
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetLeaderboard handles GET /api/classes/:id/leaderboard, which the teacher
+// and students of the class may see
+func (h *ClassHandler) GetLeaderboard(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	query := middleware.Input[ClassLeaderboardQuery](c)
+
+	if _, err := h.service.GetClass(user, query.ID); err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch class", err))
+		return
+	}
+	entries, err := h.achievements.GetClassLeaderboard(query.ID, query.Limit)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch leaderboard", err))
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
 	"lang-portal/internal/service"
 )
 
@@ -12,22 +13,26 @@ type DashboardHandler struct {
 	studyService *service.StudyService
 }
 
+// DashboardQuery holds the query parameters shared by the dashboard endpoints
+type DashboardQuery struct {
+	Lang string `form:"lang" binding:"max=16"`
+}
+
 func NewDashboardHandler(studyService *service.StudyService) *DashboardHandler {
 	return &DashboardHandler{studyService: studyService}
 }
 
 // GetLastStudySession handles the /api/dashboard/last_study_session endpoint
 func (h *DashboardHandler) GetLastStudySession(c *gin.Context) {
-	session, err := h.studyService.GetLastStudySession()
+	query := middleware.Input[DashboardQuery](c)
+
+	session, err := h.studyService.GetLastStudySession(query.Lang)
 	if err != nil {
 		_ = c.Error(errors.NewDatabaseError("Failed to fetch last study session", err))
 		return
 	}
 	if session == nil {
-		_ = c.Error(errors.NewNotFoundError(
-			"No study sessions found",
-			"There are no recorded study sessions in the system",
-		))
+		_ = c.Error(errors.New(errors.CodeNoStudySessions))
 		return
 	}
 	c.JSON(http.StatusOK, session)
@@ -35,7 +40,9 @@ func (h *DashboardHandler) GetLastStudySession(c *gin.Context) {
 
 // GetStudyProgress handles the /api/dashboard/study_progress endpoint
 func (h *DashboardHandler) GetStudyProgress(c *gin.Context) {
-	progress, err := h.studyService.GetStudyProgress()
+	query := middleware.Input[DashboardQuery](c)
+
+	progress, err := h.studyService.GetStudyProgress(query.Lang)
 	if err != nil {
 		_ = c.Error(errors.NewDatabaseError("Failed to fetch study progress", err))
 		return
@@ -43,10 +50,7 @@ func (h *DashboardHandler) GetStudyProgress(c *gin.Context) {
 
 	// Add additional context if no progress is found
 	if progress.TotalWordsStudied == 0 && progress.TotalAvailableWords == 0 {
-		_ = c.Error(errors.NewNotFoundError(
-			"No study progress found",
-			"There is no recorded study progress in the system",
-		))
+		_ = c.Error(errors.New(errors.CodeNoStudyProgress))
 		return
 	}
 
@@ -55,7 +59,9 @@ func (h *DashboardHandler) GetStudyProgress(c *gin.Context) {
 
 // GetQuickStats handles the /api/dashboard/quick-stats endpoint
 func (h *DashboardHandler) GetQuickStats(c *gin.Context) {
-	stats, err := h.studyService.GetQuickStats()
+	query := middleware.Input[DashboardQuery](c)
+
+	stats, err := h.studyService.GetQuickStats(query.Lang)
 	if err != nil {
 		_ = c.Error(errors.NewDatabaseError("Failed to fetch quick stats", err))
 		return
@@ -63,10 +69,7 @@ func (h *DashboardHandler) GetQuickStats(c *gin.Context) {
 
 	// Add additional context if no stats are found
 	if stats.TotalStudySessions == 0 {
-		_ = c.Error(errors.NewNotFoundError(
-			"No statistics available",
-			"There are no recorded study sessions to generate statistics",
-		))
+		_ = c.Error(errors.New(errors.CodeNoStatistics))
 		return
 	}
 
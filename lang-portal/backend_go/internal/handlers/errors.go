@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+)
+
+// GetErrorCatalog handles GET /api/errors, listing every error code with its
+// messages in the language requested by Accept-Language
+func GetErrorCatalog(c *gin.Context) {
+	lang := errors.MatchLanguage(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", lang)
+	c.JSON(http.StatusOK, errors.Catalog(c.GetHeader("Accept-Language")))
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+// eventKeepAlive is how often a comment is sent on an idle event stream, so
+// that proxies do not close it
+const eventKeepAlive = 15 * time.Second
+
+type EventHandler struct {
+	bus *service.EventBus
+}
+
+// EventStreamQuery holds the query parameters of GET /api/events. The
+// stream carries the caller's own events; following another user, or every
+// user with All, needs the administer permission.
+type EventStreamQuery struct {
+	UserID    int  `form:"user_id" binding:"omitempty,min=1,excluded_with=All"`
+	SessionID int  `form:"session_id" binding:"omitempty,min=1"`
+	All       bool `form:"all"`
+}
+
+func NewEventHandler(bus *service.EventBus) *EventHandler {
+	return &EventHandler{bus: bus}
+}
+
+// Stream handles GET /api/events, streaming study and word events as
+// server-sent events until the client disconnects. A client reconnecting
+// with the Last-Event-ID header receives the recent events it missed.
+func (h *EventHandler) Stream(c *gin.Context) {
+	query := middleware.Input[EventStreamQuery](c)
+
+	filter := service.EventFilter{UserID: middleware.CurrentUser(c).ID, SessionID: query.SessionID}
+	if query.All || query.UserID != 0 && query.UserID != filter.UserID {
+		if err := middleware.Authorize(c, service.PermissionAdminister); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		filter.UserID = query.UserID
+	}
+
+	lastID, _ := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64)
+	sub := h.bus.Subscribe(filter, lastID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case event, ok := <-sub.Events:
+			if !ok {
+				// Dropped for falling behind; the client reconnects
+				return false
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return false
+			}
+			_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			return err == nil
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+type GoalHandler struct {
+	service *service.GoalService
+}
+
+// ReminderInput asks for a notification when a goal is at risk. At is a
+// time of day in UTC, defaulting to 18:00.
+type ReminderInput struct {
+	Channel string `json:"channel" binding:"required,oneof=webhook email"`
+	Address string `json:"address" binding:"required,notblank,max=2000"`
+	At      string `json:"at" binding:"omitempty,datetime=15:04"`
+}
+
+// CreateGoalRequest is the body of POST /api/goals
+type CreateGoalRequest struct {
+	Metric   string         `json:"metric" binding:"required,oneof=reviews_per_day new_words_per_week minutes_per_day"`
+	Target   int            `json:"target" binding:"required,min=1,max=100000"`
+	Reminder *ReminderInput `json:"reminder"`
+}
+
+// GoalIDParams holds the path parameters of /api/goals/:id
+type GoalIDParams struct {
+	ID int `uri:"id" json:"-" binding:"required,min=1"`
+}
+
+// NotificationListQuery holds the query parameters of GET /api/notifications
+type NotificationListQuery struct {
+	Limit int `form:"limit,default=50" binding:"min=1,max=500"`
+}
+
+func NewGoalHandler(service *service.GoalService) *GoalHandler {
+	return &GoalHandler{service: service}
+}
+
+// GetGoals handles GET /api/goals
+func (h *GoalHandler) GetGoals(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	goals, err := h.service.GetGoals(user, time.Now())
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch goals", err))
+		return
+	}
+	c.JSON(http.StatusOK, goals)
+}
+
+// CreateGoal handles POST /api/goals
+func (h *GoalHandler) CreateGoal(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	input := middleware.Input[CreateGoalRequest](c)
+
+	goalInput := service.GoalInput{Metric: input.Metric, Target: input.Target}
+	if r := input.Reminder; r != nil {
+		goalInput.Reminder = &service.Reminder{Channel: r.Channel, Address: r.Address, At: r.At}
+	}
+	goal, err := h.service.CreateGoal(user, goalInput, time.Now())
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to create goal", err))
+		return
+	}
+	c.JSON(http.StatusCreated, goal)
+}
+
+// DeleteGoal handles DELETE /api/goals/:id
+func (h *GoalHandler) DeleteGoal(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	params := middleware.Input[GoalIDParams](c)
+
+	deleted, err := h.service.DeleteGoal(user, params.ID)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to delete goal", err))
+		return
+	}
+	if !deleted {
+		_ = c.Error(errors.New(errors.CodeGoalNotFound))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetNotifications handles GET /api/notifications
+func (h *GoalHandler) GetNotifications(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	query := middleware.Input[NotificationListQuery](c)
+
+	notifications, err := h.service.GetNotifications(user, query.Limit)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch notifications", err))
+		return
+	}
+	c.JSON(http.StatusOK, notifications)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+type GradingHandler struct {
+	service *service.GradingService
+}
+
+// GradeRequest is the body of POST /api/grade. Expected lists the accepted
+// translations of Prompt; with a SessionID, the outcome is recorded as a
+// review of every word detected in the matched translation, which needs the
+// study permission and the caller's own session.
+type GradeRequest struct {
+	Prompt    string   `json:"prompt" binding:"max=1000"`
+	Expected  []string `json:"expected" binding:"required,min=1,max=10,dive,notblank,max=1000"`
+	Answer    string   `json:"answer" binding:"max=1000"`
+	Language  string   `json:"language" binding:"max=16"`
+	SessionID int      `json:"session_id" binding:"omitempty,min=1"`
+	Judge     bool     `json:"judge"`
+}
+
+func NewGradingHandler(service *service.GradingService) *GradingHandler {
+	return &GradingHandler{service: service}
+}
+
+// Grade handles POST /api/grade
+func (h *GradingHandler) Grade(c *gin.Context) {
+	input := middleware.Input[GradeRequest](c)
+	if input.SessionID != 0 {
+		if err := middleware.Authorize(c, service.PermissionStudy); err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+
+	result, err := h.service.Grade(c.Request.Context(), middleware.CurrentUser(c), service.GradeInput{
+		Prompt:    input.Prompt,
+		Expected:  input.Expected,
+		Answer:    input.Answer,
+		Language:  input.Language,
+		SessionID: input.SessionID,
+		Judge:     input.Judge,
+	})
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to grade answer", err))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
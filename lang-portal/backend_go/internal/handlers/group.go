@@ -2,10 +2,10 @@ package handlers
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
 	"lang-portal/internal/service"
 )
 
@@ -13,13 +13,67 @@ type GroupHandler struct {
 	service *service.GroupService
 }
 
+// GroupListQuery holds the query parameters of GET /api/groups
+type GroupListQuery struct {
+	Lang string `form:"lang" binding:"max=16"`
+}
+
+// GroupIDParams holds the path parameters of /api/groups/:id
+type GroupIDParams struct {
+	ID int `uri:"id" json:"-" binding:"required,min=1"`
+}
+
+// CreateGroupRequest is the body of POST /api/groups. Giving a query creates
+// a smart group.
+type CreateGroupRequest struct {
+	Name     string           `json:"name" binding:"required,min=1,max=100"`
+	Language string           `json:"language" binding:"max=16"`
+	Query    *GroupQueryInput `json:"query"`
+}
+
+// GroupQueryInput selects the words of a smart group. Words must match every
+// criterion given.
+type GroupQueryInput struct {
+	Tags             []string               `json:"tags" binding:"omitempty,max=10,dive,notblank,max=50"`
+	Parts            map[string]interface{} `json:"parts" binding:"omitempty,max=10"`
+	AccuracyBelow    *float64               `json:"accuracy_below" binding:"omitempty,gt=0,lte=1"`
+	MissedWithinDays *int                   `json:"missed_within_days" binding:"omitempty,min=1,max=365"`
+}
+
+// query converts the input to the service's group query
+func (q *GroupQueryInput) query() *service.GroupQuery {
+	if q == nil {
+		return nil
+	}
+	return &service.GroupQuery{
+		Tags:             q.Tags,
+		Parts:            q.Parts,
+		AccuracyBelow:    q.AccuracyBelow,
+		MissedWithinDays: q.MissedWithinDays,
+	}
+}
+
+// AddWordToGroupRequest is the input of POST /api/groups/:id/words
+type AddWordToGroupRequest struct {
+	GroupID int `uri:"id" json:"-" binding:"required,min=1"`
+	WordID  int `json:"word_id" binding:"required,min=1"`
+}
+
+// GroupWordParams holds the path parameters of /api/groups/:id/words/:wordId
+type GroupWordParams struct {
+	GroupID int `uri:"id" json:"-" binding:"required,min=1"`
+	WordID  int `uri:"wordId" json:"-" binding:"required,min=1"`
+}
+
 func NewGroupHandler(service *service.GroupService) *GroupHandler {
 	return &GroupHandler{service: service}
 }
 
 // GetGroups handles GET /api/groups
 func (h *GroupHandler) GetGroups(c *gin.Context) {
-	groups, err := h.service.GetGroups()
+	query := middleware.Input[GroupListQuery](c)
+
+	groups, err := h.service.GetGroups(service.GroupFilter{Language: query.Lang})
 	if err != nil {
 		_ = c.Error(errors.NewDatabaseError("Failed to fetch groups", err))
 		return
@@ -29,26 +83,15 @@ func (h *GroupHandler) GetGroups(c *gin.Context) {
 
 // GetGroupByID handles GET /api/groups/:id
 func (h *GroupHandler) GetGroupByID(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		_ = c.Error(errors.NewInvalidInputError(
-			"Invalid group ID",
-			"The group ID must be a valid number",
-			map[string]string{"id": c.Param("id")},
-		))
-		return
-	}
+	params := middleware.Input[GroupIDParams](c)
 
-	group, err := h.service.GetGroupByID(id)
+	group, err := h.service.GetGroupByID(params.ID)
 	if err != nil {
 		_ = c.Error(errors.NewDatabaseError("Failed to fetch group", err))
 		return
 	}
 	if group == nil {
-		_ = c.Error(errors.NewNotFoundError(
-			"Group not found",
-			"The requested group does not exist",
-		))
+		_ = c.Error(errors.New(errors.CodeGroupNotFound))
 		return
 	}
 	c.JSON(http.StatusOK, group)
@@ -56,20 +99,9 @@ func (h *GroupHandler) GetGroupByID(c *gin.Context) {
 
 // CreateGroup handles POST /api/groups
 func (h *GroupHandler) CreateGroup(c *gin.Context) {
-	var input struct {
-		Name string `json:"name" binding:"required,min=1,max=100"`
-	}
+	input := middleware.Input[CreateGroupRequest](c)
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		_ = c.Error(errors.NewValidationError(
-			"Invalid group data",
-			"The provided group data is invalid",
-			map[string]string{"name": "Name is required and must be between 1 and 100 characters"},
-		))
-		return
-	}
-
-	group, err := h.service.CreateGroup(input.Name)
+	group, err := h.service.CreateGroup(middleware.CurrentUser(c), input.Name, input.Language, input.Query.query())
 	if err != nil {
 		_ = c.Error(errors.NewDatabaseError("Failed to create group", err))
 		return
@@ -78,32 +110,28 @@ func (h *GroupHandler) CreateGroup(c *gin.Context) {
 	c.JSON(http.StatusCreated, group)
 }
 
-// AddWordToGroup handles POST /api/groups/:id/words
-func (h *GroupHandler) AddWordToGroup(c *gin.Context) {
-	groupID, err := strconv.Atoi(c.Param("id"))
+// DeleteGroup handles DELETE /api/groups/:id by moving the group to the trash
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	params := middleware.Input[GroupIDParams](c)
+
+	deleted, err := h.service.DeleteGroup(middleware.CurrentUser(c), params.ID)
 	if err != nil {
-		_ = c.Error(errors.NewInvalidInputError(
-			"Invalid group ID",
-			"The group ID must be a valid number",
-			map[string]string{"id": c.Param("id")},
-		))
+		_ = c.Error(errors.NewDatabaseError("Failed to delete group", err))
 		return
 	}
-
-	var input struct {
-		WordID int `json:"word_id" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&input); err != nil {
-		_ = c.Error(errors.NewValidationError(
-			"Invalid word data",
-			"The word ID is required",
-			map[string]string{"word_id": "Word ID is required"},
-		))
+	if !deleted {
+		_ = c.Error(errors.New(errors.CodeGroupNotFound))
 		return
 	}
 
-	if err := h.service.AddWordToGroup(input.WordID, groupID); err != nil {
+	c.Status(http.StatusNoContent)
+}
+
+// AddWordToGroup handles POST /api/groups/:id/words
+func (h *GroupHandler) AddWordToGroup(c *gin.Context) {
+	input := middleware.Input[AddWordToGroupRequest](c)
+
+	if err := h.service.AddWordToGroup(middleware.CurrentUser(c), input.WordID, input.GroupID); err != nil {
 		_ = c.Error(errors.NewDatabaseError("Failed to add word to group", err))
 		return
 	}
@@ -111,29 +139,11 @@ func (h *GroupHandler) AddWordToGroup(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
-// RemoveWordFromGroup handles DELETE /api/groups/:groupId/words/:wordId
+// RemoveWordFromGroup handles DELETE /api/groups/:id/words/:wordId
 func (h *GroupHandler) RemoveWordFromGroup(c *gin.Context) {
-	groupID, err := strconv.Atoi(c.Param("groupId"))
-	if err != nil {
-		_ = c.Error(errors.NewInvalidInputError(
-			"Invalid group ID",
-			"The group ID must be a valid number",
-			map[string]string{"groupId": c.Param("groupId")},
-		))
-		return
-	}
-
-	wordID, err := strconv.Atoi(c.Param("wordId"))
-	if err != nil {
-		_ = c.Error(errors.NewInvalidInputError(
-			"Invalid word ID",
-			"The word ID must be a valid number",
-			map[string]string{"wordId": c.Param("wordId")},
-		))
-		return
-	}
+	params := middleware.Input[GroupWordParams](c)
 
-	if err := h.service.RemoveWordFromGroup(wordID, groupID); err != nil {
+	if err := h.service.RemoveWordFromGroup(middleware.CurrentUser(c), params.WordID, params.GroupID); err != nil {
 		_ = c.Error(errors.NewDatabaseError("Failed to remove word from group", err))
 		return
 	}
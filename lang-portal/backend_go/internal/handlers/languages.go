@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+type LanguageHandler struct {
+	service *service.LanguageService
+}
+
+// CreateLanguageRequest is the body of POST /api/languages
+type CreateLanguageRequest struct {
+	Code       string `json:"code" binding:"required,min=2,max=16"`
+	Name       string `json:"name" binding:"required,max=100"`
+	Script     string `json:"script" binding:"required,len=4,alpha"`
+	HasReading bool   `json:"has_reading"`
+}
+
+func NewLanguageHandler(service *service.LanguageService) *LanguageHandler {
+	return &LanguageHandler{service: service}
+}
+
+// GetLanguages handles GET /api/languages
+func (h *LanguageHandler) GetLanguages(c *gin.Context) {
+	languages, err := h.service.GetLanguages()
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch languages", err))
+		return
+	}
+	c.JSON(http.StatusOK, languages)
+}
+
+// CreateLanguage handles POST /api/languages
+func (h *LanguageHandler) CreateLanguage(c *gin.Context) {
+	input := middleware.Input[CreateLanguageRequest](c)
+
+	language, err := h.service.CreateLanguage(service.Language{
+		Code:       input.Code,
+		Name:       input.Name,
+		Script:     input.Script,
+		HasReading: input.HasReading,
+	})
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to create language", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, language)
+}
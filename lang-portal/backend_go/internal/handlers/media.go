@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+// uploadField is the multipart form field holding an uploaded file
+const uploadField = "file"
+
+type MediaHandler struct {
+	service *service.MediaService
+}
+
+// AudioParams holds the parameters of /api/words/:id/audio. Example selects
+// the example sentence at that position instead of the word itself.
+type AudioParams struct {
+	ID      int  `uri:"id" json:"-" binding:"required,min=1"`
+	Example *int `form:"example" binding:"omitempty,min=0"`
+}
+
+// ImageParams holds the path parameters of /api/words/:id/images/:imageId
+type ImageParams struct {
+	WordID  int `uri:"id" json:"-" binding:"required,min=1"`
+	ImageID int `uri:"imageId" json:"-" binding:"omitempty,min=1"`
+}
+
+// MediaParams holds the path parameter of /api/media/:hash
+type MediaParams struct {
+	Hash string `uri:"hash" json:"-" binding:"required,len=64,hexadecimal,lowercase"`
+}
+
+// immutableCache lets clients keep content-addressed media forever, since
+// its URL changes whenever the content does
+const immutableCache = "public, max-age=31536000, immutable"
+
+func NewMediaHandler(service *service.MediaService) *MediaHandler {
+	return &MediaHandler{service: service}
+}
+
+// UploadAudio handles POST /api/words/:id/audio. The file is sent either as
+// the raw request body or in the "file" field of a multipart form.
+func (h *MediaHandler) UploadAudio(c *gin.Context) {
+	params := middleware.Input[AudioParams](c)
+
+	body, err := uploadedFile(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer body.Close()
+
+	clip, err := h.service.AttachAudio(params.ID, params.Example, body)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to store audio", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, clip)
+}
+
+// GetAudio handles GET /api/words/:id/audio. Range and conditional requests
+// are supported.
+func (h *MediaHandler) GetAudio(c *gin.Context) {
+	params := middleware.Input[AudioParams](c)
+
+	file, err := h.service.OpenAudio(params.ID, params.Example)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to open audio", err))
+		return
+	}
+	if file == nil {
+		_ = c.Error(errors.New(errors.CodeAudioNotFound))
+		return
+	}
+	serveMedia(c, file, "no-cache")
+}
+
+// UploadImage handles POST /api/words/:id/images. The image is sent like
+// audio, and the response lists the URLs of its resized variants.
+func (h *MediaHandler) UploadImage(c *gin.Context) {
+	params := middleware.Input[ImageParams](c)
+
+	body, err := uploadedFile(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer body.Close()
+
+	image, err := h.service.AttachImage(params.WordID, body)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to store image", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, image)
+}
+
+// DeleteImage handles DELETE /api/words/:id/images/:imageId
+func (h *MediaHandler) DeleteImage(c *gin.Context) {
+	params := middleware.Input[ImageParams](c)
+
+	deleted, err := h.service.DeleteImage(params.WordID, params.ImageID)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to delete image", err))
+		return
+	}
+	if !deleted {
+		_ = c.Error(errors.New(errors.CodeImageNotFound))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetMedia handles GET /api/media/:hash
+func (h *MediaHandler) GetMedia(c *gin.Context) {
+	params := middleware.Input[MediaParams](c)
+
+	file, err := h.service.OpenMedia(params.Hash)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to open media", err))
+		return
+	}
+	if file == nil {
+		_ = c.Error(errors.New(errors.CodeMediaNotFound))
+		return
+	}
+	serveMedia(c, file, immutableCache)
+}
+
+// serveMedia streams a media file with its validators, answering range and
+// conditional requests
+func serveMedia(c *gin.Context, file *service.MediaFile, cacheControl string) {
+	defer file.Content.Close()
+
+	c.Header("Content-Type", file.ContentType)
+	c.Header("ETag", fmt.Sprintf("%q", file.Hash))
+	c.Header("Cache-Control", cacheControl)
+	http.ServeContent(c.Writer, c.Request, "", file.CreatedAt, file.Content)
+}
+
+// uploadedFile returns the uploaded file of a request, read from the "file"
+// field of a multipart form or else from the raw body
+func uploadedFile(c *gin.Context) (io.ReadCloser, error) {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return c.Request.Body, nil
+	}
+
+	missing := errors.New(errors.CodeValidationFailed).WithData([]map[string]string{{
+		"field":   uploadField,
+		"tag":     "required",
+		"value":   "",
+		"message": "The multipart form must contain the file in the \"file\" field",
+	}})
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, missing
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, missing
+		}
+		if part.FormName() == uploadField {
+			return part, nil
+		}
+		part.Close()
+	}
+}
@@ -0,0 +1,611 @@
+package handlers
+
+import (
+	"net/http"
+
+	"lang-portal/internal/errors"
+	"lang-portal/internal/openapi"
+	"lang-portal/internal/service"
+)
+
+// APIInfo describes the API in the generated OpenAPI document
+var APIInfo = openapi.Info{
+	Title:   "Lang Portal API",
+	Version: "1.0.0",
+}
+
+// Operations documents every route registered by the server. Each entry must
+// match a registered route; the router test fails when they drift apart.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		// Dashboard
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/dashboard/last_study_session",
+			Summary:  "Get the most recent study session",
+			Tags:     []string{"dashboard"},
+			Query:    DashboardQuery{},
+			Response: service.StudySession{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/dashboard/study_progress",
+			Summary:  "Get word mastery progress",
+			Tags:     []string{"dashboard"},
+			Query:    DashboardQuery{},
+			Response: service.StudyProgress{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/dashboard/quick-stats",
+			Summary:  "Get quick overview statistics",
+			Tags:     []string{"dashboard"},
+			Query:    DashboardQuery{},
+			Response: service.QuickStats{},
+		},
+
+		// Languages
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/languages",
+			Summary:  "List the languages taught through the portal",
+			Tags:     []string{"languages"},
+			Response: []service.Language{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/languages",
+			Summary:  "Add a language",
+			Tags:     []string{"languages"},
+			Body:     CreateLanguageRequest{},
+			Response: service.Language{},
+			Status:   http.StatusCreated,
+		},
+
+		// Words
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/words",
+			Summary:  "List words with review statistics",
+			Tags:     []string{"words"},
+			Query:    WordListQuery{},
+			Response: service.WordPagination{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/words/:id",
+			Summary:  "Get a word",
+			Tags:     []string{"words"},
+			Response: service.Word{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/words",
+			Summary:  "Create a word",
+			Tags:     []string{"words"},
+			Body:     CreateWordRequest{},
+			Response: service.Word{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:   http.MethodPut,
+			Path:     "/api/words/:id",
+			Summary:  "Update a word",
+			Tags:     []string{"words"},
+			Body:     UpdateWordRequest{},
+			Response: service.Word{},
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/api/words/:id",
+			Summary: "Move a word to the trash",
+			Tags:    []string{"words"},
+			Status:  http.StatusNoContent,
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/words/:id/audio",
+			Summary:  "Upload the pronunciation audio of a word or example sentence",
+			Tags:     []string{"words", "media"},
+			Query:    AudioParams{},
+			BodyType: "application/octet-stream",
+			Response: service.AudioClip{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/words/:id/audio",
+			Summary:     "Stream the pronunciation audio of a word or example sentence",
+			Tags:        []string{"words", "media"},
+			Query:       AudioParams{},
+			ContentType: "audio/*",
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/words/:id/images",
+			Summary:  "Upload a picture of a word, resized into thumbnails",
+			Tags:     []string{"words", "media"},
+			BodyType: "application/octet-stream",
+			Response: service.WordImage{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/api/words/:id/images/:imageId",
+			Summary: "Remove a picture from a word",
+			Tags:    []string{"words", "media"},
+			Status:  http.StatusNoContent,
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/words/:id/sentences",
+			Summary:  "Generate example sentences using only the vocabulary of a group",
+			Tags:     []string{"words"},
+			Body:     GenerateSentencesRequest{},
+			Response: service.SentenceSet{},
+		},
+
+		// Media
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/media/:hash",
+			Summary:     "Download a stored media file by the hash in its URL",
+			Tags:        []string{"media"},
+			ContentType: "application/octet-stream",
+		},
+
+		// Tags
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/tags",
+			Summary:  "Autocomplete tag names by prefix",
+			Tags:     []string{"tags"},
+			Query:    TagSearchQuery{},
+			Response: []service.Tag{},
+		},
+
+		// Groups
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/groups",
+			Summary:  "List groups with word counts",
+			Tags:     []string{"groups"},
+			Query:    GroupListQuery{},
+			Response: []service.Group{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/groups/:id",
+			Summary:  "Get a group and its words",
+			Tags:     []string{"groups"},
+			Response: service.GroupWithWords{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/groups",
+			Summary:  "Create a group",
+			Tags:     []string{"groups"},
+			Body:     CreateGroupRequest{},
+			Response: service.Group{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/api/groups/:id",
+			Summary: "Move a group to the trash",
+			Tags:    []string{"groups"},
+			Status:  http.StatusNoContent,
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/api/groups/:id/words",
+			Summary: "Add a word to a group",
+			Tags:    []string{"groups"},
+			Body:    AddWordToGroupRequest{},
+			Status:  http.StatusNoContent,
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/api/groups/:id/words/:wordId",
+			Summary: "Remove a word from a group",
+			Tags:    []string{"groups"},
+			Status:  http.StatusNoContent,
+		},
+
+		// Study
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/study/sessions",
+			Summary:  "Start a study session on a group or a tag query",
+			Tags:     []string{"study"},
+			Body:     CreateStudySessionRequest{},
+			Response: service.StudySession{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/study/sessions/:id/reviews",
+			Summary:  "Record a word review",
+			Tags:     []string{"study"},
+			Body:     AddWordReviewRequest{},
+			Response: service.WordReviewItem{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/study/sessions/:id/reviews",
+			Summary:  "List the reviews of a study session",
+			Tags:     []string{"study"},
+			Response: []service.WordReviewItem{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/study/sessions/:id/complete",
+			Summary:  "Complete a study session",
+			Tags:     []string{"study"},
+			Response: service.StudySession{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/study/sessions/:id/words",
+			Summary:  "List the words studied in a session",
+			Tags:     []string{"study"},
+			Response: []service.GroupWord{},
+		},
+
+		// Texts
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/texts",
+			Summary:  "List reading texts with the current learner's progress",
+			Tags:     []string{"texts"},
+			Query:    TextListQuery{},
+			Response: []service.Text{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/texts",
+			Summary:  "Add a reading text, linking its tokens to words",
+			Tags:     []string{"texts"},
+			Body:     CreateTextRequest{},
+			Response: service.TextDetails{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/texts/recommendation",
+			Summary:  "Recommend the next text whose unknown words are within a band",
+			Tags:     []string{"texts"},
+			Query:    TextRecommendationQuery{},
+			Response: service.Text{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/texts/:id",
+			Summary:  "Get a text with its tokens and the current learner's mastery of them",
+			Tags:     []string{"texts"},
+			Response: service.TextDetails{},
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/api/texts/:id",
+			Summary: "Delete a text",
+			Tags:    []string{"texts"},
+			Status:  http.StatusNoContent,
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/texts/analyze",
+			Summary:  "Lemmatize a text, report its vocabulary coverage and optionally create a group of its unknown words",
+			Tags:     []string{"texts"},
+			Body:     AnalyzeTextRequest{},
+			Response: service.TextAnalysis{},
+		},
+
+		// Grading
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/grade",
+			Summary:  "Grade a free-text translation and record reviews of its vocabulary",
+			Tags:     []string{"study"},
+			Body:     GradeRequest{},
+			Response: service.GradeResult{},
+		},
+
+		// Goals
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/goals",
+			Summary:  "List the current learner's goals with their progress",
+			Tags:     []string{"goals"},
+			Response: []service.Goal{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/goals",
+			Summary:  "Set a goal for the current learner, optionally with a reminder",
+			Tags:     []string{"goals"},
+			Body:     CreateGoalRequest{},
+			Response: service.Goal{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/api/goals/:id",
+			Summary: "Delete a goal of the current learner",
+			Tags:    []string{"goals"},
+			Status:  http.StatusNoContent,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/notifications",
+			Summary:  "List the goal reminders sent to the current learner",
+			Tags:     []string{"goals"},
+			Query:    NotificationListQuery{},
+			Response: []service.Notification{},
+		},
+
+		// Achievements
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/achievements",
+			Summary:  "Get the current learner's XP, level and achievements",
+			Tags:     []string{"achievements"},
+			Response: service.AchievementSummary{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/leaderboard",
+			Summary:  "Rank learners by XP",
+			Tags:     []string{"achievements"},
+			Query:    LeaderboardQuery{},
+			Response: []service.LeaderboardEntry{},
+		},
+
+		// Classes
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/classes",
+			Summary:  "List the classes the current user teaches or attends",
+			Tags:     []string{"classes"},
+			Response: []service.Class{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/classes",
+			Summary:  "Create a class taught by the current user",
+			Tags:     []string{"classes"},
+			Body:     CreateClassRequest{},
+			Response: service.Class{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/classes/:id",
+			Summary:  "Get a class with its students",
+			Tags:     []string{"classes"},
+			Response: service.ClassDetails{},
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/api/classes/:id",
+			Summary: "Delete a class with its assignments",
+			Tags:    []string{"classes"},
+			Status:  http.StatusNoContent,
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/api/classes/:id/students",
+			Summary: "Enrol a student in a class",
+			Tags:    []string{"classes"},
+			Body:    AddStudentRequest{},
+			Status:  http.StatusNoContent,
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/api/classes/:id/students/:userId",
+			Summary: "Remove a student from a class",
+			Tags:    []string{"classes"},
+			Status:  http.StatusNoContent,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/classes/:id/assignments",
+			Summary:  "List the assignments of a class with the students' progress",
+			Tags:     []string{"classes"},
+			Response: []service.Assignment{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/classes/:id/assignments",
+			Summary:  "Assign a group to a class with a due date and required accuracy",
+			Tags:     []string{"classes"},
+			Body:     CreateAssignmentRequest{},
+			Response: service.Assignment{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/api/classes/:id/assignments/:assignmentId",
+			Summary: "Delete an assignment",
+			Tags:    []string{"classes"},
+			Status:  http.StatusNoContent,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/classes/:id/reports/students",
+			Summary:  "Report each student's assignments and accuracy to the teacher",
+			Tags:     []string{"classes"},
+			Response: []service.StudentReport{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/classes/:id/reports/words",
+			Summary:  "Report how the class does on each word of its assigned groups",
+			Tags:     []string{"classes"},
+			Query:    WordReportQuery{},
+			Response: []service.WordReport{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/classes/:id/leaderboard",
+			Summary:  "Rank the students of a class by XP",
+			Tags:     []string{"classes", "achievements"},
+			Query:    ClassLeaderboardQuery{},
+			Response: []service.LeaderboardEntry{},
+		},
+
+		// Events
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/events",
+			Summary:     "Stream study and vocabulary events as server-sent events",
+			Tags:        []string{"events"},
+			Query:       EventStreamQuery{},
+			ContentType: "text/event-stream",
+		},
+
+		// Webhooks
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/webhooks",
+			Summary:  "List webhook subscriptions",
+			Tags:     []string{"webhooks"},
+			Response: []service.Webhook{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/webhooks",
+			Summary:  "Subscribe a URL to study and vocabulary events",
+			Tags:     []string{"webhooks"},
+			Body:     CreateWebhookRequest{},
+			Response: service.Webhook{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/api/webhooks/:id",
+			Summary: "Delete a webhook and its deliveries",
+			Tags:    []string{"webhooks"},
+			Status:  http.StatusNoContent,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/webhooks/:id/deliveries",
+			Summary:  "List the deliveries of a webhook with their latest attempt",
+			Tags:     []string{"webhooks"},
+			Query:    DeliveryListQuery{},
+			Response: []service.WebhookDelivery{},
+		},
+
+		// Trash
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/trash",
+			Summary:  "List deleted words and groups",
+			Tags:     []string{"trash"},
+			Response: []service.TrashItem{},
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/api/trash/:type/:id/restore",
+			Summary: "Restore a deleted word or group",
+			Tags:    []string{"trash"},
+			Status:  http.StatusNoContent,
+		},
+
+		// Users
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/users",
+			Summary:  "List users (teachers and admins only)",
+			Tags:     []string{"users"},
+			Response: []service.User{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/users/me",
+			Summary:  "Get the user identified by the bearer token",
+			Tags:     []string{"users"},
+			Response: service.User{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/users/me/token",
+			Summary:  "Replace the caller's bearer token",
+			Tags:     []string{"users"},
+			Response: service.User{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/users",
+			Summary:  "Create a user and issue their bearer token (admins only)",
+			Tags:     []string{"users"},
+			Body:     CreateUserRequest{},
+			Response: service.User{},
+			Status:   http.StatusCreated,
+		},
+		{
+			Method:   http.MethodPut,
+			Path:     "/api/users/:id/role",
+			Summary:  "Change the role of a user",
+			Tags:     []string{"users"},
+			Body:     SetRoleRequest{},
+			Response: service.User{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/users/:id/token",
+			Summary:  "Issue a new bearer token to a user",
+			Tags:     []string{"users"},
+			Response: service.User{},
+		},
+
+		// Audit
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/audit",
+			Summary:  "List recorded changes to words and groups",
+			Tags:     []string{"audit"},
+			Query:    AuditListQuery{},
+			Response: []service.AuditEntry{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/api/audit/:id/revert",
+			Summary:  "Revert a recorded change",
+			Tags:     []string{"audit"},
+			Response: service.AuditEntry{},
+			Status:   http.StatusCreated,
+		},
+
+		// Meta
+		{
+			Method:  http.MethodGet,
+			Path:    "/api/openapi.json",
+			Summary: "Get this OpenAPI document",
+			Tags:    []string{"meta"},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/docs",
+			Summary:     "Browse the API documentation",
+			Tags:        []string{"meta"},
+			ContentType: "text/html",
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/api/errors",
+			Summary:  "List error codes with localized messages",
+			Tags:     []string{"meta"},
+			Response: []errors.CatalogEntry{},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/metrics",
+			Summary:     "Prometheus metrics",
+			Tags:        []string{"meta"},
+			ContentType: "text/plain",
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/llm"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+// Defaults of POST /api/words/:id/sentences
+const (
+	defaultSentenceCount      = 3
+	defaultSentenceDifficulty = llm.DifficultyEasy
+)
+
+type SentenceHandler struct {
+	service *service.SentenceService
+}
+
+// GenerateSentencesRequest is the body of POST /api/words/:id/sentences.
+// Count defaults to 3 and Difficulty to easy.
+type GenerateSentencesRequest struct {
+	ID         int    `uri:"id" json:"-" binding:"required,min=1"`
+	GroupID    int    `json:"group_id" binding:"required,min=1"`
+	Count      int    `json:"count" binding:"omitempty,min=1,max=10"`
+	Difficulty string `json:"difficulty" binding:"omitempty,oneof=easy medium hard"`
+	Refresh    bool   `json:"refresh"`
+}
+
+func NewSentenceHandler(service *service.SentenceService) *SentenceHandler {
+	return &SentenceHandler{service: service}
+}
+
+// GenerateSentences handles POST /api/words/:id/sentences
+func (h *SentenceHandler) GenerateSentences(c *gin.Context) {
+	input := middleware.Input[GenerateSentencesRequest](c)
+
+	opts := service.SentenceOptions{
+		GroupID:    input.GroupID,
+		Count:      input.Count,
+		Difficulty: input.Difficulty,
+		Refresh:    input.Refresh,
+	}
+	if opts.Count == 0 {
+		opts.Count = defaultSentenceCount
+	}
+	if opts.Difficulty == "" {
+		opts.Difficulty = defaultSentenceDifficulty
+	}
+
+	set, err := h.service.GenerateSentences(c.Request.Context(), input.ID, opts)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to generate sentences", err))
+		return
+	}
+	c.JSON(http.StatusOK, set)
+}
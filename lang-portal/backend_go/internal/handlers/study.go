@@ -2,10 +2,10 @@ package handlers
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
 	"lang-portal/internal/service"
 )
 
@@ -13,26 +13,47 @@ type StudyHandler struct {
 	service *service.StudyService
 }
 
+// SessionIDParams holds the path parameters of /api/study/sessions/:id
+type SessionIDParams struct {
+	ID int `uri:"id" json:"-" binding:"required,min=1"`
+}
+
+// CreateStudySessionRequest is the body of POST /api/study/sessions. A
+// session studies either a group, in the activity StudyActivityID if it is
+// given, or the words carrying all of Tags, limited to Language if it is
+// given.
+type CreateStudySessionRequest struct {
+	GroupID         int      `json:"group_id" binding:"required_without=Tags,excluded_with=Tags,omitempty,min=1"`
+	StudyActivityID *int     `json:"study_activity_id" binding:"excluded_with=Tags,omitempty,min=1"`
+	Tags            []string `json:"tags" binding:"omitempty,min=1,max=10,dive,notblank,max=50"`
+	Language        string   `json:"language" binding:"excluded_with=GroupID,max=16"`
+}
+
+// AddWordReviewRequest is the input of POST /api/study/sessions/:id/reviews.
+// Either the outcome is given as correct, or the learner's answer is given
+// and checked against every gloss of the word.
+type AddWordReviewRequest struct {
+	SessionID int     `uri:"id" json:"-" binding:"required,min=1"`
+	WordID    int     `json:"word_id" binding:"required,min=1"`
+	Correct   *bool   `json:"correct" binding:"required_without=Answer,excluded_with=Answer"`
+	Answer    *string `json:"answer" binding:"omitempty,min=1,max=200"`
+}
+
 func NewStudyHandler(service *service.StudyService) *StudyHandler {
 	return &StudyHandler{service: service}
 }
 
 // CreateStudySession handles POST /api/study/sessions
 func (h *StudyHandler) CreateStudySession(c *gin.Context) {
-	var input struct {
-		GroupID int `json:"group_id" binding:"required"`
+	input := middleware.Input[CreateStudySessionRequest](c)
+
+	var session *service.StudySession
+	var err error
+	if len(input.Tags) > 0 {
+		session, err = h.service.CreateTagStudySession(middleware.CurrentUser(c), input.Tags, input.Language)
+	} else {
+		session, err = h.service.CreateStudySession(middleware.CurrentUser(c), input.GroupID, input.StudyActivityID)
 	}
-
-	if err := c.ShouldBindJSON(&input); err != nil {
-		_ = c.Error(errors.NewValidationError(
-			"Invalid study session data",
-			"The group ID is required",
-			map[string]string{"group_id": "Group ID is required"},
-		))
-		return
-	}
-
-	session, err := h.service.CreateStudySession(input.GroupID)
 	if err != nil {
 		_ = c.Error(errors.NewDatabaseError("Failed to create study session", err))
 		return
@@ -43,65 +64,61 @@ func (h *StudyHandler) CreateStudySession(c *gin.Context) {
 
 // AddWordReview handles POST /api/study/sessions/:id/reviews
 func (h *StudyHandler) AddWordReview(c *gin.Context) {
-	sessionID, err := strconv.Atoi(c.Param("id"))
+	input := middleware.Input[AddWordReviewRequest](c)
+
+	var review *service.WordReviewItem
+	var err error
+	if input.Answer != nil {
+		review, err = h.service.AddAnswerReview(middleware.CurrentUser(c), input.SessionID, input.WordID, *input.Answer)
+	} else {
+		review, err = h.service.AddWordReview(middleware.CurrentUser(c), input.SessionID, input.WordID, *input.Correct)
+	}
 	if err != nil {
-		_ = c.Error(errors.NewInvalidInputError(
-			"Invalid session ID",
-			"The session ID must be a valid number",
-			map[string]string{"id": c.Param("id")},
-		))
+		_ = c.Error(errors.NewDatabaseError("Failed to add word review", err))
 		return
 	}
 
-	var input struct {
-		WordID  int  `json:"word_id" binding:"required"`
-		Correct bool `json:"correct" binding:"required"`
-	}
+	c.JSON(http.StatusCreated, review)
+}
+
+// CompleteStudySession handles POST /api/study/sessions/:id/complete
+func (h *StudyHandler) CompleteStudySession(c *gin.Context) {
+	params := middleware.Input[SessionIDParams](c)
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		_ = c.Error(errors.NewValidationError(
-			"Invalid word review data",
-			"Both word ID and correct status are required",
-			map[string]interface{}{
-				"word_id": "Word ID is required",
-				"correct": "Correct status is required",
-			},
-		))
+	session, err := h.service.CompleteStudySession(middleware.CurrentUser(c), params.ID)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to complete study session", err))
 		return
 	}
 
-	review, err := h.service.AddWordReview(sessionID, input.WordID, input.Correct)
+	c.JSON(http.StatusOK, session)
+}
+
+// GetSessionWords handles GET /api/study/sessions/:id/words
+func (h *StudyHandler) GetSessionWords(c *gin.Context) {
+	params := middleware.Input[SessionIDParams](c)
+
+	words, err := h.service.GetSessionWords(middleware.CurrentUser(c), params.ID)
 	if err != nil {
-		_ = c.Error(errors.NewDatabaseError("Failed to add word review", err))
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch session words", err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, review)
+	c.JSON(http.StatusOK, words)
 }
 
 // GetSessionReviews handles GET /api/study/sessions/:id/reviews
 func (h *StudyHandler) GetSessionReviews(c *gin.Context) {
-	sessionID, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		_ = c.Error(errors.NewInvalidInputError(
-			"Invalid session ID",
-			"The session ID must be a valid number",
-			map[string]string{"id": c.Param("id")},
-		))
-		return
-	}
+	params := middleware.Input[SessionIDParams](c)
 
-	reviews, err := h.service.GetSessionReviews(sessionID)
+	reviews, err := h.service.GetSessionReviews(middleware.CurrentUser(c), params.ID)
 	if err != nil {
 		_ = c.Error(errors.NewDatabaseError("Failed to fetch session reviews", err))
 		return
 	}
 
 	if len(reviews) == 0 {
-		_ = c.Error(errors.NewNotFoundError(
-			"No reviews found",
-			"The study session has no recorded reviews",
-		))
+		_ = c.Error(errors.New(errors.CodeNoSessionReviews))
 		return
 	}
 
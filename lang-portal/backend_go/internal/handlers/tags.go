@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+type TagHandler struct {
+	service *service.TagService
+}
+
+// TagSearchQuery holds the query parameters of GET /api/tags. Q is the prefix
+// typed so far; without it the most used tags are listed.
+type TagSearchQuery struct {
+	Q     string `form:"q" binding:"max=50"`
+	Limit int    `form:"limit,default=10" binding:"min=1,max=50"`
+}
+
+func NewTagHandler(service *service.TagService) *TagHandler {
+	return &TagHandler{service: service}
+}
+
+// SearchTags handles GET /api/tags, used to autocomplete tag names
+func (h *TagHandler) SearchTags(c *gin.Context) {
+	query := middleware.Input[TagSearchQuery](c)
+
+	tags, err := h.service.SearchTags(query.Q, query.Limit)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to search tags", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
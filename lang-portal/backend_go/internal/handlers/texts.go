@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+type TextHandler struct {
+	service *service.TextService
+}
+
+// TextListQuery holds the query parameters of GET /api/texts
+type TextListQuery struct {
+	Lang string `form:"lang" binding:"max=16"`
+}
+
+// TextIDParams holds the path parameters of /api/texts/:id
+type TextIDParams struct {
+	ID int `uri:"id" json:"-" binding:"required,min=1"`
+}
+
+// CreateTextRequest is the body of POST /api/texts
+type CreateTextRequest struct {
+	Title      string  `json:"title" binding:"required,notblank,max=200"`
+	Author     *string `json:"author" binding:"omitempty,notblank,max=100"`
+	Language   string  `json:"language" binding:"max=16"`
+	Difficulty string  `json:"difficulty" binding:"required,oneof=easy medium hard"`
+	Body       string  `json:"body" binding:"required,notblank,max=50000"`
+}
+
+// TextRecommendationQuery holds the query parameters of
+// GET /api/texts/recommendation: the band of words the learner has not
+// mastered that the recommended text may contain
+type TextRecommendationQuery struct {
+	Lang       string `form:"lang" binding:"max=16"`
+	MinUnknown int    `form:"min_unknown,default=1" binding:"min=0,max=10000"`
+	MaxUnknown int    `form:"max_unknown,default=10" binding:"min=0,max=10000,gtefield=MinUnknown"`
+}
+
+// AnalyzeTextRequest is the body of POST /api/texts/analyze. Giving a
+// GroupName creates a group of that name from the unknown lemmas.
+type AnalyzeTextRequest struct {
+	Text      string `json:"text" binding:"required,notblank,max=50000"`
+	Language  string `json:"language" binding:"max=16"`
+	GroupName string `json:"group_name" binding:"omitempty,notblank,max=100"`
+}
+
+func NewTextHandler(service *service.TextService) *TextHandler {
+	return &TextHandler{service: service}
+}
+
+// AnalyzeText handles POST /api/texts/analyze. Creating a group adds words
+// to the shared vocabulary, which curators alone may do.
+func (h *TextHandler) AnalyzeText(c *gin.Context) {
+	input := middleware.Input[AnalyzeTextRequest](c)
+	if input.GroupName != "" {
+		if err := middleware.Authorize(c, service.PermissionCurate); err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+
+	analysis, err := h.service.AnalyzeText(middleware.CurrentUser(c), service.AnalyzeInput{
+		Text:      input.Text,
+		Language:  input.Language,
+		GroupName: input.GroupName,
+	})
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to analyze text", err))
+		return
+	}
+
+	status := http.StatusOK
+	if analysis.Group != nil {
+		status = http.StatusCreated
+	}
+	c.JSON(status, analysis)
+}
+
+// GetTexts handles GET /api/texts
+func (h *TextHandler) GetTexts(c *gin.Context) {
+	query := middleware.Input[TextListQuery](c)
+
+	texts, err := h.service.GetTexts(middleware.CurrentUser(c), service.TextFilter{Language: query.Lang})
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch texts", err))
+		return
+	}
+	c.JSON(http.StatusOK, texts)
+}
+
+// GetText handles GET /api/texts/:id
+func (h *TextHandler) GetText(c *gin.Context) {
+	params := middleware.Input[TextIDParams](c)
+
+	text, err := h.service.GetText(middleware.CurrentUser(c), params.ID)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch text", err))
+		return
+	}
+	if text == nil {
+		_ = c.Error(errors.New(errors.CodeTextNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, text)
+}
+
+// CreateText handles POST /api/texts
+func (h *TextHandler) CreateText(c *gin.Context) {
+	input := middleware.Input[CreateTextRequest](c)
+
+	text, err := h.service.CreateText(middleware.CurrentUser(c), service.TextInput{
+		Language:   input.Language,
+		Title:      input.Title,
+		Author:     input.Author,
+		Difficulty: input.Difficulty,
+		Body:       input.Body,
+	})
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to create text", err))
+		return
+	}
+	c.JSON(http.StatusCreated, text)
+}
+
+// DeleteText handles DELETE /api/texts/:id
+func (h *TextHandler) DeleteText(c *gin.Context) {
+	params := middleware.Input[TextIDParams](c)
+
+	deleted, err := h.service.DeleteText(params.ID)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to delete text", err))
+		return
+	}
+	if !deleted {
+		_ = c.Error(errors.New(errors.CodeTextNotFound))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RecommendText handles GET /api/texts/recommendation
+func (h *TextHandler) RecommendText(c *gin.Context) {
+	query := middleware.Input[TextRecommendationQuery](c)
+
+	text, err := h.service.RecommendText(middleware.CurrentUser(c), query.Lang, query.MinUnknown, query.MaxUnknown)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to recommend a text", err))
+		return
+	}
+	c.JSON(http.StatusOK, text)
+}
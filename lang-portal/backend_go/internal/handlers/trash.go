@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+type TrashHandler struct {
+	service *service.TrashService
+}
+
+// TrashItemParams holds the path parameters of /api/trash/:type/:id/restore
+type TrashItemParams struct {
+	Type string `uri:"type" json:"-" binding:"required,oneof=words groups"`
+	ID   int    `uri:"id" json:"-" binding:"required,min=1"`
+}
+
+func NewTrashHandler(service *service.TrashService) *TrashHandler {
+	return &TrashHandler{service: service}
+}
+
+// GetTrash handles GET /api/trash
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	items, err := h.service.ListTrash()
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch trash", err))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// RestoreItem handles POST /api/trash/:type/:id/restore
+func (h *TrashHandler) RestoreItem(c *gin.Context) {
+	params := middleware.Input[TrashItemParams](c)
+
+	restored, err := h.service.Restore(middleware.CurrentUser(c), params.Type, params.ID)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to restore item", err))
+		return
+	}
+	if !restored {
+		_ = c.Error(errors.New(errors.CodeTrashItemNotFound).WithData(map[string]interface{}{
+			"type": params.Type,
+			"id":   params.ID,
+		}))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
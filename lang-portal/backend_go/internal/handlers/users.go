@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+type UserHandler struct {
+	service *service.UserService
+}
+
+// CreateUserRequest is the body of POST /api/users. Role defaults to
+// learner.
+type CreateUserRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+	Role string `json:"role" binding:"omitempty,oneof=admin teacher learner"`
+}
+
+// SetRoleRequest is the body of PUT /api/users/:id/role
+type SetRoleRequest struct {
+	ID   int    `uri:"id" json:"-" binding:"required,min=1"`
+	Role string `json:"role" binding:"required,oneof=admin teacher learner"`
+}
+
+// UserIDParams identifies the user in /api/users/:id routes
+type UserIDParams struct {
+	ID int `uri:"id" json:"-" binding:"required,min=1"`
+}
+
+func NewUserHandler(service *service.UserService) *UserHandler {
+	return &UserHandler{service: service}
+}
+
+// GetUsers handles GET /api/users
+func (h *UserHandler) GetUsers(c *gin.Context) {
+	users, err := h.service.GetUsers()
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch users", err))
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// GetCurrentUser handles GET /api/users/me
+func (h *UserHandler) GetCurrentUser(c *gin.Context) {
+	c.JSON(http.StatusOK, middleware.CurrentUser(c))
+}
+
+// CreateUser handles POST /api/users. The response carries the new user's
+// bearer token.
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	input := middleware.Input[CreateUserRequest](c)
+	if input.Role == "" {
+		input.Role = service.RoleLearner
+	}
+
+	user, err := h.service.CreateUser(input.Name, input.Role)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to create user", err))
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// SetRole handles PUT /api/users/:id/role
+func (h *UserHandler) SetRole(c *gin.Context) {
+	input := middleware.Input[SetRoleRequest](c)
+
+	user, err := h.service.SetRole(input.ID, input.Role)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to set role", err))
+		return
+	}
+	if user == nil {
+		_ = c.Error(errors.New(errors.CodeResourceNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// IssueOwnToken handles POST /api/users/me/token. The caller's old token
+// stops working.
+func (h *UserHandler) IssueOwnToken(c *gin.Context) {
+	h.issueToken(c, middleware.CurrentUser(c).ID)
+}
+
+// IssueToken handles POST /api/users/:id/token, letting an admin hand a new
+// token to a user who lost theirs
+func (h *UserHandler) IssueToken(c *gin.Context) {
+	h.issueToken(c, middleware.Input[UserIDParams](c).ID)
+}
+
+func (h *UserHandler) issueToken(c *gin.Context, id int) {
+	user, err := h.service.IssueToken(id)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to issue token", err))
+		return
+	}
+	if user == nil {
+		_ = c.Error(errors.New(errors.CodeResourceNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
+	"lang-portal/internal/service"
+)
+
+type WebhookHandler struct {
+	service *service.WebhookService
+}
+
+// CreateWebhookRequest is the body of POST /api/webhooks. An empty Events
+// subscribes to every event; an empty Secret is generated.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,http_url,max=2000"`
+	Events []string `json:"events" binding:"omitempty,max=20,dive,oneof=session.created review.added session.completed word.created word.updated word.deleted achievement.earned"`
+	Secret string   `json:"secret" binding:"omitempty,min=16,max=200"`
+}
+
+// WebhookParams holds the path parameters of /api/webhooks/:id
+type WebhookParams struct {
+	ID int `uri:"id" json:"-" binding:"required,min=1"`
+}
+
+// DeliveryListQuery holds the parameters of GET /api/webhooks/:id/deliveries
+type DeliveryListQuery struct {
+	ID    int `uri:"id" json:"-" binding:"required,min=1"`
+	Limit int `form:"limit,default=50" binding:"min=1,max=500"`
+}
+
+func NewWebhookHandler(service *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// GetWebhooks handles GET /api/webhooks
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.service.GetWebhooks()
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch webhooks", err))
+		return
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// CreateWebhook handles POST /api/webhooks. The response is the only one
+// that includes the secret.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	input := middleware.Input[CreateWebhookRequest](c)
+
+	webhook, err := h.service.CreateWebhook(middleware.CurrentUser(c), input.URL, input.Events, input.Secret)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to create webhook", err))
+		return
+	}
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// DeleteWebhook handles DELETE /api/webhooks/:id
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	params := middleware.Input[WebhookParams](c)
+
+	deleted, err := h.service.DeleteWebhook(params.ID)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to delete webhook", err))
+		return
+	}
+	if !deleted {
+		_ = c.Error(errors.New(errors.CodeWebhookNotFound))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetDeliveries handles GET /api/webhooks/:id/deliveries
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	query := middleware.Input[DeliveryListQuery](c)
+
+	deliveries, err := h.service.GetDeliveries(query.ID, query.Limit)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch webhook deliveries", err))
+		return
+	}
+	if deliveries == nil {
+		_ = c.Error(errors.New(errors.CodeWebhookNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
@@ -1,11 +1,12 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"lang-portal/internal/errors"
+	"lang-portal/internal/middleware"
 	"lang-portal/internal/service"
 )
 
@@ -13,49 +14,105 @@ type WordHandler struct {
 	service *service.WordService
 }
 
+// WordListQuery holds the query parameters of GET /api/words. Repeated tag
+// parameters select the words carrying all of them.
+type WordListQuery struct {
+	Page         int      `form:"page,default=1" binding:"min=1"`
+	ItemsPerPage int      `form:"items_per_page,default=100" binding:"min=1,max=100"`
+	Lang         string   `form:"lang" binding:"max=16"`
+	Tags         []string `form:"tag" binding:"omitempty,max=10,dive,notblank,max=50"`
+}
+
+// WordIDParams holds the path parameters of /api/words/:id
+type WordIDParams struct {
+	ID int `uri:"id" json:"-" binding:"required,min=1"`
+}
+
+// WordFields are the editable fields of a word. The translation may be left
+// out when senses are given; it is then the gloss of the first sense.
+type WordFields struct {
+	Language     string          `json:"language" binding:"max=16"`
+	Term         string          `json:"term" binding:"required,max=100"`
+	Translation  string          `json:"translation" binding:"required_without=Senses,max=200"`
+	Reading      *string         `json:"reading" binding:"omitempty,max=200"`
+	Romanization *string         `json:"romanization" binding:"omitempty,max=200"`
+	Parts        json.RawMessage `json:"parts"`
+	Senses       []SenseInput    `json:"senses" binding:"omitempty,min=1,max=20,unique=Gloss,dive"`
+	Examples     []ExampleInput  `json:"examples" binding:"omitempty,max=20,dive"`
+	Tags         []string        `json:"tags" binding:"omitempty,max=20,dive,notblank,max=50"`
+}
+
+// SenseInput is one gloss of a word. Lower priorities come first.
+type SenseInput struct {
+	Gloss    string `json:"gloss" binding:"required,max=200"`
+	Priority int    `json:"priority" binding:"min=0"`
+}
+
+// ExampleInput is an example sentence with its translation and source
+type ExampleInput struct {
+	Sentence    string  `json:"sentence" binding:"required,max=1000"`
+	Translation *string `json:"translation" binding:"omitempty,max=1000"`
+	Source      *string `json:"source" binding:"omitempty,max=200"`
+}
+
+// CreateWordRequest is the body of POST /api/words
+type CreateWordRequest struct {
+	WordFields
+}
+
+// UpdateWordRequest is the input of PUT /api/words/:id
+type UpdateWordRequest struct {
+	ID int `uri:"id" json:"-" binding:"required,min=1"`
+	WordFields
+}
+
+// input converts the fields to the service's word input
+func (f WordFields) input() service.WordInput {
+	input := service.WordInput{
+		Language:     f.Language,
+		Term:         f.Term,
+		Translation:  f.Translation,
+		Reading:      f.Reading,
+		Romanization: f.Romanization,
+		Parts:        f.Parts,
+		Tags:         f.Tags,
+	}
+	for _, s := range f.Senses {
+		input.Senses = append(input.Senses, service.Sense{Gloss: s.Gloss, Priority: s.Priority})
+	}
+	for _, e := range f.Examples {
+		input.Examples = append(input.Examples, service.Example{
+			Sentence:    e.Sentence,
+			Translation: e.Translation,
+			Source:      e.Source,
+		})
+	}
+	return input
+}
+
 func NewWordHandler(service *service.WordService) *WordHandler {
 	return &WordHandler{service: service}
 }
 
 // GetWords handles the /api/words endpoint
 func (h *WordHandler) GetWords(c *gin.Context) {
-	// Parse and validate pagination parameters
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if err != nil || page < 1 {
-		_ = c.Error(errors.NewInvalidInputError(
-			"Invalid page number",
-			"Page number must be a positive integer",
-			map[string]string{"page": c.Query("page")},
-		))
-		return
-	}
-
-	itemsPerPage, err := strconv.Atoi(c.DefaultQuery("items_per_page", "100"))
-	if err != nil || itemsPerPage < 1 || itemsPerPage > 100 {
-		_ = c.Error(errors.NewInvalidInputError(
-			"Invalid items per page",
-			"Items per page must be between 1 and 100",
-			map[string]string{"items_per_page": c.Query("items_per_page")},
-		))
-		return
-	}
+	query := middleware.Input[WordListQuery](c)
 
-	words, err := h.service.GetWords(page, itemsPerPage)
+	words, err := h.service.GetWords(query.Page, query.ItemsPerPage, service.WordFilter{
+		Language: query.Lang,
+		Tags:     query.Tags,
+	})
 	if err != nil {
 		_ = c.Error(errors.NewDatabaseError("Failed to fetch words", err))
 		return
 	}
 
 	// Check if there are any words in the result
-	if len(words.Items) == 0 && words.TotalItems > 0 && page > 1 {
-		_ = c.Error(errors.NewInvalidInputError(
-			"Invalid page number",
-			"The requested page number exceeds the total number of pages",
-			map[string]interface{}{
-				"page":        page,
-				"total_pages": words.TotalPages,
-			},
-		))
+	if len(words.Items) == 0 && words.TotalItems > 0 && query.Page > 1 {
+		_ = c.Error(errors.New(errors.CodePageOutOfRange).WithData(map[string]interface{}{
+			"page":        query.Page,
+			"total_pages": words.TotalPages,
+		}))
 		return
 	}
 
@@ -64,28 +121,64 @@ func (h *WordHandler) GetWords(c *gin.Context) {
 
 // GetWordByID handles the /api/words/:id endpoint
 func (h *WordHandler) GetWordByID(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+	params := middleware.Input[WordIDParams](c)
+
+	word, err := h.service.GetWordByID(params.ID)
 	if err != nil {
-		_ = c.Error(errors.NewInvalidInputError(
-			"Invalid word ID",
-			"The word ID must be a valid number",
-			map[string]string{"id": c.Param("id")},
-		))
+		_ = c.Error(errors.NewDatabaseError("Failed to fetch word", err))
+		return
+	}
+	if word == nil {
+		_ = c.Error(errors.New(errors.CodeWordNotFound))
 		return
 	}
 
-	word, err := h.service.GetWordByID(id)
+	c.JSON(http.StatusOK, word)
+}
+
+// CreateWord handles POST /api/words
+func (h *WordHandler) CreateWord(c *gin.Context) {
+	input := middleware.Input[CreateWordRequest](c)
+
+	word, err := h.service.CreateWord(middleware.CurrentUser(c), input.input())
 	if err != nil {
-		_ = c.Error(errors.NewDatabaseError("Failed to fetch word", err))
+		_ = c.Error(errors.NewDatabaseError("Failed to create word", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, word)
+}
+
+// UpdateWord handles PUT /api/words/:id
+func (h *WordHandler) UpdateWord(c *gin.Context) {
+	input := middleware.Input[UpdateWordRequest](c)
+
+	word, err := h.service.UpdateWord(middleware.CurrentUser(c), input.ID, input.input())
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to update word", err))
 		return
 	}
 	if word == nil {
-		_ = c.Error(errors.NewNotFoundError(
-			"Word not found",
-			"The requested word does not exist",
-		))
+		_ = c.Error(errors.New(errors.CodeWordNotFound))
 		return
 	}
 
 	c.JSON(http.StatusOK, word)
 }
+
+// DeleteWord handles DELETE /api/words/:id by moving the word to the trash
+func (h *WordHandler) DeleteWord(c *gin.Context) {
+	params := middleware.Input[WordIDParams](c)
+
+	deleted, err := h.service.DeleteWord(middleware.CurrentUser(c), params.ID)
+	if err != nil {
+		_ = c.Error(errors.NewDatabaseError("Failed to delete word", err))
+		return
+	}
+	if !deleted {
+		_ = c.Error(errors.New(errors.CodeWordNotFound))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,159 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxResponseSize caps the completion response read from the API
+const maxResponseSize = 1 << 20
+
+// Client generates material with a chat completions API compatible with
+// OpenAI's, as also served by Ollama under /v1
+type Client struct {
+	baseURL string
+	model   string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewClient returns a client for the API at baseURL, such as
+// "http://localhost:11434/v1". apiKey may be empty for local servers.
+func NewClient(baseURL, model, apiKey string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Model returns the name of the model requested from the API
+func (c *Client) Model() string {
+	return c.model
+}
+
+// GenerateSentences asks the model for example sentences as a JSON object
+func (c *Client) GenerateSentences(ctx context.Context, req SentenceRequest) ([]Sentence, error) {
+	prompt, err := sentencePrompt(req)
+	if err != nil {
+		return nil, err
+	}
+	content, err := c.complete(ctx, sentenceInstructions, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	var answer struct {
+		Sentences []Sentence `json:"sentences"`
+	}
+	if err := json.Unmarshal([]byte(stripCodeFence(content)), &answer); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	for i := range answer.Sentences {
+		if answer.Sentences[i].Difficulty == "" {
+			answer.Sentences[i].Difficulty = req.Difficulty
+		}
+	}
+	return answer.Sentences, nil
+}
+
+// JudgeAnswer asks the model to grade an answer. The score it gives is
+// clamped to [0, 1].
+func (c *Client) JudgeAnswer(ctx context.Context, req GradeRequest) (*Judgment, error) {
+	prompt, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	content, err := c.complete(ctx, judgeInstructions, string(prompt))
+	if err != nil {
+		return nil, err
+	}
+
+	var judgment Judgment
+	if err := json.Unmarshal([]byte(stripCodeFence(content)), &judgment); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	judgment.Score = min(max(judgment.Score, 0), 1)
+	return &judgment, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []chatMessage     `json:"messages"`
+	Temperature    float64           `json:"temperature"`
+	ResponseFormat map[string]string `json:"response_format"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// complete sends a system and user message and returns the content of the
+// first choice, which the model is asked to format as a JSON object
+func (c *Client) complete(ctx context.Context, system, user string) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model: c.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		Temperature:    0.7,
+		ResponseFormat: map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("llm: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return "", fmt.Errorf("llm: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("llm: unexpected status %d: %.200s", resp.StatusCode, data)
+	}
+
+	var completion chatResponse
+	if err := json.Unmarshal(data, &completion); err != nil || len(completion.Choices) == 0 {
+		return "", fmt.Errorf("%w: no completion in %.200s", ErrInvalidResponse, data)
+	}
+	return completion.Choices[0].Message.Content, nil
+}
+
+// stripCodeFence removes the Markdown code fence some models wrap JSON in
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimPrefix(s, "json")
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
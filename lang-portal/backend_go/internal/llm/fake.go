@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Fake is a deterministic Provider for tests and offline development. It
+// strings the requested words together instead of calling a model, so the
+// same request always yields the same sentences, made only of the given
+// vocabulary, and it accepts only answers equal to an expected one.
+type Fake struct{}
+
+// Model returns "fake"
+func (Fake) Model() string {
+	return "fake"
+}
+
+// GenerateSentences builds each sentence from the word followed by one, two
+// or three vocabulary words depending on the difficulty, rotating through
+// the vocabulary from one sentence to the next
+func (Fake) GenerateSentences(ctx context.Context, req SentenceRequest) ([]Sentence, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	extra := 1
+	switch req.Difficulty {
+	case DifficultyMedium:
+		extra = 2
+	case DifficultyHard:
+		extra = 3
+	}
+	extra = min(extra, len(req.Vocabulary))
+
+	sentences := make([]Sentence, req.Count)
+	for i := range sentences {
+		terms := []string{req.Word.Term}
+		translations := []string{req.Word.Translation}
+		for j := 0; j < extra; j++ {
+			t := req.Vocabulary[(i*extra+j)%len(req.Vocabulary)]
+			terms = append(terms, t.Term)
+			translations = append(translations, t.Translation)
+		}
+		sentences[i] = Sentence{
+			Text:        capitalize(strings.Join(terms, " ")) + ".",
+			Translation: capitalize(strings.Join(translations, " ")) + ".",
+			Difficulty:  req.Difficulty,
+		}
+	}
+	return sentences, nil
+}
+
+// JudgeAnswer accepts an answer equal to one of the expected answers,
+// ignoring case, punctuation and spacing
+func (Fake) JudgeAnswer(ctx context.Context, req GradeRequest) (*Judgment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	answer := words(req.Answer)
+	for _, expected := range req.Expected {
+		if words(expected) == answer {
+			return &Judgment{Score: 1, Correct: true, Feedback: "The answer matches an expected translation."}, nil
+		}
+	}
+	return &Judgment{Score: 0, Correct: false, Feedback: "The answer differs from the expected translations."}, nil
+}
+
+// words lowercases s and keeps only its words, separated by single spaces
+func words(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
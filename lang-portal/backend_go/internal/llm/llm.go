@@ -0,0 +1,82 @@
+// Package llm generates learning material and grades answers with a large
+// language model. The Generator and Judge interfaces are implemented by
+// Client, which talks to any OpenAI-compatible chat completions API such as
+// Ollama, and by Fake, a deterministic stand-in for tests and offline
+// development.
+package llm
+
+import (
+	"context"
+	"errors"
+)
+
+// Sentence difficulties, from simple sentences to longer ones with more
+// vocabulary
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// Difficulties lists the sentence difficulties, easiest first
+var Difficulties = []string{DifficultyEasy, DifficultyMedium, DifficultyHard}
+
+// ErrInvalidResponse is returned when a model's answer cannot be parsed
+var ErrInvalidResponse = errors.New("llm: invalid response")
+
+// Term is a word of the vocabulary with its translation
+type Term struct {
+	Term        string `json:"term"`
+	Translation string `json:"translation"`
+}
+
+// SentenceRequest asks for Count example sentences in Language that use Word
+// and otherwise only words from Vocabulary
+type SentenceRequest struct {
+	Language   string `json:"language"`
+	Word       Term   `json:"word"`
+	Vocabulary []Term `json:"vocabulary"`
+	Difficulty string `json:"difficulty"`
+	Count      int    `json:"count"`
+}
+
+// Sentence is a generated example sentence with its translation
+type Sentence struct {
+	Text        string `json:"text"`
+	Translation string `json:"translation"`
+	Difficulty  string `json:"difficulty"`
+}
+
+// Generator produces learning material. Model identifies the model behind
+// it, so that results cached for one model are not served for another.
+type Generator interface {
+	Model() string
+	GenerateSentences(ctx context.Context, req SentenceRequest) ([]Sentence, error)
+}
+
+// GradeRequest asks whether Answer is an acceptable translation of Prompt
+// into Language, given the Expected answers
+type GradeRequest struct {
+	Language string   `json:"language"`
+	Prompt   string   `json:"prompt"`
+	Expected []string `json:"expected"`
+	Answer   string   `json:"answer"`
+}
+
+// Judgment is a model's assessment of an answer. Score ranges from 0 to 1.
+type Judgment struct {
+	Score    float64 `json:"score"`
+	Correct  bool    `json:"correct"`
+	Feedback string  `json:"feedback"`
+}
+
+// Judge grades free-text answers
+type Judge interface {
+	JudgeAnswer(ctx context.Context, req GradeRequest) (*Judgment, error)
+}
+
+// Provider is a model that both generates material and grades answers
+type Provider interface {
+	Generator
+	Judge
+}
@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var latinRequest = SentenceRequest{
+	Language:   "la",
+	Word:       Term{"amat", "loves"},
+	Vocabulary: []Term{{"puella", "girl"}, {"nauta", "sailor"}, {"rosam", "rose"}},
+	Difficulty: DifficultyMedium,
+	Count:      2,
+}
+
+func TestClientGeneratesSentences(t *testing.T) {
+	var received chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` +
+			"```json\\n{\\\"sentences\\\":[{\\\"text\\\":\\\"Puella nautam amat.\\\",\\\"translation\\\":\\\"The girl loves the sailor.\\\"}]}\\n```" +
+			`"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/v1/", "llama3.1", "secret", time.Second)
+	sentences, err := client.GenerateSentences(context.Background(), latinRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, []Sentence{{"Puella nautam amat.", "The girl loves the sailor.", DifficultyMedium}}, sentences)
+
+	assert.Equal(t, "llama3.1", received.Model)
+	if assert.Len(t, received.Messages, 2) {
+		assert.Equal(t, "system", received.Messages[0].Role)
+		assert.Contains(t, received.Messages[1].Content, `"term": "puella"`)
+	}
+	assert.Equal(t, "json_object", received.ResponseFormat["type"])
+}
+
+func TestClientReportsFailures(t *testing.T) {
+	status, content := http.StatusOK, `{"choices":[{"message":{"content":"Sure! Here are some sentences."}}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "llama3.1", "", time.Second)
+
+	_, err := client.GenerateSentences(context.Background(), latinRequest)
+	assert.ErrorIs(t, err, ErrInvalidResponse)
+
+	status, content = http.StatusNotFound, `{"error":"model not found"}`
+	_, err = client.GenerateSentences(context.Background(), latinRequest)
+	assert.ErrorContains(t, err, "unexpected status 404")
+}
+
+func TestFakeIsDeterministic(t *testing.T) {
+	first, err := Fake{}.GenerateSentences(context.Background(), latinRequest)
+	assert.NoError(t, err)
+	second, err := Fake{}.GenerateSentences(context.Background(), latinRequest)
+	assert.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, []Sentence{
+		{"Amat puella nauta.", "Loves girl sailor.", DifficultyMedium},
+		{"Amat rosam puella.", "Loves rose girl.", DifficultyMedium},
+	}, first)
+
+	// Without vocabulary the word stands alone
+	alone := SentenceRequest{Word: Term{"salve", "hello"}, Difficulty: DifficultyHard, Count: 1}
+	sentences, err := Fake{}.GenerateSentences(context.Background(), alone)
+	assert.NoError(t, err)
+	assert.Equal(t, "Salve.", sentences[0].Text)
+}
+
+func TestClientJudgesAnswers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var received chatRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if assert.Len(t, received.Messages, 2) {
+			assert.Contains(t, received.Messages[1].Content, `"answer": "puella nautam amat"`)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"{\"score\":1.5,\"correct\":true,\"feedback\":\"Well done.\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "llama3.1", "", time.Second)
+	judgment, err := client.JudgeAnswer(context.Background(), GradeRequest{
+		Language: "la",
+		Prompt:   "The girl loves the sailor.",
+		Expected: []string{"Puella nautam amat."},
+		Answer:   "puella nautam amat",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &Judgment{Score: 1, Correct: true, Feedback: "Well done."}, judgment, "the score is clamped")
+}
+
+func TestFakeJudgesExactAnswers(t *testing.T) {
+	req := GradeRequest{Expected: []string{"Puella nautam amat."}, Answer: "puella  nautam amat"}
+	judgment, err := Fake{}.JudgeAnswer(context.Background(), req)
+	assert.NoError(t, err)
+	assert.True(t, judgment.Correct)
+
+	req.Answer = "Nautam puella amat."
+	judgment, err = Fake{}.JudgeAnswer(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, judgment.Correct)
+	assert.Equal(t, 0.0, judgment.Score)
+}
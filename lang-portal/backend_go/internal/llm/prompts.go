@@ -0,0 +1,37 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const sentenceInstructions = `You write example sentences for language learners.
+Use the given word in every sentence. Apart from it, use only words from the
+given vocabulary, inflected as the grammar requires; do not introduce any other
+content words. Match the requested difficulty: easy sentences are short and
+simple, medium ones combine a few words, hard ones are longer and may use
+subordinate clauses.
+Answer with a JSON object of the form
+{"sentences": [{"text": "...", "translation": "...", "difficulty": "..."}]}
+where translation is an English translation of text.`
+
+const judgeInstructions = `You grade translations written by language learners.
+You are given the English prompt, the expected translations into the target
+language and the learner's answer. Accept answers with a different but
+grammatical word order, synonyms with the same meaning, and missing long-vowel
+marks. Reject answers with wrong inflections or a different meaning.
+Answer with a JSON object of the form
+{"score": 0.0, "correct": true, "feedback": "..."}
+where score ranges from 0 (unrelated) to 1 (perfect) and feedback briefly
+explains any mistakes to the learner in English.`
+
+// sentencePrompt renders a request as the user message, embedding it as JSON
+// so that terms are quoted unambiguously
+func sentencePrompt(req SentenceRequest) (string, error) {
+	data, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Write %d %s sentences in the language with code %q for this request:\n%s",
+		req.Count, req.Difficulty, req.Language, data), nil
+}
@@ -0,0 +1,48 @@
+package media
+
+import "bytes"
+
+// Audio content types accepted for upload
+const (
+	AudioMPEG = "audio/mpeg"
+	AudioOgg  = "audio/ogg"
+	AudioWAV  = "audio/wav"
+	AudioWebM = "audio/webm"
+	AudioMP4  = "audio/mp4"
+	AudioFLAC = "audio/flac"
+)
+
+// AudioTypes lists the accepted audio content types
+var AudioTypes = []string{AudioMPEG, AudioOgg, AudioWAV, AudioWebM, AudioMP4, AudioFLAC}
+
+// DetectAudio identifies an audio format from the leading bytes of a file.
+// It looks at the content rather than trusting the client's Content-Type.
+func DetectAudio(data []byte) (string, bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte("ID3")):
+		return AudioMPEG, true
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0:
+		// MPEG audio frame sync
+		return AudioMPEG, true
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return AudioOgg, true
+	case len(data) >= 12 && bytes.Equal(data[0:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WAVE")):
+		return AudioWAV, true
+	case bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return AudioWebM, true
+	case len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp")) && isAudioBrand(data[8:12]):
+		return AudioMP4, true
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return AudioFLAC, true
+	}
+	return "", false
+}
+
+// isAudioBrand reports whether an MP4 major brand denotes audio only content
+func isAudioBrand(brand []byte) bool {
+	switch string(brand) {
+	case "M4A ", "M4B ", "mp42", "isom", "dash":
+		return true
+	}
+	return false
+}
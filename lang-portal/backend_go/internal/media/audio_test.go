@@ -0,0 +1,32 @@
+package media
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectAudio(t *testing.T) {
+	tests := map[string]struct {
+		data []byte
+		want string
+	}{
+		"mp3 with tag":   {[]byte("ID3\x04\x00rest"), AudioMPEG},
+		"mp3 frame":      {[]byte{0xFF, 0xFB, 0x90, 0x64}, AudioMPEG},
+		"ogg":            {[]byte("OggS\x00\x02"), AudioOgg},
+		"wav":            {[]byte("RIFF\x24\x00\x00\x00WAVEfmt "), AudioWAV},
+		"webm":           {[]byte{0x1A, 0x45, 0xDF, 0xA3, 0x01}, AudioWebM},
+		"m4a":            {[]byte("\x00\x00\x00\x20ftypM4A \x00\x00"), AudioMP4},
+		"flac":           {[]byte("fLaC\x00\x00"), AudioFLAC},
+		"png":            {[]byte("\x89PNG\r\n\x1a\n"), ""},
+		"avi is no wave": {[]byte("RIFF\x24\x00\x00\x00AVI LIST"), ""},
+		"empty":          {nil, ""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := DetectAudio(tt.data)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want != "", ok)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Image content types produced by ProcessImage
+const (
+	ImageJPEG = "image/jpeg"
+	ImagePNG  = "image/png"
+)
+
+// ImageFormats lists the formats accepted for upload, as named by the image
+// package
+var ImageFormats = []string{"jpeg", "png", "gif", "webp"}
+
+// Image variant names. VariantOriginal is the uploaded image itself, re-encoded
+// to drop its metadata and capped at MaxImageDimension.
+const (
+	VariantOriginal = "original"
+	VariantLarge    = "large"
+	VariantMedium   = "medium"
+	VariantThumb    = "thumb"
+)
+
+// ImageSize is a bounding box an image variant is scaled down to fit
+type ImageSize struct {
+	Name string
+	Max  int
+}
+
+// ImageSizes are the resized variants produced for every image, largest first
+var ImageSizes = []ImageSize{
+	{VariantLarge, 1600},
+	{VariantMedium, 800},
+	{VariantThumb, 200},
+}
+
+const (
+	// MaxImageDimension caps the width and height of the stored original
+	MaxImageDimension = 4096
+	// maxImagePixels rejects images whose decoded size would exhaust memory
+	maxImagePixels = 50_000_000
+	jpegQuality    = 85
+)
+
+var (
+	ErrUnsupportedImage = errors.New("media: unsupported image format")
+	ErrImageDimensions  = errors.New("media: image dimensions too large")
+)
+
+// ImageVariant is an encoded rendition of an uploaded image
+type ImageVariant struct {
+	Name        string
+	Width       int
+	Height      int
+	ContentType string
+	Data        []byte
+}
+
+// ProcessImage decodes an uploaded image, applies its EXIF orientation and
+// renders the original and every ImageSizes variant. Re-encoding drops all
+// metadata, including EXIF location data. Images with transparency are
+// encoded as PNG, others as JPEG. Variants are never scaled up, so a small
+// image yields identical variants.
+func ProcessImage(data []byte) ([]ImageVariant, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrImageDimensions
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	img = orient(img, jpegOrientation(data))
+	opaque := isOpaque(img)
+
+	original, err := encodeVariant(VariantOriginal, fit(img, MaxImageDimension), opaque)
+	if err != nil {
+		return nil, err
+	}
+	variants := []ImageVariant{original}
+	for _, size := range ImageSizes {
+		scaled := fit(img, size.Max)
+		if scaled == img {
+			variant := original
+			variant.Name = size.Name
+			variants = append(variants, variant)
+			continue
+		}
+		variant, err := encodeVariant(size.Name, scaled, opaque)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// fit scales img down to fit a limit by limit box, keeping its aspect ratio.
+// It returns img itself if it already fits.
+func fit(img image.Image, limit int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= limit && h <= limit {
+		return img
+	}
+	if w >= h {
+		h = max(1, h*limit/w)
+		w = limit
+	} else {
+		w = max(1, w*limit/h)
+		h = limit
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+func encodeVariant(name string, img image.Image, opaque bool) (ImageVariant, error) {
+	var buf bytes.Buffer
+	variant := ImageVariant{Name: name, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if opaque {
+		variant.ContentType = ImageJPEG
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return variant, err
+		}
+	} else {
+		variant.ContentType = ImagePNG
+		if err := png.Encode(&buf, img); err != nil {
+			return variant, err
+		}
+	}
+	variant.Data = buf.Bytes()
+	return variant, nil
+}
+
+// isOpaque reports whether an image has no transparent pixels
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
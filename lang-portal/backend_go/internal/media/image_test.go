@@ -0,0 +1,87 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF segment holding only an orientation tag
+// after the start of a JPEG file
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	payload := append([]byte("Exif\x00\x00"), append(append(tiff, entry...), 0, 0, 0, 0)...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	return append(append([]byte{0xFF, 0xD8}, segment...), data[2:]...)
+}
+
+func TestProcessImageVariants(t *testing.T) {
+	variants, err := ProcessImage(encodeJPEG(t, 2000, 1000))
+	if !assert.NoError(t, err) || !assert.Len(t, variants, 4) {
+		return
+	}
+	sizes := map[string][2]int{}
+	for _, v := range variants {
+		assert.Equal(t, ImageJPEG, v.ContentType)
+		sizes[v.Name] = [2]int{v.Width, v.Height}
+	}
+	assert.Equal(t, [2]int{2000, 1000}, sizes[VariantOriginal])
+	assert.Equal(t, [2]int{1600, 800}, sizes[VariantLarge])
+	assert.Equal(t, [2]int{800, 400}, sizes[VariantMedium])
+	assert.Equal(t, [2]int{200, 100}, sizes[VariantThumb])
+}
+
+func TestProcessImageOrientationAndExif(t *testing.T) {
+	data := withOrientation(encodeJPEG(t, 40, 20), 6)
+	assert.Equal(t, 6, jpegOrientation(data))
+
+	variants, err := ProcessImage(data)
+	if assert.NoError(t, err) {
+		original := variants[0]
+		assert.Equal(t, 20, original.Width, "rotated upright")
+		assert.Equal(t, 40, original.Height)
+		assert.False(t, bytes.Contains(original.Data, []byte("Exif")), "metadata stripped")
+		assert.Equal(t, 1, jpegOrientation(original.Data))
+	}
+}
+
+func TestProcessImageTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 128})
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+
+	variants, err := ProcessImage(buf.Bytes())
+	if assert.NoError(t, err) {
+		assert.Equal(t, ImagePNG, variants[0].ContentType)
+		assert.Equal(t, variants[0].Data, variants[3].Data, "small images are not scaled up")
+	}
+}
+
+func TestProcessImageRejectsOtherFiles(t *testing.T) {
+	_, err := ProcessImage([]byte("OggS audio"))
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+}
@@ -0,0 +1,108 @@
+// Package media stores uploaded audio and image files. Files are addressed by
+// the SHA-256 of their content, so identical uploads are stored once.
+package media
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrNotFound is returned by Store.Open when no content is stored under a key
+var ErrNotFound = errors.New("media: not found")
+
+// Store keeps media content under opaque keys. Implementations other than
+// FileStore, such as object storage, only need to satisfy this interface.
+type Store interface {
+	// Put stores the content read from r under key, replacing any content
+	// already stored there
+	Put(key string, r io.Reader) error
+	// Open returns the content stored under key, or ErrNotFound
+	Open(key string) (io.ReadSeekCloser, error)
+	// Delete removes the content stored under key. Deleting a missing key is
+	// not an error.
+	Delete(key string) error
+}
+
+// validKey restricts keys to names that are safe as file names
+var validKey = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// FileStore is a Store on the local filesystem. Content is spread over
+// subdirectories named after the first two characters of the key.
+type FileStore struct {
+	root string
+}
+
+// NewFileStore returns a store rooted at dir. The directory is created on
+// the first Put.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{root: dir}
+}
+
+func (s *FileStore) path(key string) (string, error) {
+	if !validKey.MatchString(key) || len(key) < 2 {
+		return "", fmt.Errorf("media: invalid key %q", key)
+	}
+	return filepath.Join(s.root, key[:2], key), nil
+}
+
+// Put writes the content to a temporary file and renames it into place, so
+// readers never see a partial file
+func (s *FileStore) Put(key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *FileStore) Open(key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *FileStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Hash returns the hex encoded SHA-256 of data, used as its storage key
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
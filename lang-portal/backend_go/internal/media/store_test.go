@@ -0,0 +1,31 @@
+package media
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	data := []byte("OggS audio")
+	key := Hash(data)
+
+	_, err := store.Open(key)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, store.Put(key, bytes.NewReader(data)))
+	f, err := store.Open(key)
+	if assert.NoError(t, err) {
+		got, _ := io.ReadAll(f)
+		f.Close()
+		assert.Equal(t, data, got)
+	}
+
+	assert.NoError(t, store.Delete(key))
+	assert.NoError(t, store.Delete(key), "deleting a missing key is not an error")
+
+	assert.Error(t, store.Put("../escape", bytes.NewReader(data)))
+}
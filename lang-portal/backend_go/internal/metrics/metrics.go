@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "lang_portal"
+
+// Metrics owns the Prometheus registry and every collector exposed on /metrics
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	queryDuration *prometheus.HistogramVec
+	sessions      prometheus.Counter
+	reviews       *prometheus.CounterVec
+
+	reviewsTotal   atomic.Int64
+	reviewsCorrect atomic.Int64
+}
+
+// New creates the collectors and registers them, together with the connection
+// pool statistics of db and the Go runtime/process collectors
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Duration of database work per service method.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"method"}),
+		sessions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "study_sessions_created_total",
+			Help:      "Number of study sessions created.",
+		}),
+		reviews: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "study_reviews_recorded_total",
+			Help:      "Number of word reviews recorded by result.",
+		}, []string{"result"}),
+	}
+
+	accuracy := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "study_review_accuracy_ratio",
+		Help:      "Share of correct answers among reviews recorded since the server started.",
+	}, m.accuracy)
+
+	m.registry.MustRegister(
+		m.httpRequests,
+		m.httpDuration,
+		m.queryDuration,
+		m.sessions,
+		m.reviews,
+		accuracy,
+		collectors.NewDBStatsCollector(db, "words"),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a completed HTTP request
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveQuery records the time a service method spent talking to the database
+func (m *Metrics) ObserveQuery(method string, duration time.Duration) {
+	m.queryDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// SessionCreated counts a newly created study session
+func (m *Metrics) SessionCreated() {
+	m.sessions.Inc()
+}
+
+// ReviewRecorded counts a recorded word review
+func (m *Metrics) ReviewRecorded(correct bool) {
+	result := "wrong"
+	if correct {
+		result = "correct"
+		m.reviewsCorrect.Add(1)
+	}
+	m.reviewsTotal.Add(1)
+	m.reviews.WithLabelValues(result).Inc()
+}
+
+func (m *Metrics) accuracy() float64 {
+	total := m.reviewsTotal.Load()
+	if total == 0 {
+		return 0
+	}
+	return float64(m.reviewsCorrect.Load()) / float64(total)
+}
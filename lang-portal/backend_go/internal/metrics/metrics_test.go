@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"database/sql"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	m := New(db)
+	m.ObserveRequest("GET", "/api/words/:id", 200, 15*time.Millisecond)
+	m.ObserveQuery("WordService.GetWordByID", 2*time.Millisecond)
+	m.SessionCreated()
+	m.ReviewRecorded(true)
+	m.ReviewRecorded(false)
+	m.ReviewRecorded(true)
+	m.ReviewRecorded(true)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	out := string(body)
+
+	assert.Contains(t, out, `lang_portal_http_requests_total{method="GET",route="/api/words/:id",status="200"} 1`)
+	assert.Contains(t, out, `lang_portal_db_query_duration_seconds_count{method="WordService.GetWordByID"} 1`)
+	assert.Contains(t, out, `lang_portal_study_sessions_created_total 1`)
+	assert.Contains(t, out, `lang_portal_study_reviews_recorded_total{result="correct"} 3`)
+	assert.Contains(t, out, `lang_portal_study_review_accuracy_ratio 0.75`)
+	assert.Contains(t, out, `go_sql_open_connections{db_name="words"}`)
+}
@@ -2,18 +2,32 @@ package middleware
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"runtime/debug"
+	"reflect"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	apperrors "lang-portal/internal/errors"
 )
 
-// ErrorHandler middleware handles all errors in a consistent way
-func ErrorHandler() gin.HandlerFunc {
+// Error output formats
+const (
+	ErrorFormatJSON    = "json"    // {"error": {...}} envelope
+	ErrorFormatProblem = "problem" // RFC 7807 application/problem+json
+)
+
+// problemContentType is the media type of RFC 7807 problem details
+const problemContentType = "application/problem+json"
+
+// ErrorHandler middleware handles all errors in a consistent way. Errors are
+// rendered in defaultFormat unless the client asks for problem details
+// through its Accept header.
+func ErrorHandler(defaultFormat string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Process request
 		c.Next()
@@ -21,60 +35,115 @@ func ErrorHandler() gin.HandlerFunc {
 		// Error handling
 		if len(c.Errors) > 0 {
 			err := c.Errors.Last().Err
-			handleError(c, err)
+			handleError(c, err, defaultFormat)
 		}
 	}
 }
 
-// handleError processes different types of errors and sends appropriate responses
-func handleError(c *gin.Context, err error) {
-	// Check if it's already an AppError
-	if appErr, ok := apperrors.IsAppError(err); ok {
-		// Log internal error if present
-		if appErr.Internal != nil {
-			log.Printf("Internal error: %v\nStack trace:\n%s", appErr.Internal, debug.Stack())
-		}
+// NoRoute reports unknown routes through the error handler
+func NoRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_ = c.Error(apperrors.New(apperrors.CodeRouteNotFound).WithData(map[string]string{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+		}))
+	}
+}
 
-		c.JSON(appErr.Code, gin.H{
-			"error": gin.H{
-				"type":    appErr.Type,
-				"message": appErr.Message,
-				"detail":  appErr.Detail,
-				"data":    appErr.Data,
-			},
-		})
+// handleError converts any error into an AppError and renders it
+func handleError(c *gin.Context, err error, defaultFormat string) {
+	appErr := toAppError(err)
+	appErr.RequestID = GetRequestID(c)
+
+	// Log internal error if present
+	if appErr.Internal != nil || appErr.Status >= http.StatusInternalServerError {
+		slog.Error("request failed",
+			"request_id", appErr.RequestID,
+			"code", appErr.Code,
+			"error", err.Error(),
+		)
+	}
+
+	// A handler that already started the response cannot be given an error body
+	if c.Writer.Written() {
+		return
+	}
+
+	msg, lang := apperrors.Localize(appErr.Code, c.GetHeader("Accept-Language"))
+	appErr.Message = msg.Title
+	appErr.Detail = msg.Detail
+	c.Header("Content-Language", lang)
+
+	if wantsProblem(c, defaultFormat) {
+		c.Render(appErr.Status, problemRender{problem{
+			Type:      "/api/errors#" + appErr.Code,
+			Title:     appErr.Message,
+			Status:    appErr.Status,
+			Detail:    appErr.Detail,
+			Instance:  c.Request.URL.Path,
+			Code:      appErr.Code,
+			Data:      appErr.Data,
+			RequestID: appErr.RequestID,
+		}})
 		return
 	}
 
-	// Handle specific error types
+	c.JSON(appErr.Status, gin.H{"error": appErr})
+}
+
+// toAppError maps arbitrary errors onto catalog errors
+func toAppError(err error) *apperrors.AppError {
+	var appErr *apperrors.AppError
+	var validationErrors validator.ValidationErrors
 	switch {
+	case errors.As(err, &appErr):
+		return appErr
 	case errors.Is(err, sql.ErrNoRows):
-		appErr := apperrors.NewNotFoundError(
-			"Resource not found",
-			"The requested resource could not be found",
-		)
-		c.JSON(appErr.Code, gin.H{"error": appErr})
-
-	case errors.As(err, &validator.ValidationErrors{}):
-		validationErrors := err.(validator.ValidationErrors)
-		appErr := apperrors.NewValidationError(
-			"Validation failed",
-			"One or more fields failed validation",
-			formatValidationErrors(validationErrors),
-		)
-		c.JSON(appErr.Code, gin.H{"error": appErr})
-
+		return apperrors.New(apperrors.CodeResourceNotFound)
+	case errors.As(err, &validationErrors):
+		return apperrors.New(apperrors.CodeValidationFailed).WithData(formatValidationErrors(validationErrors))
 	default:
-		// Log unexpected errors
-		log.Printf("Unexpected error: %v\nStack trace:\n%s", err, debug.Stack())
+		return apperrors.New(apperrors.CodeInternal).WithInternal(err)
+	}
+}
 
-		// Return a generic error message
-		appErr := apperrors.NewInternalError(
-			"An unexpected error occurred",
-			err,
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": appErr})
+// wantsProblem reports whether the error should be rendered as problem details
+func wantsProblem(c *gin.Context, defaultFormat string) bool {
+	accept := c.GetHeader("Accept")
+	if strings.Contains(accept, problemContentType) {
+		return true
+	}
+	if defaultFormat != ErrorFormatProblem {
+		return false
 	}
+	// Clients that explicitly accept only plain JSON keep the envelope
+	return accept == "" || !strings.Contains(accept, "application/json") || strings.Contains(accept, "*/*")
+}
+
+// problem is an RFC 7807 problem details object with extension members
+type problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail"`
+	Instance  string      `json:"instance"`
+	Code      string      `json:"code"`
+	Data      interface{} `json:"data,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// problemRender writes a problem with the application/problem+json media type
+type problemRender struct {
+	problem problem
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.problem)
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", problemContentType)
 }
 
 // formatValidationErrors converts validator.ValidationErrors to a more user-friendly format
@@ -82,7 +151,7 @@ func formatValidationErrors(errors validator.ValidationErrors) []map[string]stri
 	var formattedErrors []map[string]string
 	for _, err := range errors {
 		formattedErrors = append(formattedErrors, map[string]string{
-			"field":   err.Field(),
+			"field":   fieldPath(err),
 			"tag":     err.Tag(),
 			"value":   err.Param(),
 			"message": getValidationErrorMessage(err),
@@ -91,15 +160,40 @@ func formatValidationErrors(errors validator.ValidationErrors) []map[string]stri
 	return formattedErrors
 }
 
+// fieldPath returns the client-facing path of a field, e.g. "senses[0].gloss",
+// by dropping the struct type name from the validator namespace
+func fieldPath(err validator.FieldError) string {
+	namespace := err.Namespace()
+	if _, rest, ok := strings.Cut(namespace, "."); ok {
+		return rest
+	}
+	return err.Field()
+}
+
 // getValidationErrorMessage returns a user-friendly message for validation errors
 func getValidationErrorMessage(err validator.FieldError) string {
+	isString := err.Kind() == reflect.String
 	switch err.Tag() {
 	case "required":
 		return "This field is required"
-	case "min":
-		return "Value is below minimum length"
-	case "max":
-		return "Value exceeds maximum length"
+	case "min", "gte":
+		if isString {
+			return fmt.Sprintf("Value must be at least %s characters long", err.Param())
+		}
+		if err.Kind() == reflect.Slice || err.Kind() == reflect.Map {
+			return fmt.Sprintf("Value must contain at least %s items", err.Param())
+		}
+		return fmt.Sprintf("Value must be at least %s", err.Param())
+	case "max", "lte":
+		if isString {
+			return fmt.Sprintf("Value must be at most %s characters long", err.Param())
+		}
+		if err.Kind() == reflect.Slice || err.Kind() == reflect.Map {
+			return fmt.Sprintf("Value must contain at most %s items", err.Param())
+		}
+		return fmt.Sprintf("Value must be at most %s", err.Param())
+	case "oneof":
+		return fmt.Sprintf("Value must be one of: %s", strings.Join(strings.Fields(err.Param()), ", "))
 	case "email":
 		return "Invalid email format"
 	default:
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+)
+
+func setupErrorRouter(format string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID(), ErrorHandler(format), Recovery())
+	r.NoRoute(NoRoute())
+	r.GET("/word", func(c *gin.Context) {
+		_ = c.Error(apperrors.New(apperrors.CodeWordNotFound))
+	})
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	return r
+}
+
+type envelope struct {
+	Error struct {
+		Code      string `json:"code"`
+		Type      string `json:"type"`
+		Message   string `json:"message"`
+		RequestID string `json:"request_id"`
+	} `json:"error"`
+}
+
+func TestErrorEnvelopeIsLocalized(t *testing.T) {
+	r := setupErrorRouter(ErrorFormatJSON)
+
+	req := httptest.NewRequest(http.MethodGet, "/word", nil)
+	req.Header.Set("Accept-Language", "es-MX, en;q=0.5")
+	req.Header.Set(RequestIDHeader, "abc")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "es", w.Header().Get("Content-Language"))
+
+	var body envelope
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "WORD_NOT_FOUND", body.Error.Code)
+	assert.Equal(t, "NOT_FOUND", body.Error.Type)
+	assert.Equal(t, "Palabra no encontrada", body.Error.Message)
+	assert.Equal(t, "abc", body.Error.RequestID)
+}
+
+func TestPanicsUseTheErrorEnvelope(t *testing.T) {
+	r := setupErrorRouter(ErrorFormatJSON)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var body envelope
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "INTERNAL_ERROR", body.Error.Code)
+	assert.NotContains(t, w.Body.String(), "boom")
+}
+
+func TestProblemDetails(t *testing.T) {
+	r := setupErrorRouter(ErrorFormatJSON)
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "ROUTE_NOT_FOUND", body["code"])
+	assert.Equal(t, "/api/errors#ROUTE_NOT_FOUND", body["type"])
+	assert.EqualValues(t, 404, body["status"])
+	assert.Equal(t, "/missing", body["instance"])
+}
+
+func TestProblemDetailsAsDefaultFormat(t *testing.T) {
+	r := setupErrorRouter(ErrorFormatProblem)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/word", nil))
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+	req := httptest.NewRequest(http.MethodGet, "/word", nil)
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	assert.Contains(t, w.Body.String(), `"error"`)
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apperrors "lang-portal/internal/errors"
+	"lang-portal/internal/service"
+)
+
+// bearerPrefix starts an Authorization header carrying a user token
+const bearerPrefix = "Bearer "
+
+// AccessTokenParam is the query parameter QueryToken reads a bearer token
+// from
+const AccessTokenParam = "access_token"
+
+// userKey is the gin context key holding the current user
+const userKey = "current_user"
+
+// Authenticator finds the user a token was issued to; it is implemented by
+// service.UserService
+type Authenticator interface {
+	Authenticate(token string) (*service.User, error)
+}
+
+// Identity resolves the bearer token of the Authorization header into the
+// current user. Requests without the header are anonymous; any other
+// scheme, or a token that matches no user, is rejected.
+func Identity(users Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, bearerPrefix)
+		if !ok {
+			token = ""
+		}
+		if authenticate(c, users, token) {
+			c.Next()
+		}
+	}
+}
+
+// QueryToken resolves the AccessTokenParam query parameter into the current
+// user of requests Identity left anonymous. It is meant for event streams:
+// browsers cannot set headers on an EventSource.
+func QueryToken(users Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := c.GetQuery(AccessTokenParam)
+		if !ok || CurrentUser(c) != nil {
+			c.Next()
+			return
+		}
+		if authenticate(c, users, token) {
+			c.Next()
+		}
+	}
+}
+
+// authenticate sets the user token was issued to as the current user. It
+// aborts the request and returns false if the token is empty or matches no
+// user.
+func authenticate(c *gin.Context, users Authenticator, token string) bool {
+	var user *service.User
+	if token != "" {
+		var err error
+		user, err = users.Authenticate(token)
+		if err != nil {
+			_ = c.Error(apperrors.NewDatabaseError("Failed to authenticate user", err))
+			c.Abort()
+			return false
+		}
+	}
+	if user == nil {
+		_ = c.Error(apperrors.New(apperrors.CodeInvalidToken))
+		c.Abort()
+		return false
+	}
+	c.Set(userKey, user)
+	return true
+}
+
+// CurrentUser returns the user resolved by Identity, or nil for anonymous
+// requests
+func CurrentUser(c *gin.Context) *service.User {
+	if user, ok := c.Get(userKey); ok {
+		return user.(*service.User)
+	}
+	return nil
+}
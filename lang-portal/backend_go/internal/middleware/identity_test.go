@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"lang-portal/internal/service"
+)
+
+// fakeUsers maps tokens to the users they were issued to
+type fakeUsers map[string]*service.User
+
+func (f fakeUsers) Authenticate(token string) (*service.User, error) {
+	return f[token], nil
+}
+
+func TestIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(ErrorFormatJSON))
+	r.Use(Identity(fakeUsers{"s3cret": {ID: 7, Name: "Ms. Varro"}}))
+	r.GET("/whoami", func(c *gin.Context) {
+		if user := CurrentUser(c); user != nil {
+			c.String(http.StatusOK, user.Name)
+			return
+		}
+		c.String(http.StatusOK, "anonymous")
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+		body   string
+	}{
+		{"no header is anonymous", "", http.StatusOK, "anonymous"},
+		{"valid token", "Bearer s3cret", http.StatusOK, "Ms. Varro"},
+		{"unknown token", "Bearer guess", http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"empty token", "Bearer ", http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"other scheme", "Basic czNjcmV0", http.StatusUnauthorized, "INVALID_TOKEN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), tt.body)
+		})
+	}
+}
+
+func TestQueryToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users := fakeUsers{"s3cret": {ID: 7, Name: "Ms. Varro"}}
+	r := gin.New()
+	r.Use(ErrorHandler(ErrorFormatJSON))
+	r.Use(Identity(users))
+	r.GET("/stream", QueryToken(users), func(c *gin.Context) {
+		if user := CurrentUser(c); user != nil {
+			c.String(http.StatusOK, user.Name)
+			return
+		}
+		c.String(http.StatusOK, "anonymous")
+	})
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		body   string
+	}{
+		{"no token is anonymous", "/stream", http.StatusOK, "anonymous"},
+		{"valid token", "/stream?access_token=s3cret", http.StatusOK, "Ms. Varro"},
+		{"unknown token", "/stream?access_token=guess", http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"empty token", "/stream?access_token=", http.StatusUnauthorized, "INVALID_TOKEN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), tt.body)
+		})
+	}
+}
@@ -2,59 +2,171 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
-	"log"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Logger middleware logs request and response details
-func Logger() gin.HandlerFunc {
+// redactedValue replaces the value of any redacted body field
+const redactedValue = "[REDACTED]"
+
+// LoggerConfig controls what the request logger records
+type LoggerConfig struct {
+	LogBodies    bool     // log request and response bodies of non-GET requests
+	MaxBodyBytes int      // maximum number of body bytes captured per request/response
+	RedactFields []string // JSON field names whose values are never logged
+}
+
+// DefaultLoggerConfig returns a configuration that does not log bodies
+func DefaultLoggerConfig() LoggerConfig {
+	return LoggerConfig{
+		LogBodies:    false,
+		MaxBodyBytes: 2048,
+		RedactFields: []string{"password", "token", "secret", "authorization", "api_key"},
+	}
+}
+
+// Logger middleware writes one structured log entry per request
+func Logger(logger *slog.Logger, cfg LoggerConfig) gin.HandlerFunc {
+	redact := make(map[string]bool, len(cfg.RedactFields))
+	for _, field := range cfg.RedactFields {
+		redact[strings.ToLower(field)] = true
+	}
+
 	return func(c *gin.Context) {
 		// Start timer
 		start := time.Now()
+		logBodies := cfg.LogBodies && c.Request.Method != "GET"
 
-		// Read the request body
+		// Capture the beginning of the request body without consuming it
 		var requestBody []byte
-		if c.Request.Body != nil {
-			requestBody, _ = io.ReadAll(c.Request.Body)
-			// Restore the request body
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		if logBodies && c.Request.Body != nil {
+			requestBody, _ = io.ReadAll(io.LimitReader(c.Request.Body, int64(cfg.MaxBodyBytes)+1))
+			c.Request.Body = readCloser{
+				Reader: io.MultiReader(bytes.NewReader(requestBody), c.Request.Body),
+				Closer: c.Request.Body,
+			}
 		}
 
-		// Create a buffer for the response body
-		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
-		c.Writer = blw
+		var blw *bodyLogWriter
+		if logBodies {
+			blw = &bodyLogWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}, limit: cfg.MaxBodyBytes}
+			c.Writer = blw
+		}
 
 		// Process request
 		c.Next()
 
-		// Calculate duration
-		duration := time.Since(start)
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("request_id", GetRequestID(c)),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes_out", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		if logBodies {
+			attrs = append(attrs,
+				slog.String("request_body", formatBody(requestBody, cfg.MaxBodyBytes, redact)),
+				slog.String("response_body", formatBody(blw.body.Bytes(), cfg.MaxBodyBytes, redact)),
+			)
+		}
 
-		// Log request details
-		log.Printf("[API] %s %s %d %v\n",
-			c.Request.Method,
-			c.Request.URL.Path,
-			c.Writer.Status(),
-			duration,
-		)
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
 
-		// Log detailed request/response for non-GET requests
-		if c.Request.Method != "GET" {
-			log.Printf("[REQUEST] %s\n", string(requestBody))
-			log.Printf("[RESPONSE] %s\n", blw.body.String())
+// formatBody redacts sensitive JSON fields and truncates the body to the size cap
+func formatBody(body []byte, limit int, redact map[string]bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+	truncated := len(body) > limit
+	if truncated {
+		body = body[:limit]
+	}
+
+	var payload interface{}
+	if !truncated && json.Unmarshal(body, &payload) == nil {
+		if redacted, err := json.Marshal(redactValue(payload, redact)); err == nil {
+			return string(redacted)
+		}
+	}
+
+	// Bodies that cannot be parsed are only logged when nothing needs redacting
+	lower := strings.ToLower(string(body))
+	for field := range redact {
+		if strings.Contains(lower, field) {
+			return redactedValue
+		}
+	}
+	if truncated {
+		return string(body) + "...(truncated)"
+	}
+	return string(body)
+}
+
+// redactValue walks a decoded JSON value and masks redacted fields
+func redactValue(v interface{}, redact map[string]bool) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, inner := range value {
+			if redact[strings.ToLower(key)] {
+				value[key] = redactedValue
+				continue
+			}
+			value[key] = redactValue(inner, redact)
+		}
+		return value
+	case []interface{}:
+		for i, inner := range value {
+			value[i] = redactValue(inner, redact)
 		}
+		return value
+	default:
+		return v
 	}
 }
 
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
 type bodyLogWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body  *bytes.Buffer
+	limit int
 }
 
-func (w bodyLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	if remaining := w.limit + 1 - w.body.Len(); remaining > 0 {
+		if len(b) < remaining {
+			remaining = len(b)
+		}
+		w.body.Write(b[:remaining])
+	}
 	return w.ResponseWriter.Write(b)
 }
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerRedactsBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, nil))
+	cfg := DefaultLoggerConfig()
+	cfg.LogBodies = true
+
+	r := gin.New()
+	r.Use(RequestID(), Logger(logger, cfg))
+	r.POST("/login", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"token": "abc", "user": "marcus"})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/login",
+		strings.NewReader(`{"user":"marcus","password":"hunter2"}`))
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-42", w.Header().Get(RequestIDHeader))
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "req-42", entry["request_id"])
+	assert.Equal(t, "/login", entry["route"])
+	assert.EqualValues(t, 200, entry["status"])
+	assert.NotContains(t, entry["request_body"], "hunter2")
+	assert.NotContains(t, entry["response_body"], "abc")
+	assert.Contains(t, entry["request_body"], "marcus")
+}
+
+func TestFormatBodyTruncates(t *testing.T) {
+	body := formatBody([]byte(strings.Repeat("a", 20)), 10, map[string]bool{})
+	assert.Equal(t, strings.Repeat("a", 10)+"...(truncated)", body)
+}
+
+func TestRequestIDRejectsMalformedHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	id := w.Header().Get(RequestIDHeader)
+	assert.Len(t, id, 32)
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that did not match any registered route, so
+// arbitrary paths cannot blow up metric cardinality
+const unmatchedRoute = "unmatched"
+
+// RequestRecorder receives the outcome of every HTTP request
+type RequestRecorder interface {
+	ObserveRequest(method, route string, status int, duration time.Duration)
+}
+
+// Metrics middleware records request counts and latencies per route template
+func Metrics(recorder RequestRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		recorder.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"lang-portal/internal/service"
+)
+
+func TestPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(ErrorFormatJSON))
+	r.Use(Identity(fakeUsers{
+		"teacher": {ID: 1, Name: "Ms. Varro", Role: service.RoleTeacher},
+		"learner": {ID: 2, Name: "Marcus", Role: service.RoleLearner},
+	}))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	r.GET("/me", Authenticated(), ok)
+	r.POST("/words", Require(service.PermissionCurate), ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+		body   string
+	}{
+		{"anonymous user", http.MethodGet, "/me", "", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"signed in user", http.MethodGet, "/me", "learner", http.StatusOK, "ok"},
+		{"anonymous curator", http.MethodPost, "/words", "", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"learner curating", http.MethodPost, "/words", "learner", http.StatusForbidden, `"permission":"curate"`},
+		{"teacher curating", http.MethodPost, "/words", "teacher", http.StatusOK, "ok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), tt.body)
+		})
+	}
+}
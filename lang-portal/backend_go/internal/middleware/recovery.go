@@ -1,7 +1,8 @@
 package middleware
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"runtime/debug"
 
 	"github.com/gin-gonic/gin"
@@ -14,13 +15,14 @@ func Recovery() gin.HandlerFunc {
 		defer func() {
 			if err := recover(); err != nil {
 				// Log the stack trace
-				log.Printf("Panic recovered: %v\nStack trace:\n%s", err, debug.Stack())
+				slog.Error("panic recovered",
+					"request_id", GetRequestID(c),
+					"panic", fmt.Sprint(err),
+					"stack", string(debug.Stack()),
+				)
 
 				// Create an internal server error
-				appErr := errors.NewInternalError(
-					"An unexpected error occurred",
-					nil,
-				)
+				appErr := errors.NewInternalError(fmt.Sprintf("panic: %v", err), nil)
 
 				// Add the error to the context
 				_ = c.Error(appErr)
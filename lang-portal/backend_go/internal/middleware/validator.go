@@ -1,93 +1,297 @@
 package middleware
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
-	"lang-portal/internal/errors"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
+	apperrors "lang-portal/internal/errors"
 )
 
-// ValidatePagination validates pagination parameters
-func ValidatePagination() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		page := c.DefaultQuery("page", "1")
-		itemsPerPage := c.DefaultQuery("items_per_page", "100")
-
-		// Validate page
-		pageNum, err := strconv.Atoi(page)
-		if err != nil || pageNum < 1 {
-			_ = c.Error(errors.NewInvalidInputError(
-				"Invalid page number",
-				"Page number must be a positive integer",
-				map[string]string{"page": page},
-			))
-			c.Abort()
+// inputKey is the gin context key holding the input bound by Validate
+const inputKey = "validated_input"
+
+var configureValidatorOnce sync.Once
+
+// configureValidator makes validation errors report the name a client used
+// (path, query or JSON) rather than the Go struct field name, and registers
+// the non-standard notblank tag
+func configureValidator() {
+	configureValidatorOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
 			return
 		}
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"uri", "form", "json"} {
+				name := strings.Split(field.Tag.Get(tag), ",")[0]
+				if name != "" && name != "-" {
+					return name
+				}
+			}
+			return field.Name
+		})
+		_ = v.RegisterValidation("notblank", validators.NotBlank)
+	})
+}
+
+// Validate binds the path parameters (`uri` tags), query string (`form` tags)
+// and JSON body (`json` tags) of a request into a new T, then runs its
+// `binding` validator tags. Every failure is reported in a single validation
+// error; on success the handler retrieves the value with Input.
+func Validate[T any]() gin.HandlerFunc {
+	configureValidator()
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	hasBody := hasTag(t, "json")
+
+	return func(c *gin.Context) {
+		input := new(T)
+		var failures []map[string]string
+
+		params := make(map[string][]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = []string{p.Value}
+		}
+		failures = append(failures, bindValues(input, "uri", params)...)
+		failures = append(failures, bindValues(input, "form", c.Request.URL.Query())...)
+
+		if hasBody && c.Request.Body != nil && c.Request.ContentLength != 0 {
+			if !isJSON(c.GetHeader("Content-Type")) {
+				abortInvalidContentType(c, "application/json")
+				return
+			}
+			if failure := bindJSON(c.Request.Body, input); failure != nil {
+				failures = append(failures, failure)
+			}
+		}
 
-		// Validate items per page
-		itemsNum, err := strconv.Atoi(itemsPerPage)
-		if err != nil || itemsNum < 1 || itemsNum > 100 {
-			_ = c.Error(errors.NewInvalidInputError(
-				"Invalid items per page",
-				"Items per page must be between 1 and 100",
-				map[string]string{"items_per_page": itemsPerPage},
-			))
+		// Only run the validator once the raw input decoded cleanly, so a
+		// malformed value is not reported a second time as missing
+		if len(failures) == 0 {
+			var validationErrors validator.ValidationErrors
+			if err := binding.Validator.ValidateStruct(input); errors.As(err, &validationErrors) {
+				failures = formatValidationErrors(validationErrors)
+			} else if err != nil {
+				failures = append(failures, map[string]string{
+					"field":   "",
+					"tag":     "invalid",
+					"value":   "",
+					"message": err.Error(),
+				})
+			}
+		}
+
+		if len(failures) > 0 {
+			_ = c.Error(apperrors.New(apperrors.CodeValidationFailed).WithData(failures))
 			c.Abort()
 			return
 		}
 
+		c.Set(inputKey, input)
 		c.Next()
 	}
 }
 
-// ValidateID validates ID parameters
-func ValidateID(paramName string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		id := c.Param(paramName)
-		if id == "" {
-			_ = c.Error(errors.NewInvalidInputError(
-				"Missing ID parameter",
-				"The ID parameter is required",
-				map[string]string{paramName: "missing"},
-			))
-			c.Abort()
-			return
+// Input returns the value bound by the Validate middleware of the route.
+// It panics if the route was registered without Validate[T].
+func Input[T any](c *gin.Context) *T {
+	input, ok := c.Get(inputKey)
+	if !ok {
+		panic(fmt.Sprintf("middleware.Input: no validated input on route %s", c.FullPath()))
+	}
+	return input.(*T)
+}
+
+// bindValues assigns the values of a path or query source to the fields
+// carrying the given tag, honoring `default=` options, and reports every
+// value that cannot be converted to its field type
+func bindValues(ptr interface{}, tag string, values map[string][]string) []map[string]string {
+	var failures []map[string]string
+	v := reflect.ValueOf(ptr).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		spec := field.Tag.Get(tag)
+		if spec == "" || spec == "-" || !field.IsExported() {
+			continue
 		}
+		name, opts, _ := strings.Cut(spec, ",")
 
-		idNum, err := strconv.Atoi(id)
-		if err != nil || idNum < 1 {
-			_ = c.Error(errors.NewInvalidInputError(
-				"Invalid ID",
-				"ID must be a positive integer",
-				map[string]string{paramName: id},
-			))
-			c.Abort()
-			return
+		raw, ok := values[name]
+		if !ok {
+			for _, opt := range strings.Split(opts, ",") {
+				if def, found := strings.CutPrefix(opt, "default="); found {
+					raw, ok = []string{def}, true
+				}
+			}
+		}
+		if !ok || len(raw) == 0 {
+			continue
 		}
 
-		c.Next()
+		if err := setField(v.Field(i), raw); err != nil {
+			failures = append(failures, map[string]string{
+				"field":   name,
+				"tag":     "type",
+				"value":   raw[0],
+				"message": fmt.Sprintf("Value must be of type %s", describeKind(field.Type)),
+			})
+		}
 	}
+	return failures
 }
 
-// ValidateContentType ensures the request has the correct content type
+// setField converts raw string values into the field's type
+func setField(field reflect.Value, raw []string) error {
+	switch field.Kind() {
+	case reflect.Ptr:
+		value := reflect.New(field.Type().Elem())
+		if err := setField(value.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	case reflect.Slice:
+		// Each repeated parameter is one item; values are not split on
+		// commas, which tag names may contain
+		slice := reflect.MakeSlice(field.Type(), 0, len(raw))
+		for _, item := range raw {
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err := setField(elem, []string{item}); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, elem)
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	value := raw[0]
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// bindJSON decodes the request body, describing the first decoding failure
+func bindJSON(body io.Reader, ptr interface{}) map[string]string {
+	err := json.NewDecoder(body).Decode(ptr)
+	if err == nil || errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return map[string]string{
+			"field":   typeErr.Field,
+			"tag":     "type",
+			"value":   typeErr.Value,
+			"message": fmt.Sprintf("Value must be of type %s", describeKind(typeErr.Type)),
+		}
+	}
+	return map[string]string{
+		"field":   "body",
+		"tag":     "json",
+		"value":   "",
+		"message": "Request body must be valid JSON",
+	}
+}
+
+// hasTag reports whether any field of struct type t carries the tag
+func hasTag(t reflect.Type, tag string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get(tag); name != "" && name != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+func describeKind(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	default:
+		return t.Kind().String()
+	}
+}
+
+// isJSON reports whether a Content-Type header denotes JSON, ignoring
+// parameters such as charset
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// ValidateContentType ensures the request has the correct media type.
+// Parameters such as "; charset=utf-8" are ignored.
 func ValidateContentType(contentType string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.Method == http.MethodPost || c.Request.Method == http.MethodPut {
-			if ct := c.GetHeader("Content-Type"); ct != contentType {
-				_ = c.Error(errors.NewInvalidInputError(
-					"Invalid Content-Type",
-					"Request must include correct Content-Type header",
-					map[string]string{
-						"expected": contentType,
-						"received": ct,
-					},
-				))
-				c.Abort()
+			mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+			if err != nil || mediaType != contentType {
+				abortInvalidContentType(c, contentType)
 				return
 			}
 		}
 		c.Next()
 	}
 }
+
+func abortInvalidContentType(c *gin.Context, expected string) {
+	_ = c.Error(apperrors.New(apperrors.CodeInvalidContentType).WithData(map[string]string{
+		"expected": expected,
+		"received": c.GetHeader("Content-Type"),
+	}))
+	c.Abort()
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type testInput struct {
+	ID      int      `uri:"id" json:"-" binding:"required,min=1"`
+	Page    int      `form:"page,default=1" binding:"min=1"`
+	Name    string   `json:"name" binding:"required,max=5"`
+	Correct *bool    `json:"correct" binding:"required"`
+	Tags    []string `json:"tags,omitempty" binding:"omitempty,dive,notblank"`
+}
+
+func setupValidateRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(ErrorFormatJSON))
+	r.POST("/items/:id", Validate[testInput](), func(c *gin.Context) {
+		c.JSON(http.StatusOK, Input[testInput](c))
+	})
+	return r
+}
+
+func validationFields(t *testing.T, body []byte) []string {
+	var resp struct {
+		Error struct {
+			Type string              `json:"type"`
+			Data []map[string]string `json:"data"`
+		} `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &resp))
+	assert.Equal(t, "VALIDATION_ERROR", resp.Error.Type)
+
+	var fields []string
+	for _, failure := range resp.Error.Data {
+		fields = append(fields, failure["field"])
+	}
+	return fields
+}
+
+func TestValidateBindsAllSources(t *testing.T) {
+	r := setupValidateRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/items/7?page=3", strings.NewReader(`{"name":"amo","correct":false}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"Page":3,"name":"amo","correct":false}`, w.Body.String())
+}
+
+func TestValidateReportsEveryFailure(t *testing.T) {
+	r := setupValidateRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/items/0?page=0", strings.NewReader(`{"name":"too long","tags":["verb"," "]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.ElementsMatch(t, []string{"id", "page", "name", "correct", "tags[1]"}, validationFields(t, w.Body.Bytes()))
+}
+
+func TestValidateReportsMalformedValues(t *testing.T) {
+	r := setupValidateRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/items/abc?page=x", strings.NewReader(`{"name":"amo","correct":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.ElementsMatch(t, []string{"id", "page"}, validationFields(t, w.Body.Bytes()))
+}
+
+func TestValidateRejectsNonJSONBody(t *testing.T) {
+	r := setupValidateRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/items/1", strings.NewReader(`name=amo`))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_CONTENT_TYPE")
+}
+
+type tagQuery struct {
+	Tags []string `form:"tag" binding:"omitempty,dive,notblank"`
+}
+
+func TestValidateKeepsCommasInRepeatedQueryValues(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(ErrorFormatJSON))
+	r.GET("/words", Validate[tagQuery](), func(c *gin.Context) {
+		c.JSON(http.StatusOK, Input[tagQuery](c).Tags)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/words?tag=Caesar,%20Book%201&tag=verbs", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `["Caesar, Book 1","verbs"]`, w.Body.String())
+}
@@ -0,0 +1,124 @@
+// Package migrate applies the SQL files in db/migrations and records which
+// ones have run, so that non-idempotent statements such as ALTER TABLE are
+// only executed once per database.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const createTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		name TEXT PRIMARY KEY,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+
+// Apply runs every *.sql file in dir that has not been applied yet, in
+// lexical order, each in its own transaction. It returns the names of the
+// files it applied.
+func Apply(db *sql.DB, dir string) ([]string, error) {
+	if _, err := db.Exec(createTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find migration files: %w", err)
+	}
+	sort.Strings(files)
+
+	var applied []string
+	for _, file := range files {
+		name := filepath.Base(file)
+
+		var done bool
+		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE name = ?)", name).Scan(&done)
+		if err != nil {
+			return applied, fmt.Errorf("failed to check migration %s: %w", name, err)
+		}
+		if done {
+			continue
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return applied, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		if err := applyOne(db, name, string(content)); err != nil {
+			return applied, err
+		}
+		applied = append(applied, name)
+	}
+	return applied, nil
+}
+
+// applyOne runs a migration on a dedicated connection with foreign keys
+// switched off, so that tables can be rebuilt without cascading deletes, and
+// checks that it left no foreign key dangling before committing. This is the
+// procedure SQLite documents for schema changes ALTER TABLE cannot express.
+func applyOne(db *sql.DB, name, content string) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start migration %s: %w", name, err)
+	}
+	defer conn.Close()
+
+	var foreignKeys bool
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return fmt.Errorf("failed to start migration %s: %w", name, err)
+	}
+	if foreignKeys {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return fmt.Errorf("failed to start migration %s: %w", name, err)
+		}
+		defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start migration %s: %w", name, err)
+	}
+	defer tx.Rollback()
+
+	// Rows written while enforcement was off may already dangle; only
+	// violations introduced by the migration itself fail it
+	before, err := countViolations(tx)
+	if err != nil {
+		return fmt.Errorf("failed to check foreign keys before migration %s: %w", name, err)
+	}
+	if _, err := tx.Exec(content); err != nil {
+		return fmt.Errorf("failed to execute migration %s: %w", name, err)
+	}
+	after, err := countViolations(tx)
+	if err != nil {
+		return fmt.Errorf("failed to check foreign keys after migration %s: %w", name, err)
+	}
+	if after > before {
+		return fmt.Errorf("migration %s leaves %d dangling foreign keys", name, after-before)
+	}
+
+	if _, err := tx.Exec("INSERT INTO schema_migrations (name) VALUES (?)", name); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", name, err)
+	}
+	return tx.Commit()
+}
+
+// countViolations returns the number of rows whose foreign keys do not match
+func countViolations(tx *sql.Tx) (int, error) {
+	rows, err := tx.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
@@ -0,0 +1,107 @@
+package migrate
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyRunsEachMigrationOnce(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("001_create.sql", "CREATE TABLE items (id INTEGER PRIMARY KEY);")
+	write("002_alter.sql", "ALTER TABLE items ADD COLUMN name TEXT;")
+
+	applied, err := Apply(db, dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"001_create.sql", "002_alter.sql"}, applied)
+
+	// A second run must not repeat the ALTER TABLE
+	applied, err = Apply(db, dir)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	write("003_broken.sql", "ALTER TABLE missing ADD COLUMN x TEXT;")
+	_, err = Apply(db, dir)
+	assert.Error(t, err)
+
+	var count int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count))
+	assert.Equal(t, 2, count, "a failed migration is not recorded")
+}
+
+func TestLanguagesMigrationMovesRowsToLatin(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// Apply the migrations that predate languages, then add 004 once the
+	// database holds Latin-only rows
+	dir := t.TempDir()
+	copyMigration := func(name string) {
+		data, err := os.ReadFile(filepath.Join("../../db/migrations", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	copyMigration("001_initial_schema.sql")
+	copyMigration("002_soft_delete.sql")
+	copyMigration("003_users_and_audit.sql")
+	_, err = Apply(db, dir)
+	assert.NoError(t, err)
+
+	_, err = db.Exec(`
+		INSERT INTO words (id, latin_word, english_translation, parts) VALUES (1, 'amare', 'to love', '{}');
+		INSERT INTO groups (id, name) VALUES (1, 'Verbs');
+		INSERT INTO words_groups (word_id, group_id) VALUES (1, 1);
+		INSERT INTO audit_log (entity, entity_id, action, after)
+		VALUES ('word', 1, 'create', '{"id":1,"latin_word":"amare","english_translation":"to love","parts":{},"deleted_at":null}');
+	`)
+	assert.NoError(t, err)
+
+	copyMigration("004_languages.sql")
+	applied, err := Apply(db, dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"004_languages.sql"}, applied)
+
+	var language, term, translation string
+	err = db.QueryRow("SELECT language_code, term, translation FROM words WHERE id = 1").
+		Scan(&language, &term, &translation)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"la", "amare", "to love"}, []string{language, term, translation})
+
+	assert.NoError(t, db.QueryRow("SELECT language_code FROM groups WHERE id = 1").Scan(&language))
+	assert.Equal(t, "la", language)
+
+	var members int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM words_groups").Scan(&members))
+	assert.Equal(t, 1, members, "memberships survive the table rebuild")
+
+	var snapshot string
+	assert.NoError(t, db.QueryRow("SELECT after FROM audit_log WHERE entity_id = 1").Scan(&snapshot))
+	assert.JSONEq(t,
+		`{"id":1,"language":"la","term":"amare","translation":"to love","reading":null,"romanization":null,"parts":{},"deleted_at":null}`,
+		snapshot,
+	)
+}
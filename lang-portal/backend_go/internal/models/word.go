@@ -7,8 +7,9 @@ import (
 
 type Word struct {
 	ID                int             `json:"id"`
-	LatinWord         string          `json:"latin_word"`
-	EnglishTranslation string         `json:"english_translation"`
+	Language          string          `json:"language"`
+	Term              string          `json:"term"`
+	Translation       string          `json:"translation"`
 	Parts            json.RawMessage  `json:"parts"`
 	CorrectCount     int             `json:"correct_count"`
 	WrongCount       int             `json:"wrong_count"`
@@ -27,7 +28,7 @@ func GetWords(page, itemsPerPage int) (*WordPagination, error) {
 	offset := (page - 1) * itemsPerPage
 
 	query := `
-		SELECT w.id, w.latin_word, w.english_translation, w.parts,
+		SELECT w.id, w.language_code, w.term, w.translation, w.parts,
 			   COUNT(CASE WHEN wri.correct = 1 THEN 1 END) as correct_count,
 			   COUNT(CASE WHEN wri.correct = 0 THEN 1 END) as wrong_count
 		FROM words w
@@ -44,7 +45,7 @@ func GetWords(page, itemsPerPage int) (*WordPagination, error) {
 	var words []Word
 	for rows.Next() {
 		var w Word
-		if err := rows.Scan(&w.ID, &w.LatinWord, &w.EnglishTranslation, &w.Parts,
+		if err := rows.Scan(&w.ID, &w.Language, &w.Term, &w.Translation, &w.Parts,
 			&w.CorrectCount, &w.WrongCount); err != nil {
 			return nil, err
 		}
@@ -72,7 +73,7 @@ func GetWords(page, itemsPerPage int) (*WordPagination, error) {
 // GetWordByID retrieves a single word by its ID
 func GetWordByID(id int) (*Word, error) {
 	query := `
-		SELECT w.id, w.latin_word, w.english_translation, w.parts,
+		SELECT w.id, w.language_code, w.term, w.translation, w.parts,
 			   COUNT(CASE WHEN wri.correct = 1 THEN 1 END) as correct_count,
 			   COUNT(CASE WHEN wri.correct = 0 THEN 1 END) as wrong_count
 		FROM words w
@@ -82,7 +83,7 @@ func GetWordByID(id int) (*Word, error) {
 
 	var word Word
 	err := db.QueryRow(query, id).Scan(
-		&word.ID, &word.LatinWord, &word.EnglishTranslation, &word.Parts,
+		&word.ID, &word.Language, &word.Term, &word.Translation, &word.Parts,
 		&word.CorrectCount, &word.WrongCount,
 	)
 	if err == sql.ErrNoRows {
@@ -0,0 +1,104 @@
+// Package notify delivers notifications to learners through pluggable
+// channels. Webhook posts them as JSON to a URL; Email sends them through an
+// SMTP server, such as a local stand-in like Mailpit during development.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message is a notification addressed to To, a URL or an email address
+// depending on the channel. Data carries structured details for machine
+// consumers.
+type Message struct {
+	To      string      `json:"-"`
+	Subject string      `json:"subject"`
+	Body    string      `json:"body"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Channel delivers messages
+type Channel interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Webhook posts messages as JSON
+type Webhook struct {
+	client *http.Client
+}
+
+// NewWebhook returns a webhook channel whose requests time out after timeout
+func NewWebhook(timeout time.Duration) *Webhook {
+	return &Webhook{client: &http.Client{Timeout: timeout}}
+}
+
+// Send posts msg to its URL, failing unless the response status is 2xx
+func (w *Webhook) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.To, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notify: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Email sends messages as plain-text mail through an SMTP server without
+// authentication
+type Email struct {
+	addr string
+	from string
+}
+
+// NewEmail returns an email channel sending through the server at addr, such
+// as "localhost:1025", from the given address
+func NewEmail(addr, from string) *Email {
+	return &Email{addr: addr, from: from}
+}
+
+// Send mails msg to its address
+func (e *Email) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("notify: invalid header value")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(e.addr, nil, e.from, []string{msg.To}, []byte(b.String()))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookPostsJSON(t *testing.T) {
+	var received map[string]interface{}
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(time.Second)
+	msg := Message{To: server.URL, Subject: "Goal at risk", Body: "5 of 20 reviews", Data: map[string]int{"goal_id": 1}}
+	assert.NoError(t, webhook.Send(context.Background(), msg))
+	assert.Equal(t, "Goal at risk", received["subject"])
+	assert.Equal(t, map[string]interface{}{"goal_id": float64(1)}, received["data"])
+
+	status = http.StatusGone
+	assert.ErrorContains(t, webhook.Send(context.Background(), msg), "unexpected status 410")
+}
+
+// smtpStandIn accepts one mail over SMTP and returns its data
+func smtpStandIn(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	mails := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				reply("250 OK")
+			case "DATA":
+				reply("354 Go ahead")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				mails <- data.String()
+				reply("250 Queued")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+	return listener.Addr().String(), mails
+}
+
+func TestEmailSendsThroughSMTP(t *testing.T) {
+	addr, mails := smtpStandIn(t)
+	email := NewEmail(addr, "portal@localhost")
+
+	err := email.Send(context.Background(), Message{To: "marcus@example.com", Subject: "Goal at risk", Body: "5 of 20 reviews\ntoday"})
+	assert.NoError(t, err)
+	mail := <-mails
+	assert.Contains(t, mail, "From: portal@localhost\r\n")
+	assert.Contains(t, mail, "To: marcus@example.com\r\n")
+	assert.Contains(t, mail, "Subject: Goal at risk\r\n")
+	assert.Contains(t, mail, "5 of 20 reviews\r\ntoday")
+
+	err = email.Send(context.Background(), Message{To: "marcus@example.com\r\nBcc: x@example.com", Subject: "x"})
+	assert.Error(t, err)
+}
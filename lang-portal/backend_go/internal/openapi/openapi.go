@@ -0,0 +1,304 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operation documents a single registered route
+type Operation struct {
+	Method      string      // HTTP method, e.g. GET
+	Path        string      // gin route template, e.g. /api/words/:id
+	Summary     string      // one line description
+	Tags        []string    // grouping used by the docs UI
+	Query       interface{} // struct whose `form` tagged fields are query parameters
+	Body        interface{} // JSON request body
+	BodyType    string      // media type of a non-JSON request body, such as an uploaded file
+	Response    interface{} // JSON response body for the success status
+	Status      int         // success status code, defaults to 200
+	ContentType string      // success response media type, defaults to application/json
+}
+
+// Info describes the API as a whole
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string                        `json:"openapi"`
+	Info       Info                          `json:"info"`
+	Paths      map[string]map[string]*Path   `json:"paths"`
+	Components map[string]map[string]*Schema `json:"components"`
+}
+
+// Path is an OpenAPI operation object
+type Path struct {
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	OperationID string               `json:"operationId"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *Body                `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is an OpenAPI path or query parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// Body is an OpenAPI request body
+type Body struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response is an OpenAPI response
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType wraps the schema of a request or response body
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// errorSchemaName is the component describing the error envelope
+const errorSchemaName = "ErrorResponse"
+
+// Build creates the document for the registered routes, using the matching
+// operation for each route. Routes without an operation are still listed so
+// the document never hides an endpoint; Undocumented reports them.
+func Build(info Info, routes gin.RoutesInfo, ops []Operation) *Document {
+	schemas := newSchemaRegistry()
+	doc := &Document{
+		OpenAPI:    "3.0.3",
+		Info:       info,
+		Paths:      make(map[string]map[string]*Path),
+		Components: map[string]map[string]*Schema{"schemas": schemas.components},
+	}
+	schemas.components[errorSchemaName] = errorSchema()
+
+	documented := make(map[string]Operation, len(ops))
+	for _, op := range ops {
+		documented[op.Method+" "+op.Path] = op
+	}
+
+	for _, route := range routes {
+		op, ok := documented[route.Method+" "+route.Path]
+		if !ok {
+			op = Operation{Method: route.Method, Path: route.Path, Summary: "Undocumented"}
+		}
+		path := toOpenAPIPath(op.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*Path)
+		}
+		doc.Paths[path][strings.ToLower(op.Method)] = buildOperation(op, schemas)
+	}
+	return doc
+}
+
+func buildOperation(op Operation, schemas *schemaRegistry) *Path {
+	p := &Path{
+		Summary:     op.Summary,
+		Tags:        op.Tags,
+		OperationID: operationID(op),
+		Responses:   make(map[string]*Response),
+	}
+
+	for _, name := range pathParams(op.Path) {
+		p.Parameters = append(p.Parameters, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: pathParamType(name)},
+		})
+	}
+	p.Parameters = append(p.Parameters, queryParams(op.Query, schemas)...)
+
+	if op.Body != nil {
+		p.RequestBody = &Body{
+			Required: true,
+			Content: map[string]*MediaType{
+				"application/json": {Schema: schemas.schemaFor(op.Body)},
+			},
+		}
+	} else if op.BodyType != "" {
+		p.RequestBody = &Body{
+			Required: true,
+			Content: map[string]*MediaType{
+				op.BodyType: {Schema: &Schema{Type: "string", Format: "binary"}},
+			},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	contentType := op.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if op.Response != nil {
+		success.Content = map[string]*MediaType{contentType: {Schema: schemas.schemaFor(op.Response)}}
+	} else if status != http.StatusNoContent {
+		success.Content = map[string]*MediaType{contentType: {}}
+	}
+	p.Responses[strconv.Itoa(status)] = success
+	p.Responses["default"] = &Response{
+		Description: "Error",
+		Content: map[string]*MediaType{
+			"application/json": {Schema: &Schema{Ref: "#/components/schemas/" + errorSchemaName}},
+		},
+	}
+	return p
+}
+
+// queryParams lists the `form` tagged fields of a query struct
+func queryParams(query interface{}, schemas *schemaRegistry) []Parameter {
+	if query == nil {
+		return nil
+	}
+	t := reflect.TypeOf(query)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := fieldName(field, "form")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		schema := schemas.schemaOf(field.Type)
+		required := applyBinding(schema, field.Tag.Get("binding"))
+		params = append(params, Parameter{Name: name, In: "query", Required: required, Schema: schema})
+	}
+	return params
+}
+
+func errorSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"error": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"code":       {Type: "string"},
+					"type":       {Type: "string"},
+					"message":    {Type: "string"},
+					"detail":     {Type: "string"},
+					"data":       {},
+					"request_id": {Type: "string"},
+				},
+			},
+		},
+	}
+}
+
+// toOpenAPIPath converts /words/:id and /files/*path to /words/{id} and /files/{path}
+func toOpenAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func pathParams(path string) []string {
+	var params []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
+
+// pathParamType documents id parameters such as :id and :groupId as integers
+// and any other parameter as a string
+func pathParamType(name string) string {
+	if name == "id" || strings.HasSuffix(name, "Id") {
+		return "integer"
+	}
+	return "string"
+}
+
+// operationID derives a stable identifier such as get_api_words_id
+func operationID(op Operation) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.Method))
+	for _, segment := range strings.Split(op.Path, "/") {
+		segment = strings.TrimLeft(segment, ":*")
+		if segment == "" {
+			continue
+		}
+		b.WriteByte('_')
+		b.WriteString(strings.NewReplacer("-", "_", ".", "_").Replace(segment))
+	}
+	return b.String()
+}
+
+// Undocumented lists registered routes that have no operation, and operations
+// that do not match any registered route
+func Undocumented(routes gin.RoutesInfo, ops []Operation) (missing, stale []string) {
+	documented := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		documented[op.Method+" "+op.Path] = true
+	}
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if !documented[key] {
+			missing = append(missing, key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	return missing, stale
+}
+
+// Handler serves the document as JSON. It is built on first use, once every
+// route has been registered on the engine.
+func Handler(info Info, engine *gin.Engine, ops []Operation) gin.HandlerFunc {
+	var once sync.Once
+	var doc *Document
+	return func(c *gin.Context) {
+		once.Do(func() { doc = Build(info, engine.Routes(), ops) })
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+//go:embed docs.html
+var docsPage []byte
+
+// DocsHandler serves a self-contained page rendering the document at specURL
+func DocsHandler(specURL string) gin.HandlerFunc {
+	page := strings.ReplaceAll(string(docsPage), "{{SPEC_URL}}", specURL)
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
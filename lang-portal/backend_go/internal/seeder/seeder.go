@@ -13,17 +13,57 @@ type SeedData struct {
 	Words  []Word  `json:"words"`
 }
 
+// defaultLanguage is used for seed entries without a language, which
+// includes every seed file written before multi-language support
+const defaultLanguage = "la"
+
 type Group struct {
-	Name string `json:"name"`
+	Name     string `json:"name"`
+	Language string `json:"language"`
 }
 
+// Word is a seeded word. Groups are looked up by name among the groups of
+// the word's language. Synonyms are further accepted glosses after the
+// translation. LatinWord and EnglishTranslation are the keys used by older
+// seed files and are read when Term and Translation are missing.
 type Word struct {
+	Language           string          `json:"language"`
+	Term               string          `json:"term"`
+	Translation        string          `json:"translation"`
+	Synonyms           []string        `json:"synonyms"`
+	Examples           []Example       `json:"examples"`
+	Reading            *string         `json:"reading"`
+	Romanization       *string         `json:"romanization"`
 	LatinWord          string          `json:"latin_word"`
 	EnglishTranslation string          `json:"english_translation"`
 	Parts             json.RawMessage `json:"parts"`
 	Groups            []string        `json:"groups"`
 }
 
+// normalize fills in the language and the legacy keys
+func (w *Word) normalize() {
+	if w.Language == "" {
+		w.Language = defaultLanguage
+	}
+	if w.Term == "" {
+		w.Term = w.LatinWord
+	}
+	if w.Translation == "" {
+		w.Translation = w.EnglishTranslation
+	}
+}
+
+// Example is a seeded example sentence
+type Example struct {
+	Sentence    string  `json:"sentence"`
+	Translation *string `json:"translation"`
+	Source      *string `json:"source"`
+}
+
+type groupKey struct {
+	language, name string
+}
+
 // Seeder handles database seeding operations
 type Seeder struct {
 	db *sql.DB
@@ -55,9 +95,16 @@ func (s *Seeder) LoadFromJSON(filePath string) error {
 	defer tx.Rollback()
 
 	// Create groups and store their IDs
-	groupIDs := make(map[string]int64)
+	groupIDs := make(map[groupKey]int64)
 	for _, group := range seedData.Groups {
-		result, err := tx.Exec("INSERT INTO groups (name) VALUES (?)", group.Name)
+		if group.Language == "" {
+			group.Language = defaultLanguage
+		}
+		result, err := tx.Exec(
+			"INSERT INTO groups (language_code, name) VALUES (?, ?)",
+			group.Language,
+			group.Name,
+		)
 		if err != nil {
 			return fmt.Errorf("failed to insert group %s: %v", group.Name, err)
 		}
@@ -65,32 +112,59 @@ func (s *Seeder) LoadFromJSON(filePath string) error {
 		if err != nil {
 			return fmt.Errorf("failed to get group ID for %s: %v", group.Name, err)
 		}
-		groupIDs[group.Name] = id
+		groupIDs[groupKey{group.Language, group.Name}] = id
 	}
 
 	// Create words and their group associations
 	for _, word := range seedData.Words {
+		word.normalize()
+
 		// Insert word
 		result, err := tx.Exec(
-			"INSERT INTO words (latin_word, english_translation, parts) VALUES (?, ?, ?)",
-			word.LatinWord,
-			word.EnglishTranslation,
+			`INSERT INTO words (language_code, term, translation, reading, romanization, parts)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			word.Language,
+			word.Term,
+			word.Translation,
+			word.Reading,
+			word.Romanization,
 			word.Parts,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to insert word %s: %v", word.LatinWord, err)
+			return fmt.Errorf("failed to insert word %s: %v", word.Term, err)
 		}
 
 		wordID, err := result.LastInsertId()
 		if err != nil {
-			return fmt.Errorf("failed to get word ID for %s: %v", word.LatinWord, err)
+			return fmt.Errorf("failed to get word ID for %s: %v", word.Term, err)
+		}
+
+		// Insert senses, the translation first, and examples
+		for i, gloss := range append([]string{word.Translation}, word.Synonyms...) {
+			_, err = tx.Exec(
+				"INSERT INTO word_senses (word_id, gloss, priority) VALUES (?, ?, ?)",
+				wordID, gloss, i,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert sense %s of word %s: %v", gloss, word.Term, err)
+			}
+		}
+		for i, example := range word.Examples {
+			_, err = tx.Exec(
+				`INSERT INTO word_examples (word_id, sentence, translation, source, position)
+				VALUES (?, ?, ?, ?, ?)`,
+				wordID, example.Sentence, example.Translation, example.Source, i,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert example of word %s: %v", word.Term, err)
+			}
 		}
 
 		// Create word-group associations
 		for _, groupName := range word.Groups {
-			groupID, ok := groupIDs[groupName]
+			groupID, ok := groupIDs[groupKey{word.Language, groupName}]
 			if !ok {
-				return fmt.Errorf("unknown group name: %s", groupName)
+				return fmt.Errorf("unknown %s group name: %s", word.Language, groupName)
 			}
 
 			_, err = tx.Exec(
@@ -100,7 +174,7 @@ func (s *Seeder) LoadFromJSON(filePath string) error {
 			)
 			if err != nil {
 				return fmt.Errorf("failed to associate word %s with group %s: %v",
-					word.LatinWord, groupName, err)
+					word.Term, groupName, err)
 			}
 		}
 	}
@@ -0,0 +1,521 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Achievement metrics, computed from a learner's study sessions
+const (
+	MetricSessionsCompleted = "sessions_completed"
+	MetricReviews           = "reviews"
+	MetricStreakDays        = "streak_days"
+	MetricWordsMastered     = "words_mastered"
+	MetricPerfectSessions   = "perfect_sessions"
+)
+
+// AchievementMetrics lists the metrics achievement rules may use
+var AchievementMetrics = []string{
+	MetricSessionsCompleted, MetricReviews, MetricStreakDays, MetricWordsMastered, MetricPerfectSessions,
+}
+
+// AchievementRules declares the achievements learners can earn and how
+// experience points are awarded. It is read from a YAML file.
+type AchievementRules struct {
+	XP           XPRules           `yaml:"xp"`
+	Achievements []AchievementRule `yaml:"achievements"`
+}
+
+// XPRules awards XP per review. Level n is reached at
+// LevelStep * n * (n-1) / 2 XP.
+type XPRules struct {
+	CorrectReview   int `yaml:"correct_review"`
+	IncorrectReview int `yaml:"incorrect_review"`
+	LevelStep       int `yaml:"level_step"`
+}
+
+// AchievementRule is earned once Metric reaches Threshold, adding XP to the
+// learner's total. MinReviews is the number of reviews a perfect session
+// needs.
+type AchievementRule struct {
+	ID          string `yaml:"id"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Metric      string `yaml:"metric"`
+	Threshold   int    `yaml:"threshold"`
+	MinReviews  int    `yaml:"min_reviews"`
+	XP          int    `yaml:"xp"`
+}
+
+// ParseAchievementRules reads achievement rules from YAML, rejecting unknown
+// keys and metrics
+func ParseAchievementRules(data []byte) (AchievementRules, error) {
+	var rules AchievementRules
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rules); err != nil {
+		return rules, err
+	}
+	return rules, rules.validate()
+}
+
+func (r AchievementRules) validate() error {
+	if r.XP.LevelStep < 1 {
+		return fmt.Errorf("xp.level_step must be at least 1")
+	}
+	if r.XP.CorrectReview < 0 || r.XP.IncorrectReview < 0 {
+		return fmt.Errorf("xp per review must not be negative")
+	}
+
+	seen := make(map[string]bool, len(r.Achievements))
+	for i, a := range r.Achievements {
+		switch {
+		case a.ID == "":
+			return fmt.Errorf("achievement %d has no id", i+1)
+		case seen[a.ID]:
+			return fmt.Errorf("achievement %q is declared twice", a.ID)
+		case a.Name == "":
+			return fmt.Errorf("achievement %q has no name", a.ID)
+		case !slices.Contains(AchievementMetrics, a.Metric):
+			return fmt.Errorf("achievement %q has unknown metric %q", a.ID, a.Metric)
+		case a.Threshold < 1:
+			return fmt.Errorf("achievement %q needs a threshold of at least 1", a.ID)
+		case a.XP < 0 || a.MinReviews < 0:
+			return fmt.Errorf("achievement %q has a negative xp or min_reviews", a.ID)
+		}
+		seen[a.ID] = true
+	}
+	return nil
+}
+
+// Level returns the level reached with xp and the XP at which that level
+// and the next one start
+func (x XPRules) Level(xp int) (level, start, next int) {
+	level = 1
+	for x.LevelStep*level*(level+1)/2 <= xp {
+		level++
+	}
+	return level, x.LevelStep * level * (level - 1) / 2, x.LevelStep * (level + 1) * level / 2
+}
+
+// AchievementService evaluates achievement rules against learners' study
+// history and ranks learners by XP
+type AchievementService struct {
+	instrumented
+
+	db      *sql.DB
+	mastery MasteryConfig
+	rules   AchievementRules
+	events  *EventBus
+}
+
+// Achievement is a rule with a learner's progress towards it
+type Achievement struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Metric      string     `json:"metric"`
+	Threshold   int        `json:"threshold"`
+	XP          int        `json:"xp"`
+	Progress    int        `json:"progress"`
+	Earned      bool       `json:"earned"`
+	EarnedAt    *time.Time `json:"earned_at,omitempty"`
+}
+
+// AchievementSummary is a learner's XP, level and achievements. LevelXP and
+// NextLevelXP are the XP at which the current and the next level start.
+type AchievementSummary struct {
+	UserID       int           `json:"user_id"`
+	XP           int           `json:"xp"`
+	Level        int           `json:"level"`
+	LevelXP      int           `json:"level_xp"`
+	NextLevelXP  int           `json:"next_level_xp"`
+	Achievements []Achievement `json:"achievements"`
+}
+
+// LeaderboardEntry is a learner's position on a leaderboard. Learners with
+// the same XP share a rank.
+type LeaderboardEntry struct {
+	Rank         int    `json:"rank"`
+	UserID       int    `json:"user_id"`
+	Name         string `json:"name"`
+	XP           int    `json:"xp"`
+	Level        int    `json:"level"`
+	Achievements int    `json:"achievements"`
+}
+
+func NewAchievementService(db *sql.DB, rules AchievementRules) *AchievementService {
+	return &AchievementService{db: db, instrumented: instrumented{observer: nopObserver{}}, mastery: DefaultMasteryConfig(), rules: rules}
+}
+
+// SetMasteryConfig sets the thresholds deciding which words are mastered
+func (s *AchievementService) SetMasteryConfig(c MasteryConfig) {
+	s.mastery = c
+}
+
+// SetEventBus publishes achievement events on bus
+func (s *AchievementService) SetEventBus(bus *EventBus) {
+	s.events = bus
+}
+
+// GetAchievements evaluates the rules for learner, awarding any achievement
+// reached since the last evaluation, and returns their XP, level and
+// progress towards every achievement
+func (s *AchievementService) GetAchievements(learner *User) (*AchievementSummary, error) {
+	defer timeQuery(s.observer, "AchievementService.GetAchievements")()
+
+	achievements, err := s.evaluate(learner.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &AchievementSummary{UserID: learner.ID, Achievements: achievements}
+	xp, err := s.reviewXP("s.user_id = ?", learner.ID)
+	if err != nil {
+		return nil, err
+	}
+	summary.XP = xp[learner.ID]
+	for _, a := range achievements {
+		if a.Earned {
+			summary.XP += a.XP
+		}
+	}
+	summary.Level, summary.LevelXP, summary.NextLevelXP = s.rules.XP.Level(summary.XP)
+	return summary, nil
+}
+
+// Evaluate awards learner the achievements they have reached and returns the
+// newly earned ones
+func (s *AchievementService) Evaluate(userID int) ([]Achievement, error) {
+	defer timeQuery(s.observer, "AchievementService.Evaluate")()
+
+	before, err := s.earned(userID)
+	if err != nil {
+		return nil, err
+	}
+	achievements, err := s.evaluate(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	earned := []Achievement{}
+	for _, a := range achievements {
+		if _, ok := before[a.ID]; a.Earned && !ok {
+			earned = append(earned, a)
+		}
+	}
+	return earned, nil
+}
+
+// GetLeaderboard ranks every user by XP, highest first. Only achievements
+// already awarded count towards XP.
+func (s *AchievementService) GetLeaderboard(limit int) ([]LeaderboardEntry, error) {
+	defer timeQuery(s.observer, "AchievementService.GetLeaderboard")()
+
+	return s.leaderboard("1 = 1", limit)
+}
+
+// GetClassLeaderboard ranks the students of a class by XP
+func (s *AchievementService) GetClassLeaderboard(classID, limit int) ([]LeaderboardEntry, error) {
+	defer timeQuery(s.observer, "AchievementService.GetClassLeaderboard")()
+
+	return s.leaderboard("u.id IN (SELECT user_id FROM class_students WHERE class_id = ?)", limit, classID)
+}
+
+// RunEvaluator evaluates the achievements of the learner of every review and
+// completed session published on bus until ctx is cancelled
+func (s *AchievementService) RunEvaluator(ctx context.Context, bus *EventBus, logger *slog.Logger) {
+	sub := bus.Subscribe(EventFilter{}, 0)
+	defer func() { sub.Close() }()
+
+	var lastID uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				// Dropped for falling behind; resubscribe, replaying the
+				// events missed in the meantime
+				sub = bus.Subscribe(EventFilter{}, lastID)
+				continue
+			}
+			lastID = event.ID
+			if event.UserID == nil || (event.Type != EventReviewAdded && event.Type != EventSessionCompleted) {
+				continue
+			}
+			earned, err := s.Evaluate(*event.UserID)
+			if err != nil {
+				logger.Error("achievement evaluation failed", "user_id", *event.UserID, "error", err)
+				continue
+			}
+			for _, a := range earned {
+				logger.Info("achievement earned", "user_id", *event.UserID, "achievement", a.ID)
+			}
+		}
+	}
+}
+
+// evaluate computes a learner's progress towards every rule and records the
+// achievements reached. Achievements stay earned even if their metric falls
+// back below the threshold.
+func (s *AchievementService) evaluate(userID int) ([]Achievement, error) {
+	values := map[string]int{}
+	achievements := make([]Achievement, len(s.rules.Achievements))
+	for i, rule := range s.rules.Achievements {
+		key := fmt.Sprintf("%s/%d", rule.Metric, rule.MinReviews)
+		value, ok := values[key]
+		if !ok {
+			var err error
+			if value, err = s.metricValue(userID, rule); err != nil {
+				return nil, err
+			}
+			values[key] = value
+		}
+		achievements[i] = Achievement{
+			ID:          rule.ID,
+			Name:        rule.Name,
+			Description: rule.Description,
+			Metric:      rule.Metric,
+			Threshold:   rule.Threshold,
+			XP:          rule.XP,
+			Progress:    value,
+		}
+	}
+
+	earned, err := s.earned(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range achievements {
+		a := &achievements[i]
+		if earnedAt, ok := earned[a.ID]; ok {
+			a.Earned, a.EarnedAt = true, &earnedAt
+			continue
+		}
+		if a.Progress < a.Threshold {
+			continue
+		}
+
+		var earnedAt time.Time
+		err := s.db.QueryRow(`
+			INSERT INTO user_achievements (user_id, achievement_id) VALUES (?, ?)
+			ON CONFLICT DO NOTHING
+			RETURNING earned_at`, userID, a.ID,
+		).Scan(&earnedAt)
+		if err == sql.ErrNoRows {
+			// Awarded concurrently
+			err = s.db.QueryRow(
+				"SELECT earned_at FROM user_achievements WHERE user_id = ? AND achievement_id = ?",
+				userID, a.ID,
+			).Scan(&earnedAt)
+		} else if err == nil {
+			s.events.Publish(Event{Type: EventAchievementEarned, UserID: &userID, Data: *a})
+		}
+		if err != nil {
+			return nil, err
+		}
+		a.Earned, a.EarnedAt = true, &earnedAt
+	}
+	return achievements, nil
+}
+
+// earned returns when a learner earned each of their achievements
+func (s *AchievementService) earned(userID int) (map[string]time.Time, error) {
+	rows, err := s.db.Query("SELECT achievement_id, earned_at FROM user_achievements WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	earned := map[string]time.Time{}
+	for rows.Next() {
+		var id string
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, err
+		}
+		earned[id] = at
+	}
+	return earned, rows.Err()
+}
+
+// metricValue computes the metric of a rule for a learner
+func (s *AchievementService) metricValue(userID int, rule AchievementRule) (int, error) {
+	var value int
+	switch rule.Metric {
+	case MetricSessionsCompleted:
+		err := s.db.QueryRow(
+			"SELECT COUNT(*) FROM study_sessions WHERE user_id = ? AND completed_at IS NOT NULL", userID,
+		).Scan(&value)
+		return value, err
+	case MetricReviews:
+		err := s.db.QueryRow(`
+			SELECT COUNT(*)
+			FROM word_review_items r
+			JOIN study_sessions s ON s.id = r.study_session_id
+			WHERE s.user_id = ?`, userID,
+		).Scan(&value)
+		return value, err
+	case MetricPerfectSessions:
+		err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM (
+				SELECT s.id
+				FROM study_sessions s
+				JOIN word_review_items r ON r.study_session_id = s.id
+				WHERE s.user_id = ? AND s.completed_at IS NOT NULL
+				GROUP BY s.id
+				HAVING COUNT(*) >= MAX(?, 1) AND SUM(NOT r.correct) = 0
+			)`, userID, rule.MinReviews,
+		).Scan(&value)
+		return value, err
+	case MetricStreakDays:
+		return s.longestStreak(userID)
+	case MetricWordsMastered:
+		history, err := loadLearnerHistory(s.db, &userID, nil)
+		if err != nil {
+			return 0, err
+		}
+		for _, h := range history {
+			if s.mastery.Level(h).IsLearned() {
+				value++
+			}
+		}
+		return value, nil
+	}
+	return 0, fmt.Errorf("unknown achievement metric %q", rule.Metric)
+}
+
+// longestStreak returns the longest run of consecutive UTC days on which a
+// learner reviewed words
+func (s *AchievementService) longestStreak(userID int) (int, error) {
+	rows, err := s.db.Query(`
+		SELECT DISTINCT date(r.created_at)
+		FROM word_review_items r
+		JOIN study_sessions s ON s.id = r.study_session_id
+		WHERE s.user_id = ?
+		ORDER BY 1`, userID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	longest, current := 0, 0
+	var previous time.Time
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return 0, err
+		}
+		day, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			return 0, err
+		}
+		if current > 0 && day.Equal(previous.AddDate(0, 0, 1)) {
+			current++
+		} else {
+			current = 1
+		}
+		previous = day
+		longest = max(longest, current)
+	}
+	return longest, rows.Err()
+}
+
+// reviewXP returns the XP earned from reviews by each learner whose sessions
+// match a condition on study_sessions s
+func (s *AchievementService) reviewXP(condition string, args ...interface{}) (map[int]int, error) {
+	args = append([]interface{}{s.rules.XP.CorrectReview, s.rules.XP.IncorrectReview}, args...)
+	rows, err := s.db.Query(`
+		SELECT s.user_id, SUM(CASE WHEN r.correct THEN ? ELSE ? END)
+		FROM word_review_items r
+		JOIN study_sessions s ON s.id = r.study_session_id
+		WHERE s.user_id IS NOT NULL AND `+condition+`
+		GROUP BY s.user_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	xp := map[int]int{}
+	for rows.Next() {
+		var userID, points int
+		if err := rows.Scan(&userID, &points); err != nil {
+			return nil, err
+		}
+		xp[userID] = points
+	}
+	return xp, rows.Err()
+}
+
+// leaderboard ranks the users matching a condition on users u by XP
+func (s *AchievementService) leaderboard(condition string, limit int, args ...interface{}) ([]LeaderboardEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT u.id, u.name, ua.achievement_id
+		FROM users u
+		LEFT JOIN user_achievements ua ON ua.user_id = u.id
+		WHERE `+condition+`
+		ORDER BY u.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bonus := make(map[string]int, len(s.rules.Achievements))
+	for _, rule := range s.rules.Achievements {
+		bonus[rule.ID] = rule.XP
+	}
+
+	var entries []LeaderboardEntry
+	for rows.Next() {
+		var userID int
+		var name string
+		var achievementID sql.NullString
+		if err := rows.Scan(&userID, &name, &achievementID); err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 || entries[len(entries)-1].UserID != userID {
+			entries = append(entries, LeaderboardEntry{UserID: userID, Name: name})
+		}
+		entry := &entries[len(entries)-1]
+		if xp, ok := bonus[achievementID.String]; ok && achievementID.Valid {
+			entry.XP += xp
+			entry.Achievements++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	xp, err := s.reviewXP("s.user_id IN (SELECT u.id FROM users u WHERE "+condition+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].XP += xp[entries[i].UserID]
+		entries[i].Level, _, _ = s.rules.XP.Level(entries[i].XP)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].XP > entries[j].XP })
+	for i := range entries {
+		entries[i].Rank = i + 1
+		if i > 0 && entries[i].XP == entries[i-1].XP {
+			entries[i].Rank = entries[i-1].Rank
+		}
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	if entries == nil {
+		entries = []LeaderboardEntry{}
+	}
+	return entries, nil
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testAchievements = `
+xp:
+  correct_review: 10
+  incorrect_review: 2
+  level_step: 100
+achievements:
+  - id: first_session
+    name: First steps
+    metric: sessions_completed
+    threshold: 1
+    xp: 50
+  - id: streak
+    name: On a roll
+    metric: streak_days
+    threshold: 3
+    xp: 20
+  - id: perfect_session
+    name: Flawless
+    metric: perfect_sessions
+    threshold: 1
+    min_reviews: 2
+  - id: mastered
+    name: Wordsmith
+    metric: words_mastered
+    threshold: 1
+  - id: hard_worker
+    name: Hard worker
+    metric: reviews
+    threshold: 100
+    xp: 500
+`
+
+func TestParseAchievementRules(t *testing.T) {
+	rules, err := ParseAchievementRules([]byte(testAchievements))
+	assert.NoError(t, err)
+	assert.Len(t, rules.Achievements, 5)
+	assert.Equal(t, 2, rules.Achievements[2].MinReviews)
+
+	invalid := map[string]string{
+		"unknown metric": "xp: {level_step: 100}\nachievements: [{id: a, name: A, metric: logins, threshold: 1}]",
+		"unknown key":    "xp: {level_step: 100, bonus: 5}",
+		"duplicate id":   "xp: {level_step: 100}\nachievements: [{id: a, name: A, metric: reviews, threshold: 1}, {id: a, name: B, metric: reviews, threshold: 2}]",
+		"no threshold":   "xp: {level_step: 100}\nachievements: [{id: a, name: A, metric: reviews}]",
+		"no level step":  "xp: {correct_review: 10}",
+	}
+	for name, data := range invalid {
+		_, err := ParseAchievementRules([]byte(data))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestXPLevels(t *testing.T) {
+	xp := XPRules{LevelStep: 100}
+	for _, tt := range []struct{ xp, level, start, next int }{
+		{0, 1, 0, 100},
+		{99, 1, 0, 100},
+		{100, 2, 100, 300},
+		{650, 4, 600, 1000},
+	} {
+		level, start, next := xp.Level(tt.xp)
+		assert.Equal(t, []int{tt.level, tt.start, tt.next}, []int{level, start, next}, "%d XP", tt.xp)
+	}
+}
+
+// setupAchievements creates three learners. Marcus studies on three days in a
+// row, answering amare right four times.
+func setupAchievements(t *testing.T) *AchievementService {
+	db := setupTestDB(t)
+	t.Cleanup(func() { db.Close() })
+	_, err := db.Exec(`
+		INSERT INTO users (id, name) VALUES (1, 'Marcus'), (2, 'Julia'), (3, 'Tullia');
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES
+			(1, 'la', 'amare', 'to love', '{}'),
+			(2, 'la', 'videre', 'to see', '{}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Basics');
+		INSERT INTO study_sessions (id, group_id, user_id, created_at, completed_at) VALUES
+			(1, 1, 1, '2026-10-17 08:00:00', '2026-10-17 08:10:00'),
+			(2, 1, 1, '2026-10-18 08:00:00', '2026-10-18 08:10:00'),
+			(3, 1, 1, '2026-10-19 08:00:00', NULL);
+		INSERT INTO word_review_items (word_id, study_session_id, correct, created_at) VALUES
+			(1, 1, 1, '2026-10-17 08:01:00'),
+			(2, 1, 0, '2026-10-17 08:02:00'),
+			(1, 2, 1, '2026-10-18 08:01:00'),
+			(1, 2, 1, '2026-10-18 08:02:00'),
+			(1, 3, 1, '2026-10-19 08:01:00');
+	`)
+	assert.NoError(t, err)
+
+	rules, err := ParseAchievementRules([]byte(testAchievements))
+	assert.NoError(t, err)
+	return NewAchievementService(db, rules)
+}
+
+func TestAchievementsAreAwardedOnce(t *testing.T) {
+	achievements := setupAchievements(t)
+	bus := NewEventBus()
+	sub := bus.Subscribe(EventFilter{UserID: 1}, 0)
+	defer sub.Close()
+	achievements.SetEventBus(bus)
+
+	earned, err := achievements.Evaluate(1)
+	assert.NoError(t, err)
+	var ids []string
+	for _, a := range earned {
+		ids = append(ids, a.ID)
+	}
+	assert.Equal(t, []string{"first_session", "streak", "perfect_session", "mastered"}, ids)
+	for range earned {
+		assert.Equal(t, EventAchievementEarned, nextEvent(t, sub).Type)
+	}
+
+	earned, err = achievements.Evaluate(1)
+	assert.NoError(t, err)
+	assert.Empty(t, earned)
+
+	summary, err := achievements.GetAchievements(&User{ID: 1, Name: "Marcus"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4*10+2+50+20, summary.XP)
+	assert.Equal(t, 2, summary.Level)
+	assert.Equal(t, 100, summary.LevelXP)
+	assert.Equal(t, 300, summary.NextLevelXP)
+	hardWorker := summary.Achievements[4]
+	assert.False(t, hardWorker.Earned)
+	assert.Equal(t, 5, hardWorker.Progress)
+	assert.Nil(t, hardWorker.EarnedAt)
+	assert.NotNil(t, summary.Achievements[0].EarnedAt)
+}
+
+func TestLeaderboardRanksByXP(t *testing.T) {
+	achievements := setupAchievements(t)
+	_, err := achievements.db.Exec(`
+		INSERT INTO study_sessions (id, group_id, user_id, created_at) VALUES (4, 1, 2, '2026-10-19 09:00:00');
+		INSERT INTO word_review_items (word_id, study_session_id, correct, created_at) VALUES
+			(2, 4, 1, '2026-10-19 09:01:00');
+	`)
+	assert.NoError(t, err)
+
+	// Marcus has earned nothing yet, so only his reviews count
+	board, err := achievements.GetLeaderboard(10)
+	assert.NoError(t, err)
+	if assert.Len(t, board, 3) {
+		assert.Equal(t, LeaderboardEntry{Rank: 1, UserID: 1, Name: "Marcus", XP: 42, Level: 1}, board[0])
+		assert.Equal(t, LeaderboardEntry{Rank: 2, UserID: 2, Name: "Julia", XP: 10, Level: 1}, board[1])
+		assert.Equal(t, 3, board[2].Rank)
+	}
+
+	_, err = achievements.Evaluate(1)
+	assert.NoError(t, err)
+	_, err = achievements.db.Exec("INSERT INTO word_review_items (word_id, study_session_id, correct) VALUES (2, 4, 0)")
+	assert.NoError(t, err)
+
+	board, err = achievements.GetLeaderboard(2)
+	assert.NoError(t, err)
+	if assert.Len(t, board, 2) {
+		assert.Equal(t, LeaderboardEntry{Rank: 1, UserID: 1, Name: "Marcus", XP: 112, Level: 2, Achievements: 4}, board[0])
+		assert.Equal(t, 12, board[1].XP)
+	}
+}
+
+func TestRunEvaluatorAwardsOnStudyEvents(t *testing.T) {
+	achievements := setupAchievements(t)
+	bus := NewEventBus()
+	sub := bus.Subscribe(EventFilter{}, 0)
+	defer sub.Close()
+	achievements.SetEventBus(bus)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go achievements.RunEvaluator(ctx, bus, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// The evaluator subscribes in the background, so publish until it has
+	// caught an event
+	learner := 1
+	assert.Eventually(t, func() bool {
+		bus.Publish(Event{Type: EventSessionCompleted, UserID: &learner, SessionID: 2})
+		var count int
+		_ = achievements.db.QueryRow("SELECT COUNT(*) FROM user_achievements WHERE user_id = 1").Scan(&count)
+		return count == 4
+	}, time.Second, 10*time.Millisecond)
+
+	var earned []string
+	assert.Eventually(t, func() bool {
+		for len(sub.Events) > 0 {
+			if e := <-sub.Events; e.Type == EventAchievementEarned {
+				earned = append(earned, e.Data.(Achievement).ID)
+			}
+		}
+		return len(earned) == 4
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"first_session", "streak", "perfect_session", "mastered"}, earned)
+}
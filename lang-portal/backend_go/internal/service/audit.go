@@ -0,0 +1,470 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	apperrors "lang-portal/internal/errors"
+)
+
+// Audited entity types
+const (
+	AuditEntityWord  = "word"
+	AuditEntityGroup = "group"
+)
+
+// Audited actions
+const (
+	AuditCreate     = "create"
+	AuditUpdate     = "update"
+	AuditDelete     = "delete"
+	AuditRestore    = "restore"
+	AuditAddWord    = "add_word"
+	AuditRemoveWord = "remove_word"
+	AuditRevert     = "revert"
+)
+
+// errNoChange is returned by an audited change that found nothing to do; no
+// audit entry is recorded for it
+var errNoChange = errors.New("no change")
+
+// AuditService lists recorded changes and reverts them
+type AuditService struct {
+	instrumented
+
+	db *sql.DB
+}
+
+// AuditEntry is one recorded change. Before and After are snapshots of the
+// entity; Before is null for creations.
+type AuditEntry struct {
+	ID           int             `json:"id"`
+	Entity       string          `json:"entity"`
+	EntityID     int             `json:"entity_id"`
+	Action       string          `json:"action"`
+	UserID       *int            `json:"user_id"`
+	UserName     *string         `json:"user_name"`
+	Before       json.RawMessage `json:"before"`
+	After        json.RawMessage `json:"after"`
+	RevertsID    *int            `json:"reverts_id,omitempty"`
+	RevertedByID *int            `json:"reverted_by_id,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// AuditFilter narrows the entries returned by ListEntries. Zero values match
+// everything.
+type AuditFilter struct {
+	Entity   string
+	EntityID int
+	Limit    int
+}
+
+// wordSnapshot is the audited state of a word
+type wordSnapshot struct {
+	ID           int             `json:"id"`
+	Language     string          `json:"language"`
+	Term         string          `json:"term"`
+	Translation  string          `json:"translation"`
+	Reading      *string         `json:"reading"`
+	Romanization *string         `json:"romanization"`
+	Parts        json.RawMessage `json:"parts"`
+	DeletedAt    *string         `json:"deleted_at"`
+	Senses       []Sense         `json:"senses"`
+	Examples     []Example       `json:"examples"`
+	Tags         []string        `json:"tags"`
+}
+
+// groupSnapshot is the audited state of a group, including its members
+type groupSnapshot struct {
+	ID        int         `json:"id"`
+	Language  string      `json:"language"`
+	Name      string      `json:"name"`
+	DeletedAt *string     `json:"deleted_at"`
+	WordIDs   []int       `json:"word_ids"`
+	Query     *GroupQuery `json:"query"`
+}
+
+func NewAuditService(db *sql.DB) *AuditService {
+	return &AuditService{db: db, instrumented: instrumented{observer: nopObserver{}}}
+}
+
+const auditEntryColumns = `
+	SELECT a.id, a.entity, a.entity_id, a.action, a.user_id, u.name,
+		   a.before, a.after, a.reverts_id, r.id, a.created_at
+	FROM audit_log a
+	LEFT JOIN users u ON u.id = a.user_id
+	LEFT JOIN audit_log r ON r.reverts_id = a.id`
+
+// ListEntries returns the recorded changes matching the filter, newest first
+func (s *AuditService) ListEntries(filter AuditFilter) ([]AuditEntry, error) {
+	defer timeQuery(s.observer, "AuditService.ListEntries")()
+
+	var conditions []string
+	var args []interface{}
+	if filter.Entity != "" {
+		conditions = append(conditions, "a.entity = ?")
+		args = append(args, filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		conditions = append(conditions, "a.entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+
+	query := auditEntryColumns
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY a.id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetEntry retrieves a single audit entry, or nil if it does not exist
+func (s *AuditService) GetEntry(id int) (*AuditEntry, error) {
+	defer timeQuery(s.observer, "AuditService.GetEntry")()
+
+	return getAuditEntry(s.db, id)
+}
+
+// Revert restores the entity recorded by an entry to its state before the
+// change, and records the revert as a new entry which it returns. It returns
+// nil if the entry does not exist. Only the latest change to an entity can
+// be reverted; earlier ones conflict until the later changes are reverted.
+// Changes to a group may only be reverted by those allowed to edit it.
+func (s *AuditService) Revert(actor *User, entryID int) (*AuditEntry, error) {
+	defer timeQuery(s.observer, "AuditService.Revert")()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entry, err := getAuditEntry(tx, entryID)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	if entry.RevertedByID != nil {
+		return nil, apperrors.New(apperrors.CodeAuditAlreadyReverted).
+			WithData(map[string]int{"reverted_by_id": *entry.RevertedByID})
+	}
+	if entry.Entity == AuditEntityGroup {
+		if err := requireGroupEditor(tx, actor, entry.EntityID); err != nil {
+			return nil, err
+		}
+	}
+
+	current, err := loadSnapshot(tx, entry.Entity, entry.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || !sameJSON(currentJSON, entry.After) {
+		return nil, apperrors.New(apperrors.CodeAuditRevertConflict).
+			WithData(map[string]interface{}{"entity": entry.Entity, "entity_id": entry.EntityID})
+	}
+
+	if err := applySnapshot(tx, entry.Entity, entry.EntityID, entry.Before); err != nil {
+		return nil, err
+	}
+
+	after, err := loadSnapshot(tx, entry.Entity, entry.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := recordAudit(tx, actor, entry.Entity, entry.EntityID, AuditRevert, current, after, &entry.ID)
+	if err != nil {
+		return nil, translateDBError(tx, err)
+	}
+	revert, err := getAuditEntry(tx, int(id))
+	if err != nil {
+		return nil, err
+	}
+	return revert, tx.Commit()
+}
+
+// auditedChange runs change in a transaction and records the entity's state
+// before and after it. For creations pass id 0; change returns the id of the
+// entity it changed.
+func auditedChange(db *sql.DB, actor *User, entity, action string, id int, change func(tx *sql.Tx) (int, error)) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var before interface{}
+	if id != 0 {
+		if before, err = loadSnapshot(tx, entity, id); err != nil {
+			return 0, err
+		}
+	}
+
+	id, err = change(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	after, err := loadSnapshot(tx, entity, id)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := recordAudit(tx, actor, entity, id, action, before, after, nil); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// recordAudit inserts an audit entry and returns its id
+func recordAudit(q queryer, actor *User, entity string, id int, action string, before, after interface{}, revertsID *int) (int64, error) {
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return 0, err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return 0, err
+	}
+
+	var beforeValue interface{}
+	userID := actorID(actor)
+	if string(beforeJSON) != "null" {
+		beforeValue = string(beforeJSON)
+	}
+
+	result, err := q.Exec(`
+		INSERT INTO audit_log (entity, entity_id, action, user_id, before, after, reverts_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entity, id, action, userID, beforeValue, string(afterJSON), revertsID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func getAuditEntry(q queryer, id int) (*AuditEntry, error) {
+	rows, err := q.Query(auditEntryColumns+" WHERE a.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	return scanAuditEntry(rows)
+}
+
+func scanAuditEntry(rows *sql.Rows) (*AuditEntry, error) {
+	var e AuditEntry
+	var userID, revertsID, revertedByID sql.NullInt64
+	var userName, before sql.NullString
+	var after string
+	if err := rows.Scan(&e.ID, &e.Entity, &e.EntityID, &e.Action, &userID, &userName,
+		&before, &after, &revertsID, &revertedByID, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	e.UserID = nullIntPtr(userID)
+	e.RevertsID = nullIntPtr(revertsID)
+	e.RevertedByID = nullIntPtr(revertedByID)
+	if userName.Valid {
+		e.UserName = &userName.String
+	}
+	e.Before = json.RawMessage("null")
+	if before.Valid {
+		e.Before = json.RawMessage(before.String)
+	}
+	e.After = json.RawMessage(after)
+	return &e, nil
+}
+
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+// loadSnapshot returns the current state of an entity, or a nil interface if
+// it does not exist
+func loadSnapshot(q queryer, entity string, id int) (interface{}, error) {
+	switch entity {
+	case AuditEntityWord:
+		var w wordSnapshot
+		var parts string
+		var reading, romanization, deletedAt sql.NullString
+		err := q.QueryRow(`
+			SELECT id, language_code, term, translation, reading, romanization, parts,
+				   CAST(deleted_at AS TEXT)
+			FROM words WHERE id = ?`, id,
+		).Scan(&w.ID, &w.Language, &w.Term, &w.Translation, &reading, &romanization, &parts, &deletedAt)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		w.Parts = json.RawMessage(parts)
+		w.Reading = nullStringPtr(reading)
+		w.Romanization = nullStringPtr(romanization)
+		w.DeletedAt = nullStringPtr(deletedAt)
+		if w.Senses, err = loadSenses(q, id); err != nil {
+			return nil, err
+		}
+		if w.Examples, err = loadExamples(q, id); err != nil {
+			return nil, err
+		}
+		tags, err := loadTags(q, []int{id})
+		if err != nil {
+			return nil, err
+		}
+		w.Tags = tags[id]
+		if w.Tags == nil {
+			w.Tags = []string{}
+		}
+		return &w, nil
+
+	case AuditEntityGroup:
+		var g groupSnapshot
+		var deletedAt, query sql.NullString
+		err := q.QueryRow("SELECT id, language_code, name, CAST(deleted_at AS TEXT), query FROM groups WHERE id = ?", id).
+			Scan(&g.ID, &g.Language, &g.Name, &deletedAt, &query)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		g.DeletedAt = nullStringPtr(deletedAt)
+		if g.Query, err = parseGroupQuery(query); err != nil {
+			return nil, err
+		}
+
+		rows, err := q.Query("SELECT word_id FROM words_groups WHERE group_id = ? ORDER BY word_id", id)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		g.WordIDs = []int{}
+		for rows.Next() {
+			var wordID int
+			if err := rows.Scan(&wordID); err != nil {
+				return nil, err
+			}
+			g.WordIDs = append(g.WordIDs, wordID)
+		}
+		return &g, rows.Err()
+	}
+	return nil, fmt.Errorf("unknown audit entity %q", entity)
+}
+
+// applySnapshot writes a recorded state back to an entity. A null snapshot,
+// the state before a creation, moves the entity to the trash.
+func applySnapshot(tx *sql.Tx, entity string, id int, snapshot json.RawMessage) error {
+	if len(snapshot) == 0 || string(snapshot) == "null" {
+		_, err := softDelete(tx, entityTable(entity), id)
+		return err
+	}
+
+	switch entity {
+	case AuditEntityWord:
+		var w wordSnapshot
+		if err := json.Unmarshal(snapshot, &w); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			UPDATE words
+			SET language_code = ?, term = ?, translation = ?, reading = ?, romanization = ?,
+				parts = ?, deleted_at = ?
+			WHERE id = ?`,
+			w.Language, w.Term, w.Translation, w.Reading, w.Romanization,
+			string(w.Parts), w.DeletedAt, id,
+		)
+		if err != nil {
+			return translateDBError(tx, err)
+		}
+		return writeWordDetails(tx, id, w.Senses, w.Examples, w.Tags)
+
+	case AuditEntityGroup:
+		var g groupSnapshot
+		if err := json.Unmarshal(snapshot, &g); err != nil {
+			return err
+		}
+		queryJSON, err := groupQueryJSON(g.Query)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			"UPDATE groups SET language_code = ?, name = ?, deleted_at = ?, query = ? WHERE id = ?",
+			g.Language, g.Name, g.DeletedAt, queryJSON, id,
+		)
+		if err != nil {
+			return translateDBError(tx, err)
+		}
+		if _, err := tx.Exec("DELETE FROM words_groups WHERE group_id = ?", id); err != nil {
+			return err
+		}
+		for _, wordID := range g.WordIDs {
+			_, err := tx.Exec("INSERT INTO words_groups (word_id, group_id) VALUES (?, ?)", wordID, id)
+			if err != nil {
+				return translateDBError(tx, err,
+					reference{"word_id", "words", wordID, apperrors.CodeWordNotFound},
+				)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown audit entity %q", entity)
+}
+
+// entityTable returns the table holding an audited entity
+func entityTable(entity string) string {
+	if entity == AuditEntityGroup {
+		return "groups"
+	}
+	return "words"
+}
+
+// sameJSON reports whether two JSON documents are equal, ignoring key order
+// and formatting
+func sameJSON(a, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+)
+
+func TestAuditRecordsWordChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	users := NewUserService(db)
+	teacher, err := users.CreateUser("Ms. Varro", RoleTeacher)
+	assert.NoError(t, err)
+	assert.False(t, teacher.CreatedAt.IsZero())
+
+	words := NewWordService(db)
+	audit := NewAuditService(db)
+
+	word, err := words.CreateWord(teacher, WordInput{Term: "amare", Translation: "to love"})
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", word.Parts)
+
+	_, err = words.UpdateWord(nil, word.ID, WordInput{
+		Term:        "amare",
+		Translation: "to like",
+		Parts:       json.RawMessage(`{"type":"verb"}`),
+	})
+	assert.NoError(t, err)
+
+	entries, err := audit.ListEntries(AuditFilter{Entity: AuditEntityWord, EntityID: word.ID})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	update, create := entries[0], entries[1]
+	assert.Equal(t, AuditUpdate, update.Action)
+	assert.Nil(t, update.UserID, "anonymous changes have no user")
+	assert.JSONEq(t, `{"id":1,"language":"la","term":"amare","translation":"to love","reading":null,"romanization":null,"parts":{},"deleted_at":null,"senses":[{"gloss":"to love","priority":0}],"examples":[],"tags":[]}`, string(update.Before))
+	assert.JSONEq(t, `{"id":1,"language":"la","term":"amare","translation":"to like","reading":null,"romanization":null,"parts":{"type":"verb"},"deleted_at":null,"senses":[{"gloss":"to like","priority":0}],"examples":[],"tags":[]}`, string(update.After))
+
+	assert.Equal(t, AuditCreate, create.Action)
+	assert.Equal(t, teacher.ID, *create.UserID)
+	assert.Equal(t, "Ms. Varro", *create.UserName)
+	assert.Equal(t, "null", string(create.Before))
+
+	// Only the latest change can be reverted
+	_, err = audit.Revert(teacher, create.ID)
+	assertAppError(t, err, apperrors.CodeAuditRevertConflict, http.StatusConflict)
+
+	revert, err := audit.Revert(teacher, update.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, AuditRevert, revert.Action)
+	assert.Equal(t, update.ID, *revert.RevertsID)
+
+	reverted, err := words.GetWordByID(word.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "to love", reverted.Translation)
+	assert.Equal(t, "{}", reverted.Parts)
+
+	_, err = audit.Revert(teacher, update.ID)
+	assertAppError(t, err, apperrors.CodeAuditAlreadyReverted, http.StatusConflict)
+
+	// Reverting the revert reapplies the update
+	_, err = audit.Revert(System, revert.ID)
+	assert.NoError(t, err)
+	reverted, err = words.GetWordByID(word.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "to like", reverted.Translation)
+
+	missing, err := audit.Revert(System, 999)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestAuditRevertsGroupChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts)
+		VALUES (1, 'la', 'amare', 'to love', '{"type":"verb"}');
+	`)
+	assert.NoError(t, err)
+
+	groups := NewGroupService(db)
+	audit := NewAuditService(db)
+
+	group, err := groups.CreateGroup(nil, "Verbs", "", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, groups.AddWordToGroup(System, 1, group.ID))
+
+	entries, err := audit.ListEntries(AuditFilter{Entity: AuditEntityGroup, EntityID: group.ID})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, AuditAddWord, entries[0].Action)
+	assert.JSONEq(t, `[1]`, string(mustField(t, entries[0].After, "word_ids")))
+
+	_, err = audit.Revert(System, entries[0].ID)
+	assert.NoError(t, err)
+	withWords, err := groups.GetGroupByID(group.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, withWords.Words)
+
+	// Once the later change is undone, reverting the creation moves the
+	// group to the trash
+	_, err = audit.Revert(System, entries[1].ID)
+	assert.NoError(t, err)
+	trashed, err := groups.GetGroupByID(group.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, trashed)
+
+	restored, err := NewTrashService(db).Restore(System, TrashGroups, group.ID)
+	assert.NoError(t, err)
+	assert.True(t, restored)
+
+	// Removing a word that is not in the group records nothing
+	assert.NoError(t, groups.RemoveWordFromGroup(System, 1, group.ID))
+
+	entries, err = audit.ListEntries(AuditFilter{Entity: AuditEntityGroup, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{AuditRestore, AuditRevert}, []string{entries[0].Action, entries[1].Action})
+}
+
+// mustField extracts a top-level field of a JSON object
+func mustField(t *testing.T, raw json.RawMessage, field string) json.RawMessage {
+	t.Helper()
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err != nil {
+		t.Fatalf("invalid JSON %s: %v", raw, err)
+	}
+	return object[field]
+}
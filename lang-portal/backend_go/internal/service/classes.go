@@ -0,0 +1,640 @@
+package service
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+
+	apperrors "lang-portal/internal/errors"
+)
+
+// Assignment statuses of a student. Completed and late assignments have a
+// session reaching the required accuracy, completed before or after the due
+// date; overdue ones have none although the due date has passed.
+const (
+	AssignmentCompleted  = "completed"
+	AssignmentLate       = "late"
+	AssignmentOverdue    = "overdue"
+	AssignmentInProgress = "in_progress"
+	AssignmentNotStarted = "not_started"
+)
+
+// ClassService manages classes, their students and assignments, and reports
+// on the students' progress to their teacher
+type ClassService struct {
+	instrumented
+
+	db      *sql.DB
+	mastery MasteryConfig
+}
+
+// Class is a group of students taught by a teacher
+type Class struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	TeacherID    int       `json:"teacher_id"`
+	TeacherName  string    `json:"teacher_name"`
+	StudentCount int       `json:"student_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ClassDetails is a class with its students
+type ClassDetails struct {
+	Class
+	Students []User `json:"students"`
+}
+
+// Assignment asks the students of a class to study a group by DueAt. Progress
+// lists the status of every student for the teacher, and only their own for
+// a student.
+type Assignment struct {
+	ID               int                  `json:"id"`
+	ClassID          int                  `json:"class_id"`
+	GroupID          int                  `json:"group_id"`
+	GroupName        string               `json:"group_name"`
+	StudyActivityID  *int                 `json:"study_activity_id,omitempty"`
+	DueAt            time.Time            `json:"due_at"`
+	RequiredAccuracy float64              `json:"required_accuracy"`
+	CreatedAt        time.Time            `json:"created_at"`
+	Progress         []AssignmentProgress `json:"progress"`
+}
+
+// AssignmentInput holds the fields of a new assignment
+type AssignmentInput struct {
+	GroupID          int
+	StudyActivityID  *int
+	DueAt            time.Time
+	RequiredAccuracy float64
+}
+
+// AssignmentProgress is a student's status on an assignment. BestAccuracy is
+// the highest accuracy of their completed sessions counting towards it, and
+// CompletedAt when the first one reaching the required accuracy ended.
+type AssignmentProgress struct {
+	UserID       int        `json:"user_id"`
+	Name         string     `json:"name"`
+	Status       string     `json:"status"`
+	Sessions     int        `json:"sessions"`
+	BestAccuracy *float64   `json:"best_accuracy,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+// StudentReport summarises a student's assignments and reviews in the
+// groups assigned to their class
+type StudentReport struct {
+	UserID        int            `json:"user_id"`
+	Name          string         `json:"name"`
+	Assignments   map[string]int `json:"assignments"`
+	Reviews       int            `json:"reviews"`
+	Accuracy      *float64       `json:"accuracy"`
+	LastStudiedAt *time.Time     `json:"last_studied_at"`
+}
+
+// WordReport summarises how a class does on a word of its assigned groups.
+// Mastered counts the students who have mastered it.
+type WordReport struct {
+	WordID      int     `json:"word_id"`
+	Term        string  `json:"term"`
+	Translation string  `json:"translation"`
+	Reviews     int     `json:"reviews"`
+	Accuracy    float64 `json:"accuracy"`
+	Students    int     `json:"students"`
+	Mastered    int     `json:"mastered"`
+}
+
+func NewClassService(db *sql.DB) *ClassService {
+	return &ClassService{db: db, instrumented: instrumented{observer: nopObserver{}}, mastery: DefaultMasteryConfig()}
+}
+
+// SetMasteryConfig sets the thresholds deciding which words are mastered
+func (s *ClassService) SetMasteryConfig(c MasteryConfig) {
+	s.mastery = c
+}
+
+// classColumns are the columns scanned by scanClass, selected from classes c
+const classColumns = `
+	c.id, c.name, c.teacher_id, t.name, c.created_at,
+	(SELECT COUNT(*) FROM class_students cs WHERE cs.class_id = c.id)`
+
+const classFrom = "FROM classes c JOIN users t ON t.id = c.teacher_id"
+
+func scanClass(row interface{ Scan(...interface{}) error }) (*Class, error) {
+	var c Class
+	if err := row.Scan(&c.ID, &c.Name, &c.TeacherID, &c.TeacherName, &c.CreatedAt, &c.StudentCount); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetClasses lists the classes user teaches or attends, by name
+func (s *ClassService) GetClasses(user *User) ([]Class, error) {
+	defer timeQuery(s.observer, "ClassService.GetClasses")()
+
+	rows, err := s.db.Query(`
+		SELECT `+classColumns+` `+classFrom+`
+		WHERE c.teacher_id = ? OR c.id IN (SELECT class_id FROM class_students WHERE user_id = ?)
+		ORDER BY c.name, c.id`, user.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classes := []Class{}
+	for rows.Next() {
+		c, err := scanClass(rows)
+		if err != nil {
+			return nil, err
+		}
+		classes = append(classes, *c)
+	}
+	return classes, rows.Err()
+}
+
+// CreateClass creates a class taught by teacher
+func (s *ClassService) CreateClass(teacher *User, name string) (*Class, error) {
+	defer timeQuery(s.observer, "ClassService.CreateClass")()
+
+	var id int
+	err := s.db.QueryRow(
+		"INSERT INTO classes (name, teacher_id) VALUES (?, ?) RETURNING id", name, teacher.ID,
+	).Scan(&id)
+	if err != nil {
+		return nil, translateDBError(s.db, err)
+	}
+	return s.loadClass(id)
+}
+
+// GetClass returns a class with its students to its teacher or students
+func (s *ClassService) GetClass(user *User, id int) (*ClassDetails, error) {
+	defer timeQuery(s.observer, "ClassService.GetClass")()
+
+	class, err := s.requireMember(user, id)
+	if err != nil {
+		return nil, err
+	}
+	students, err := s.loadStudents(id)
+	if err != nil {
+		return nil, err
+	}
+	return &ClassDetails{Class: *class, Students: students}, nil
+}
+
+// DeleteClass deletes a class with its enrolments and assignments
+func (s *ClassService) DeleteClass(teacher *User, id int) error {
+	defer timeQuery(s.observer, "ClassService.DeleteClass")()
+
+	if _, err := s.requireTeacher(teacher, id); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("DELETE FROM classes WHERE id = ?", id)
+	return err
+}
+
+// AddStudent enrols a user in a class. Enrolling a student twice is a no-op.
+func (s *ClassService) AddStudent(teacher *User, classID, userID int) error {
+	defer timeQuery(s.observer, "ClassService.AddStudent")()
+
+	if _, err := s.requireTeacher(teacher, classID); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("INSERT OR IGNORE INTO class_students (class_id, user_id) VALUES (?, ?)", classID, userID)
+	if err != nil {
+		return translateDBError(s.db, err,
+			reference{"user_id", "users", userID, apperrors.CodeStudentNotFound},
+		)
+	}
+	return nil
+}
+
+// RemoveStudent removes a student from a class
+func (s *ClassService) RemoveStudent(teacher *User, classID, userID int) error {
+	defer timeQuery(s.observer, "ClassService.RemoveStudent")()
+
+	if _, err := s.requireTeacher(teacher, classID); err != nil {
+		return err
+	}
+	removed, err := affectsRow(s.db.Exec(
+		"DELETE FROM class_students WHERE class_id = ? AND user_id = ?", classID, userID,
+	))
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return apperrors.New(apperrors.CodeStudentNotEnrolled).
+			WithData(map[string]interface{}{"field": "user_id", "value": userID})
+	}
+	return nil
+}
+
+// GetAssignments lists the assignments of a class by due date, with the
+// progress of every student for the teacher and of the student themselves
+// otherwise
+func (s *ClassService) GetAssignments(user *User, classID int, now time.Time) ([]Assignment, error) {
+	defer timeQuery(s.observer, "ClassService.GetAssignments")()
+
+	class, err := s.requireMember(user, classID)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.loadAssignments("a.class_id = ?", classID)
+	if err != nil {
+		return nil, err
+	}
+
+	var studentID *int
+	if class.TeacherID != user.ID {
+		studentID = &user.ID
+	}
+	for i := range assignments {
+		if assignments[i].Progress, err = s.assignmentProgress(&assignments[i], studentID, now); err != nil {
+			return nil, err
+		}
+	}
+	return assignments, nil
+}
+
+// CreateAssignment assigns a group to a class
+func (s *ClassService) CreateAssignment(teacher *User, classID int, input AssignmentInput, now time.Time) (*Assignment, error) {
+	defer timeQuery(s.observer, "ClassService.CreateAssignment")()
+
+	if _, err := s.requireTeacher(teacher, classID); err != nil {
+		return nil, err
+	}
+	groupRef := reference{"group_id", "groups", input.GroupID, apperrors.CodeGroupNotFound}
+	if err := requireActive(s.db, groupRef); err != nil {
+		return nil, err
+	}
+
+	var id int
+	err := s.db.QueryRow(`
+		INSERT INTO assignments (class_id, group_id, study_activity_id, due_at, required_accuracy)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id`,
+		classID, input.GroupID, input.StudyActivityID,
+		input.DueAt.UTC().Format(timestampLayout), input.RequiredAccuracy,
+	).Scan(&id)
+	if err != nil {
+		return nil, translateDBError(s.db, err, groupRef)
+	}
+
+	assignments, err := s.loadAssignments("a.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	assignment := &assignments[0]
+	if assignment.Progress, err = s.assignmentProgress(assignment, nil, now); err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
+// DeleteAssignment deletes an assignment of a class
+func (s *ClassService) DeleteAssignment(teacher *User, classID, id int) error {
+	defer timeQuery(s.observer, "ClassService.DeleteAssignment")()
+
+	if _, err := s.requireTeacher(teacher, classID); err != nil {
+		return err
+	}
+	deleted, err := affectsRow(s.db.Exec("DELETE FROM assignments WHERE id = ? AND class_id = ?", id, classID))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return apperrors.New(apperrors.CodeAssignmentNotFound)
+	}
+	return nil
+}
+
+// GetStudentReports reports on each student of a class: how many assignments
+// are in each status, and their reviews in sessions on the assigned groups
+func (s *ClassService) GetStudentReports(teacher *User, classID int, now time.Time) ([]StudentReport, error) {
+	defer timeQuery(s.observer, "ClassService.GetStudentReports")()
+
+	if _, err := s.requireTeacher(teacher, classID); err != nil {
+		return nil, err
+	}
+	students, err := s.loadStudents(classID)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]StudentReport, len(students))
+	index := make(map[int]*StudentReport, len(students))
+	for i, st := range students {
+		reports[i] = StudentReport{UserID: st.ID, Name: st.Name, Assignments: map[string]int{
+			AssignmentCompleted: 0, AssignmentLate: 0, AssignmentOverdue: 0,
+			AssignmentInProgress: 0, AssignmentNotStarted: 0,
+		}}
+		index[st.ID] = &reports[i]
+	}
+
+	assignments, err := s.loadAssignments("a.class_id = ?", classID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range assignments {
+		progress, err := s.assignmentProgress(&assignments[i], nil, now)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range progress {
+			index[p.UserID].Assignments[p.Status]++
+		}
+	}
+
+	rows, err := s.db.Query(`
+		SELECT s.user_id, COUNT(*), SUM(r.correct), MAX(r.created_at)
+		FROM word_review_items r
+		JOIN study_sessions s ON s.id = r.study_session_id
+		JOIN class_students cs ON cs.user_id = s.user_id AND cs.class_id = ?
+		WHERE s.group_id IN (SELECT group_id FROM assignments WHERE class_id = ?)
+		GROUP BY s.user_id`, classID, classID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID, reviews, correct int
+		var last string
+		if err := rows.Scan(&userID, &reviews, &correct, &last); err != nil {
+			return nil, err
+		}
+		report := index[userID]
+		accuracy := roundAccuracy(float64(correct) / float64(reviews))
+		report.Reviews, report.Accuracy = reviews, &accuracy
+		if at, err := time.Parse(timestampLayout, last); err == nil {
+			report.LastStudiedAt = &at
+		}
+	}
+	return reports, rows.Err()
+}
+
+// GetWordReports reports on the words the students of a class reviewed in
+// sessions on its assigned groups, or on one assignment's group, hardest
+// first
+func (s *ClassService) GetWordReports(teacher *User, classID, assignmentID int) ([]WordReport, error) {
+	defer timeQuery(s.observer, "ClassService.GetWordReports")()
+
+	if _, err := s.requireTeacher(teacher, classID); err != nil {
+		return nil, err
+	}
+	groups := "SELECT group_id FROM assignments WHERE class_id = ?"
+	args := []interface{}{classID, classID}
+	if assignmentID != 0 {
+		var exists bool
+		err := s.db.QueryRow(
+			"SELECT EXISTS(SELECT 1 FROM assignments WHERE id = ? AND class_id = ?)", assignmentID, classID,
+		).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, apperrors.New(apperrors.CodeAssignmentNotFound)
+		}
+		groups += " AND id = ?"
+		args = append(args, assignmentID)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT w.id, w.term, w.translation, s.user_id, r.correct
+		FROM word_review_items r
+		JOIN study_sessions s ON s.id = r.study_session_id
+		JOIN class_students cs ON cs.user_id = s.user_id AND cs.class_id = ?
+		JOIN words w ON w.id = r.word_id AND w.deleted_at IS NULL
+		WHERE s.group_id IN (`+groups+`)
+		ORDER BY w.id, s.user_id, r.created_at DESC, r.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []WordReport{}
+	var correct int
+	var history []bool
+	lastUser := 0
+	finishStudent := func() {
+		if len(history) > 0 && s.mastery.Level(history).IsLearned() {
+			reports[len(reports)-1].Mastered++
+		}
+		history = history[:0]
+	}
+	finishWord := func() {
+		if len(reports) > 0 {
+			finishStudent()
+			w := &reports[len(reports)-1]
+			w.Accuracy = roundAccuracy(float64(correct) / float64(w.Reviews))
+		}
+		correct = 0
+	}
+	for rows.Next() {
+		var r WordReport
+		var userID int
+		var ok bool
+		if err := rows.Scan(&r.WordID, &r.Term, &r.Translation, &userID, &ok); err != nil {
+			return nil, err
+		}
+		if len(reports) == 0 || reports[len(reports)-1].WordID != r.WordID {
+			finishWord()
+			reports = append(reports, r)
+			lastUser = 0
+		}
+		w := &reports[len(reports)-1]
+		if userID != lastUser {
+			finishStudent()
+			w.Students++
+			lastUser = userID
+		}
+		w.Reviews++
+		history = append(history, ok)
+		if ok {
+			correct++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	finishWord()
+
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].Accuracy < reports[j].Accuracy })
+	return reports, nil
+}
+
+// requireMember returns a class that user teaches or attends
+func (s *ClassService) requireMember(user *User, id int) (*Class, error) {
+	class, err := s.loadClass(id)
+	if err != nil {
+		return nil, err
+	}
+	if class.TeacherID == user.ID {
+		return class, nil
+	}
+	var enrolled bool
+	err = s.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM class_students WHERE class_id = ? AND user_id = ?)", id, user.ID,
+	).Scan(&enrolled)
+	if err != nil {
+		return nil, err
+	}
+	if !enrolled {
+		return nil, apperrors.New(apperrors.CodeForbidden)
+	}
+	return class, nil
+}
+
+// requireTeacher returns a class that user teaches
+func (s *ClassService) requireTeacher(user *User, id int) (*Class, error) {
+	class, err := s.loadClass(id)
+	if err != nil {
+		return nil, err
+	}
+	if class.TeacherID != user.ID {
+		return nil, apperrors.New(apperrors.CodeForbidden)
+	}
+	return class, nil
+}
+
+// loadClass returns a class or a CLASS_NOT_FOUND error
+func (s *ClassService) loadClass(id int) (*Class, error) {
+	class, err := scanClass(s.db.QueryRow(`SELECT `+classColumns+` `+classFrom+` WHERE c.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeClassNotFound)
+	}
+	return class, err
+}
+
+// loadStudents lists the students of a class by name
+func (s *ClassService) loadStudents(classID int) ([]User, error) {
+	rows, err := s.db.Query(`
+		SELECT u.id, u.name, u.role, u.created_at
+		FROM class_students cs
+		JOIN users u ON u.id = cs.user_id
+		WHERE cs.class_id = ?
+		ORDER BY u.name`, classID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	students := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Role, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		students = append(students, u)
+	}
+	return students, rows.Err()
+}
+
+// loadAssignments loads the assignments matching a condition on assignments a
+func (s *ClassService) loadAssignments(condition string, args ...interface{}) ([]Assignment, error) {
+	rows, err := s.db.Query(`
+		SELECT a.id, a.class_id, a.group_id, g.name, a.study_activity_id, a.due_at, a.required_accuracy, a.created_at
+		FROM assignments a
+		JOIN groups g ON g.id = a.group_id
+		WHERE `+condition+`
+		ORDER BY a.due_at, a.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []Assignment{}
+	for rows.Next() {
+		var a Assignment
+		var activityID sql.NullInt64
+		err := rows.Scan(&a.ID, &a.ClassID, &a.GroupID, &a.GroupName, &activityID,
+			&a.DueAt, &a.RequiredAccuracy, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		a.StudyActivityID = nullIntPtr(activityID)
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
+}
+
+// assignmentProgress computes the status of each student of the class, or
+// of one student, on an assignment from their sessions on its group started
+// since it was made
+func (s *ClassService) assignmentProgress(a *Assignment, studentID *int, now time.Time) ([]AssignmentProgress, error) {
+	condition := "cs.class_id = ?"
+	args := []interface{}{a.ID, a.ClassID}
+	if studentID != nil {
+		condition += " AND cs.user_id = ?"
+		args = append(args, *studentID)
+	}
+	rows, err := s.db.Query(`
+		SELECT u.id, u.name, s.id, s.completed_at, COUNT(r.id), IFNULL(SUM(r.correct), 0)
+		FROM class_students cs
+		JOIN users u ON u.id = cs.user_id
+		JOIN assignments a ON a.id = ?
+		LEFT JOIN study_sessions s ON s.user_id = cs.user_id
+			AND s.group_id = a.group_id
+			AND (a.study_activity_id IS NULL OR s.study_activity_id = a.study_activity_id)
+			AND s.created_at >= a.created_at
+		LEFT JOIN word_review_items r ON r.study_session_id = s.id
+		WHERE `+condition+`
+		GROUP BY u.id, s.id
+		ORDER BY u.name, u.id, s.completed_at`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := []AssignmentProgress{}
+	for rows.Next() {
+		var userID, reviews, correct int
+		var name string
+		var sessionID sql.NullInt64
+		var completedAt sql.NullTime
+		if err := rows.Scan(&userID, &name, &sessionID, &completedAt, &reviews, &correct); err != nil {
+			return nil, err
+		}
+		if len(progress) == 0 || progress[len(progress)-1].UserID != userID {
+			progress = append(progress, AssignmentProgress{UserID: userID, Name: name})
+		}
+		p := &progress[len(progress)-1]
+		if !sessionID.Valid {
+			continue
+		}
+		p.Sessions++
+		if !completedAt.Valid || reviews == 0 {
+			continue
+		}
+		accuracy := roundAccuracy(float64(correct) / float64(reviews))
+		if p.BestAccuracy == nil || accuracy > *p.BestAccuracy {
+			p.BestAccuracy = &accuracy
+		}
+		if p.CompletedAt == nil && float64(correct) >= a.RequiredAccuracy*float64(reviews) {
+			at := completedAt.Time
+			p.CompletedAt = &at
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range progress {
+		p := &progress[i]
+		switch {
+		case p.CompletedAt != nil && !p.CompletedAt.After(a.DueAt):
+			p.Status = AssignmentCompleted
+		case p.CompletedAt != nil:
+			p.Status = AssignmentLate
+		case now.After(a.DueAt):
+			p.Status = AssignmentOverdue
+		case p.Sessions > 0:
+			p.Status = AssignmentInProgress
+		default:
+			p.Status = AssignmentNotStarted
+		}
+	}
+	return progress, nil
+}
+
+// roundAccuracy rounds a share of correct reviews to three decimals
+func roundAccuracy(accuracy float64) float64 {
+	return math.Round(accuracy*1000) / 1000
+}
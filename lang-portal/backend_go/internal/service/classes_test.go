@@ -0,0 +1,249 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+)
+
+// setupClass creates a class taught by Cornelia with three students and one
+// assignment on Basics due at noon on 18 October, made on the 16th. Marcus
+// completes it on time, Julia fails once and passes late, and Tullia only
+// studied the group before it was assigned.
+func setupClass(t *testing.T) (*ClassService, *User, *Assignment) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { db.Close() })
+	_, err := db.Exec(`
+		INSERT INTO users (id, name) VALUES (1, 'Cornelia'), (2, 'Marcus'), (3, 'Julia'), (4, 'Tullia'), (5, 'Gaius');
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES
+			(1, 'la', 'amare', 'to love', '{}'),
+			(2, 'la', 'videre', 'to see', '{}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Basics'), (2, 'la', 'Verbs');
+	`)
+	assert.NoError(t, err)
+
+	classes := NewClassService(db)
+	classes.SetMasteryConfig(MasteryConfig{ReviewingStreak: 1, MasteredStreak: 2, MasteredAccuracy: 0.8, Window: 10})
+	teacher := &User{ID: 1, Name: "Cornelia"}
+	class, err := classes.CreateClass(teacher, "Latin I")
+	assert.NoError(t, err)
+	for _, id := range []int{2, 3, 4} {
+		assert.NoError(t, classes.AddStudent(teacher, class.ID, id))
+	}
+
+	due := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	assignment, err := classes.CreateAssignment(teacher, class.ID, AssignmentInput{
+		GroupID: 1, DueAt: due, RequiredAccuracy: 0.75,
+	}, due.Add(-48*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, "Basics", assignment.GroupName)
+	assert.Len(t, assignment.Progress, 3)
+
+	_, err = db.Exec(`
+		UPDATE assignments SET created_at = '2026-10-16 00:00:00';
+		INSERT INTO study_sessions (id, group_id, user_id, created_at, completed_at) VALUES
+			(1, 1, 2, '2026-10-17 08:00:00', '2026-10-17 08:10:00'),
+			(2, 1, 3, '2026-10-17 09:00:00', '2026-10-17 09:10:00'),
+			(3, 1, 3, '2026-10-19 08:00:00', '2026-10-19 08:10:00'),
+			(4, 1, 4, '2026-10-15 08:00:00', '2026-10-15 08:10:00'),
+			(5, 2, 2, '2026-10-17 10:00:00', '2026-10-17 10:10:00');
+		INSERT INTO word_review_items (word_id, study_session_id, correct, created_at) VALUES
+			(1, 1, 1, '2026-10-17 08:01:00'),
+			(2, 1, 1, '2026-10-17 08:02:00'),
+			(1, 1, 1, '2026-10-17 08:03:00'),
+			(2, 1, 0, '2026-10-17 08:04:00'),
+			(1, 2, 1, '2026-10-17 09:01:00'),
+			(2, 2, 0, '2026-10-17 09:02:00'),
+			(1, 3, 1, '2026-10-19 08:01:00'),
+			(2, 3, 1, '2026-10-19 08:02:00'),
+			(1, 4, 1, '2026-10-15 08:01:00'),
+			(2, 5, 0, '2026-10-17 10:01:00');
+	`)
+	assert.NoError(t, err)
+	return classes, teacher, assignment
+}
+
+func progressStatuses(progress []AssignmentProgress) map[string]string {
+	statuses := map[string]string{}
+	for _, p := range progress {
+		statuses[p.Name] = p.Status
+	}
+	return statuses
+}
+
+func TestClassAccess(t *testing.T) {
+	classes, teacher, assignment := setupClass(t)
+	student := &User{ID: 2, Name: "Marcus"}
+	outsider := &User{ID: 5, Name: "Gaius"}
+	classID := assignment.ClassID
+
+	details, err := classes.GetClass(student, classID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Cornelia", details.TeacherName)
+	assert.Len(t, details.Students, 3)
+
+	list, err := classes.GetClasses(outsider)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	_, err = classes.GetClass(outsider, classID)
+	assertAppError(t, err, apperrors.CodeForbidden, http.StatusForbidden)
+	_, err = classes.GetStudentReports(student, classID, time.Now())
+	assertAppError(t, err, apperrors.CodeForbidden, http.StatusForbidden)
+	err = classes.AddStudent(student, classID, 5)
+	assertAppError(t, err, apperrors.CodeForbidden, http.StatusForbidden)
+	_, err = classes.GetClass(teacher, 99)
+	assertAppError(t, err, apperrors.CodeClassNotFound, http.StatusNotFound)
+
+	_, err = classes.CreateClass(teacher, "Latin I")
+	assertAppError(t, err, apperrors.CodeClassNameTaken, http.StatusConflict)
+	err = classes.AddStudent(teacher, classID, 99)
+	assertAppError(t, err, apperrors.CodeStudentNotFound, http.StatusNotFound)
+	err = classes.RemoveStudent(teacher, classID, 5)
+	assertAppError(t, err, apperrors.CodeStudentNotEnrolled, http.StatusNotFound)
+	err = classes.DeleteAssignment(teacher, classID, 99)
+	assertAppError(t, err, apperrors.CodeAssignmentNotFound, http.StatusNotFound)
+}
+
+func TestAssignmentProgress(t *testing.T) {
+	classes, teacher, assignment := setupClass(t)
+	classID := assignment.ClassID
+
+	// Before the due date Julia's second session has not ended yet
+	_, err := classes.db.Exec("UPDATE study_sessions SET completed_at = NULL WHERE id = 3")
+	assert.NoError(t, err)
+	before := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	assignments, err := classes.GetAssignments(teacher, classID, before)
+	assert.NoError(t, err)
+	assert.Len(t, assignments, 1)
+	assert.Equal(t, map[string]string{
+		"Marcus": AssignmentCompleted,
+		"Julia":  AssignmentInProgress,
+		"Tullia": AssignmentNotStarted,
+	}, progressStatuses(assignments[0].Progress))
+
+	_, err = classes.db.Exec("UPDATE study_sessions SET completed_at = '2026-10-19 08:10:00' WHERE id = 3")
+	assert.NoError(t, err)
+	after := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	assignments, err = classes.GetAssignments(teacher, classID, after)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Marcus": AssignmentCompleted,
+		"Julia":  AssignmentLate,
+		"Tullia": AssignmentOverdue,
+	}, progressStatuses(assignments[0].Progress))
+
+	// Students only see their own progress
+	assignments, err = classes.GetAssignments(&User{ID: 3, Name: "Julia"}, classID, after)
+	assert.NoError(t, err)
+	julia := assignments[0].Progress
+	assert.Len(t, julia, 1)
+	assert.Equal(t, 2, julia[0].Sessions)
+	assert.Equal(t, 1.0, *julia[0].BestAccuracy)
+	assert.Equal(t, time.Date(2026, 10, 19, 8, 10, 0, 0, time.UTC), julia[0].CompletedAt.UTC())
+}
+
+func TestClassReports(t *testing.T) {
+	classes, teacher, assignment := setupClass(t)
+	classID := assignment.ClassID
+
+	students, err := classes.GetStudentReports(teacher, classID, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Len(t, students, 3)
+	julia, marcus, tullia := students[0], students[1], students[2]
+	assert.Equal(t, 1, julia.Assignments[AssignmentLate])
+	assert.Equal(t, 1, marcus.Assignments[AssignmentCompleted])
+	assert.Equal(t, 1, tullia.Assignments[AssignmentOverdue])
+	assert.Equal(t, 0, tullia.Assignments[AssignmentCompleted])
+	// Sessions on groups outside the class's assignments are left out
+	assert.Equal(t, 4, marcus.Reviews)
+	assert.Equal(t, 0.75, *marcus.Accuracy)
+	assert.Equal(t, 1, tullia.Reviews)
+
+	words, err := classes.GetWordReports(teacher, classID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []WordReport{
+		{WordID: 2, Term: "videre", Translation: "to see", Reviews: 4, Accuracy: 0.5, Students: 2, Mastered: 0},
+		{WordID: 1, Term: "amare", Translation: "to love", Reviews: 5, Accuracy: 1, Students: 3, Mastered: 2},
+	}, words)
+
+	_, err = classes.GetWordReports(teacher, classID, assignment.ID+1)
+	assertAppError(t, err, apperrors.CodeAssignmentNotFound, http.StatusNotFound)
+}
+
+func TestClassLeaderboard(t *testing.T) {
+	classes, _, assignment := setupClass(t)
+	rules, err := ParseAchievementRules([]byte(testAchievements))
+	assert.NoError(t, err)
+	achievements := NewAchievementService(classes.db, rules)
+
+	entries, err := achievements.GetClassLeaderboard(assignment.ClassID, 10)
+	assert.NoError(t, err)
+	ranks := map[string][2]int{}
+	for _, e := range entries {
+		ranks[e.Name] = [2]int{e.Rank, e.XP}
+	}
+	// The teacher is not ranked
+	assert.Equal(t, map[string][2]int{
+		"Marcus": {1, 3*10 + 2*2},
+		"Julia":  {2, 3*10 + 1*2},
+		"Tullia": {3, 10},
+	}, ranks)
+}
+
+func TestActivityAssignmentCompletedThroughStudySessions(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	_, err := db.Exec(`
+		INSERT INTO users (id, name) VALUES (1, 'Cornelia'), (2, 'Marcus');
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES (1, 'la', 'amare', 'to love', '{}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Basics');
+	`)
+	assert.NoError(t, err)
+	teacher := &User{ID: 1, Name: "Cornelia"}
+	marcus := &User{ID: 2, Name: "Marcus", Role: RoleLearner}
+	classes := NewClassService(db)
+	study := NewStudyService(db)
+
+	class, err := classes.CreateClass(teacher, "Latin I")
+	assert.NoError(t, err)
+	assert.NoError(t, classes.AddStudent(teacher, class.ID, marcus.ID))
+	now := time.Now()
+	activity := 2
+	assignment, err := classes.CreateAssignment(teacher, class.ID, AssignmentInput{
+		GroupID: 1, StudyActivityID: &activity, DueAt: now.Add(24 * time.Hour), RequiredAccuracy: 1,
+	}, now)
+	assert.NoError(t, err)
+
+	status := func() string {
+		assignments, err := classes.GetAssignments(marcus, class.ID, time.Now())
+		assert.NoError(t, err)
+		return assignments[0].Progress[0].Status
+	}
+	studyGroup := func(activityID *int) *StudySession {
+		session, err := study.CreateStudySession(marcus, assignment.GroupID, activityID)
+		assert.NoError(t, err)
+		return session
+	}
+
+	// Sessions in another activity do not count
+	other := 1
+	session := studyGroup(&other)
+	assert.Equal(t, other, *session.StudyActivityID)
+	_, err = study.AddWordReview(marcus, session.ID, 1, true)
+	assert.NoError(t, err)
+	_, err = study.CompleteStudySession(marcus, session.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, AssignmentNotStarted, status())
+
+	session = studyGroup(&activity)
+	assert.Equal(t, AssignmentInProgress, status())
+	_, err = study.AddWordReview(marcus, session.ID, 1, true)
+	assert.NoError(t, err)
+	_, err = study.CompleteStudySession(marcus, session.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, AssignmentCompleted, status())
+}
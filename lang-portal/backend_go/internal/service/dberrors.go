@@ -0,0 +1,117 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+	apperrors "lang-portal/internal/errors"
+)
+
+// constraintError describes how a violated constraint is reported to clients
+type constraintError struct {
+	code  string // catalog error code
+	field string // client-facing name of the offending field
+}
+
+// uniqueConstraints maps the columns reported by a UNIQUE failure, as
+// "table.column[, table.column]", onto API errors
+var uniqueConstraints = map[string]constraintError{
+	"groups.language_code, groups.name":           {apperrors.CodeGroupNameTaken, "name"},
+	"words.language_code, words.term":             {apperrors.CodeWordAlreadyExists, "term"},
+	"languages.code":                              {apperrors.CodeLanguageAlreadyExists, "code"},
+	"words_groups.word_id, words_groups.group_id": {apperrors.CodeWordAlreadyInGroup, "word_id"},
+	"users.name":                       {apperrors.CodeUserNameTaken, "name"},
+	"audit_log.reverts_id":             {apperrors.CodeAuditAlreadyReverted, "id"},
+	"goals.user_id, goals.metric":      {apperrors.CodeGoalAlreadyExists, "metric"},
+	"classes.teacher_id, classes.name": {apperrors.CodeClassNameTaken, "name"},
+}
+
+// reference is a foreign key written by a statement. When SQLite reports a
+// FOREIGN KEY failure, which does not name the column, the references are
+// checked in order to find the missing row.
+type reference struct {
+	field string // client-facing field name
+	table string // referenced table
+	id    int    // referenced id
+	code  string // catalog code reported when the row does not exist
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx, so that helpers can
+// run inside or outside a transaction
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// translateDBError converts SQLite constraint failures into API errors with
+// the offending field in Data. Other errors are returned unchanged. Inside a
+// transaction, pass the transaction so the reference lookups can see it.
+func translateDBError(db queryer, err error, refs ...reference) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrConstraint {
+		return err
+	}
+
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		columns := constraintSubject(sqliteErr)
+		if known, ok := uniqueConstraints[columns]; ok {
+			return apperrors.New(known.code).
+				WithData(map[string]string{"field": known.field}).
+				WithInternal(err)
+		}
+		return apperrors.New(apperrors.CodeResourceConflict).
+			WithData(map[string]string{"field": columnName(columns)}).
+			WithInternal(err)
+
+	case sqlite3.ErrConstraintForeignKey:
+		for _, ref := range refs {
+			var exists bool
+			query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE id = ?)", ref.table)
+			if lookupErr := db.QueryRow(query, ref.id).Scan(&exists); lookupErr != nil {
+				return err
+			}
+			if !exists {
+				return apperrors.New(ref.code).
+					WithData(map[string]interface{}{"field": ref.field, "value": ref.id}).
+					WithInternal(err)
+			}
+		}
+		return apperrors.New(apperrors.CodeConstraintViolation).
+			WithData(map[string]string{"constraint": "foreign_key"}).
+			WithInternal(err)
+
+	case sqlite3.ErrConstraintNotNull:
+		return apperrors.New(apperrors.CodeConstraintViolation).
+			WithData(map[string]string{"field": columnName(constraintSubject(sqliteErr)), "constraint": "not_null"}).
+			WithInternal(err)
+
+	default:
+		return apperrors.New(apperrors.CodeConstraintViolation).
+			WithData(map[string]string{"constraint": constraintSubject(sqliteErr)}).
+			WithInternal(err)
+	}
+}
+
+// constraintSubject extracts what follows "constraint failed: " in a SQLite
+// message, e.g. "groups.name" or "json_valid(parts)"
+func constraintSubject(err sqlite3.Error) string {
+	_, subject, found := strings.Cut(err.Error(), "constraint failed: ")
+	if !found {
+		return ""
+	}
+	return strings.TrimSpace(subject)
+}
+
+// columnName returns the first column of a "table.column, ..." list
+func columnName(columns string) string {
+	first := strings.Split(columns, ",")[0]
+	if _, column, ok := strings.Cut(first, "."); ok {
+		return column
+	}
+	return first
+}
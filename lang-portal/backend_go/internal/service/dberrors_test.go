@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+)
+
+// assertAppError checks that err is an AppError with the given code and status
+func assertAppError(t *testing.T, err error, code string, status int) *apperrors.AppError {
+	t.Helper()
+	var appErr *apperrors.AppError
+	if !assert.True(t, errors.As(err, &appErr), "expected an AppError, got %v", err) {
+		return nil
+	}
+	assert.Equal(t, code, appErr.Code)
+	assert.Equal(t, status, appErr.Status)
+	return appErr
+}
+
+func TestCreateGroupDuplicateName(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service := NewGroupService(db)
+	_, err := service.CreateGroup(nil, "Verbs", "", nil)
+	assert.NoError(t, err)
+
+	_, err = service.CreateGroup(nil, "Verbs", "", nil)
+	appErr := assertAppError(t, err, apperrors.CodeGroupNameTaken, http.StatusConflict)
+	if appErr != nil {
+		assert.Equal(t, map[string]string{"field": "name"}, appErr.Data)
+	}
+}
+
+func TestAddWordToGroupConstraints(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts)
+		VALUES (1, 'la', 'amare', 'to love', '{"type":"verb"}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Test Group');
+	`)
+	assert.NoError(t, err)
+
+	service := NewGroupService(db)
+	assert.NoError(t, service.AddWordToGroup(System, 1, 1))
+
+	err = service.AddWordToGroup(System, 1, 1)
+	assertAppError(t, err, apperrors.CodeWordAlreadyInGroup, http.StatusConflict)
+
+	err = service.AddWordToGroup(System, 99, 1)
+	appErr := assertAppError(t, err, apperrors.CodeWordNotFound, http.StatusNotFound)
+	if appErr != nil {
+		assert.Equal(t, map[string]interface{}{"field": "word_id", "value": 99}, appErr.Data)
+	}
+
+	err = service.AddWordToGroup(System, 1, 42)
+	assertAppError(t, err, apperrors.CodeGroupNotFound, http.StatusNotFound)
+}
+
+func TestStudyForeignKeys(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts)
+		VALUES (1, 'la', 'amare', 'to love', '{"type":"verb"}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Test Group');
+		INSERT INTO study_sessions (id, group_id) VALUES (1, 1);
+	`)
+	assert.NoError(t, err)
+
+	service := NewStudyService(db)
+
+	_, err = service.CreateStudySession(nil, 42, nil)
+	assertAppError(t, err, apperrors.CodeGroupNotFound, http.StatusNotFound)
+
+	_, err = service.AddWordReview(System, 7, 1, true)
+	assertAppError(t, err, apperrors.CodeSessionNotFound, http.StatusNotFound)
+
+	_, err = service.AddWordReview(System, 1, 99, false)
+	assertAppError(t, err, apperrors.CodeWordNotFound, http.StatusNotFound)
+}
+
+func TestTranslateDBErrorPassesThroughOtherErrors(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("SELECT * FROM missing_table")
+	assert.Equal(t, err, translateDBError(db, err))
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published on the EventBus
+const (
+	EventSessionCreated   = "session.created"
+	EventReviewAdded      = "review.added"
+	EventSessionCompleted = "session.completed"
+	EventWordCreated      = "word.created"
+	EventWordUpdated      = "word.updated"
+	EventWordDeleted      = "word.deleted"
+
+	EventAchievementEarned = "achievement.earned"
+)
+
+// EventTypes lists every event type, in the order they are documented
+var EventTypes = []string{
+	EventSessionCreated, EventReviewAdded, EventSessionCompleted,
+	EventWordCreated, EventWordUpdated, EventWordDeleted,
+	EventAchievementEarned,
+}
+
+const (
+	// eventHistory is how many recent events are kept for subscribers that
+	// reconnect
+	eventHistory = 256
+	// subscriberBuffer is how many events a subscriber may fall behind before
+	// it is dropped
+	subscriberBuffer = 64
+)
+
+// Event is a change published on the EventBus. Data holds the created or
+// updated resource, such as a StudySession, WordReviewItem or Word. UserID is
+// the learner of the session, or for vocabulary events the user who made the
+// change.
+type Event struct {
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	UserID    *int        `json:"user_id,omitempty"`
+	SessionID int         `json:"session_id,omitempty"`
+	Time      time.Time   `json:"time"`
+	Data      interface{} `json:"data"`
+}
+
+// EventFilter selects the events a subscriber receives. Zero values match
+// everything.
+type EventFilter struct {
+	UserID    int
+	SessionID int
+}
+
+func (f EventFilter) matches(e Event) bool {
+	if f.UserID != 0 && (e.UserID == nil || *e.UserID != f.UserID) {
+		return false
+	}
+	return f.SessionID == 0 || e.SessionID == f.SessionID
+}
+
+// EventBus fans out events published by the services to subscribers in the
+// same process. Publishing never blocks: a subscriber that falls too far
+// behind is dropped and its channel closed.
+type EventBus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	recent      []Event
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events matching its filter on Events until it is
+// closed
+type Subscription struct {
+	Events <-chan Event
+
+	events chan Event
+	filter EventFilter
+	bus    *EventBus
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Publish assigns the event its id and time and delivers it to every
+// matching subscriber. It is a no-op on a nil bus.
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e.ID = b.lastID
+	e.Time = time.Now().UTC()
+	b.recent = append(b.recent, e)
+	if len(b.recent) > eventHistory {
+		b.recent = b.recent[len(b.recent)-eventHistory:]
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter.matches(e) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			b.drop(sub)
+		}
+	}
+}
+
+// Subscribe starts receiving the events matching filter. Recent events with
+// an id above after are replayed first, so that a client reconnecting with
+// the last id it saw misses nothing.
+func (b *EventBus) Subscribe(filter EventFilter, after uint64) *Subscription {
+	events := make(chan Event, subscriberBuffer)
+	sub := &Subscription{Events: events, events: events, filter: filter, bus: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if after > 0 {
+		for _, e := range b.recent {
+			if e.ID > after && filter.matches(e) && len(events) < subscriberBuffer {
+				events <- e
+			}
+		}
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Resume subscribes like Subscribe, but returns every recent event matching
+// filter with an id above after instead of replaying them on the channel,
+// however many there are. It also returns how many events above after are
+// older than the history and so can no longer be replayed.
+func (b *EventBus) Resume(filter EventFilter, after uint64) (*Subscription, []Event, uint64) {
+	events := make(chan Event, subscriberBuffer)
+	sub := &Subscription{Events: events, events: events, filter: filter, bus: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var missed []Event
+	for _, e := range b.recent {
+		if e.ID > after && filter.matches(e) {
+			missed = append(missed, e)
+		}
+	}
+	var lost uint64
+	if len(b.recent) > 0 && b.recent[0].ID > after+1 {
+		lost = b.recent[0].ID - after - 1
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub, missed, lost
+}
+
+// Close stops the subscription and closes its channel
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.drop(s)
+}
+
+// drop removes a subscriber; the caller holds the lock
+func (b *EventBus) drop(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+)
+
+// nextEvent returns the next buffered event of a subscription
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case e := <-sub.Events:
+		return e
+	default:
+		t.Fatal("no event published")
+		return Event{}
+	}
+}
+
+func TestEventBusFiltersAndReplays(t *testing.T) {
+	bus := NewEventBus()
+	alice, bob := 1, 2
+
+	all := bus.Subscribe(EventFilter{}, 0)
+	defer all.Close()
+	mine := bus.Subscribe(EventFilter{UserID: alice}, 0)
+	defer mine.Close()
+
+	bus.Publish(Event{Type: EventSessionCreated, UserID: &alice, SessionID: 1})
+	bus.Publish(Event{Type: EventSessionCreated, UserID: &bob, SessionID: 2})
+	bus.Publish(Event{Type: EventReviewAdded, SessionID: 3})
+
+	assert.Len(t, all.Events, 3)
+	if assert.Len(t, mine.Events, 1) {
+		assert.Equal(t, 1, nextEvent(t, mine).SessionID)
+	}
+
+	// Reconnecting after the first event replays the ones that followed
+	replay := bus.Subscribe(EventFilter{SessionID: 2}, 1)
+	defer replay.Close()
+	if assert.Len(t, replay.Events, 1) {
+		assert.Equal(t, uint64(2), nextEvent(t, replay).ID)
+	}
+}
+
+func TestEventBusDropsSlowSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	slow := bus.Subscribe(EventFilter{}, 0)
+	for i := 0; i <= subscriberBuffer; i++ {
+		bus.Publish(Event{Type: EventReviewAdded})
+	}
+	received := 0
+	for range slow.Events {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received, "the channel is closed once the buffer overflows")
+	slow.Close()
+}
+
+func TestEventBusResumeReturnsMissedEvents(t *testing.T) {
+	bus := NewEventBus()
+	for i := 0; i < eventHistory+10; i++ {
+		bus.Publish(Event{Type: EventReviewAdded})
+	}
+
+	sub, missed, lost := bus.Resume(EventFilter{}, 5)
+	defer sub.Close()
+	assert.Len(t, missed, eventHistory, "more than a subscriber buffer is returned")
+	assert.Equal(t, uint64(11), missed[0].ID)
+	assert.Equal(t, uint64(5), lost, "events 6 to 10 are no longer in the history")
+	assert.Empty(t, sub.Events)
+
+	bus.Publish(Event{Type: EventReviewAdded})
+	assert.Equal(t, uint64(eventHistory+11), nextEvent(t, sub).ID)
+
+	current, missed, lost := bus.Resume(EventFilter{}, uint64(eventHistory+11))
+	defer current.Close()
+	assert.Empty(t, missed)
+	assert.Zero(t, lost)
+}
+
+func TestStudyServicePublishesEvents(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES (1, 'la', 'amare', 'to love', '{}');
+		INSERT INTO word_senses (word_id, gloss, priority) VALUES (1, 'to love', 0);
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Verbs');
+	`)
+	assert.NoError(t, err)
+	learner, err := NewUserService(db).CreateUser("Marcus", RoleLearner)
+	assert.NoError(t, err)
+
+	bus := NewEventBus()
+	sub := bus.Subscribe(EventFilter{UserID: learner.ID}, 0)
+	defer sub.Close()
+	study := NewStudyService(db)
+	study.SetEventBus(bus)
+
+	session, err := study.CreateStudySession(learner, 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, learner.ID, *session.UserID)
+	_, err = study.AddAnswerReview(learner, session.ID, 1, "To love!")
+	assert.NoError(t, err)
+	completed, err := study.CompleteStudySession(learner, session.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, completed.CompletedAt)
+
+	created := nextEvent(t, sub)
+	assert.Equal(t, EventSessionCreated, created.Type)
+	review := nextEvent(t, sub)
+	assert.Equal(t, EventReviewAdded, review.Type)
+	assert.Equal(t, session.ID, review.SessionID)
+	assert.Equal(t, []string{"to love"}, review.Data.(*WordReviewItem).Accepted)
+	assert.Equal(t, EventSessionCompleted, nextEvent(t, sub).Type)
+
+	_, err = study.AddWordReview(learner, session.ID, 1, true)
+	assertAppError(t, err, apperrors.CodeSessionCompleted, http.StatusConflict)
+	_, err = study.CompleteStudySession(learner, session.ID)
+	assertAppError(t, err, apperrors.CodeSessionCompleted, http.StatusConflict)
+	_, err = study.CompleteStudySession(learner, 99)
+	assertAppError(t, err, apperrors.CodeSessionNotFound, http.StatusNotFound)
+}
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/mail"
+	"net/url"
+	"time"
+
+	apperrors "lang-portal/internal/errors"
+	"lang-portal/internal/notify"
+)
+
+// Goal metrics. Reviews and minutes are counted per day, new words, words
+// reviewed for the first time, per week starting on Monday. Days are UTC.
+const (
+	GoalReviewsPerDay   = "reviews_per_day"
+	GoalNewWordsPerWeek = "new_words_per_week"
+	GoalMinutesPerDay   = "minutes_per_day"
+)
+
+// Notification channels for goal reminders
+const (
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+)
+
+// Notification statuses
+const (
+	NotificationSent   = "sent"
+	NotificationFailed = "failed"
+)
+
+// defaultRemindAt is the time of day, UTC, from which a goal without a
+// reminder time counts as at risk
+const defaultRemindAt = "18:00"
+
+const timestampLayout = "2006-01-02 15:04:05"
+
+// GoalService tracks learner goals and reminds learners of those at risk
+type GoalService struct {
+	instrumented
+
+	db       *sql.DB
+	channels map[string]notify.Channel
+}
+
+// Goal is a learner's target for a metric, with their progress in the
+// current period
+type Goal struct {
+	ID        int          `json:"id"`
+	UserID    int          `json:"user_id"`
+	Metric    string       `json:"metric"`
+	Target    int          `json:"target"`
+	Reminder  *Reminder    `json:"reminder,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	Progress  GoalProgress `json:"progress"`
+}
+
+// Reminder sends a notification to Address through Channel when the goal is
+// at risk. At is a time of day in UTC, HH:MM, defaulting to 18:00. Webhook
+// reminders make the server POST to any address, so like webhooks they are
+// kept to users with the teach permission.
+type Reminder struct {
+	Channel string `json:"channel"`
+	Address string `json:"address"`
+	At      string `json:"at"`
+}
+
+// GoalProgress is the value of a goal's metric in the period containing the
+// time it was computed. A goal is at risk when it is not met on the last day
+// of the period after its reminder time.
+type GoalProgress struct {
+	Value       int       `json:"value"`
+	Percent     float64   `json:"percent"`
+	Met         bool      `json:"met"`
+	AtRisk      bool      `json:"at_risk"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+}
+
+// GoalInput holds the fields of a new goal
+type GoalInput struct {
+	Metric   string
+	Target   int
+	Reminder *Reminder
+}
+
+// Notification is a reminder sent for a goal. Period is the first day of the
+// goal's period it was sent for.
+type Notification struct {
+	ID        int       `json:"id"`
+	GoalID    int       `json:"goal_id"`
+	Period    string    `json:"period"`
+	Channel   string    `json:"channel"`
+	Address   string    `json:"address"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	Status    string    `json:"status"`
+	Error     *string   `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewGoalService(db *sql.DB) *GoalService {
+	return &GoalService{db: db, instrumented: instrumented{observer: nopObserver{}}, channels: map[string]notify.Channel{}}
+}
+
+// SetChannel registers the channel delivering reminders of the given kind,
+// ChannelWebhook or ChannelEmail. Reminders through a channel that is not
+// registered are recorded as failed.
+func (s *GoalService) SetChannel(name string, channel notify.Channel) {
+	s.channels[name] = channel
+}
+
+// GetGoals lists the goals of learner with their progress at now
+func (s *GoalService) GetGoals(learner *User, now time.Time) ([]Goal, error) {
+	defer timeQuery(s.observer, "GoalService.GetGoals")()
+
+	goals, err := s.loadGoals("user_id = ?", learner.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range goals {
+		if goals[i].Progress, err = s.progress(&goals[i], now); err != nil {
+			return nil, err
+		}
+	}
+	return goals, nil
+}
+
+// CreateGoal sets a goal for learner. A learner has at most one goal per
+// metric.
+func (s *GoalService) CreateGoal(learner *User, input GoalInput, now time.Time) (*Goal, error) {
+	defer timeQuery(s.observer, "GoalService.CreateGoal")()
+
+	var channel, address, at interface{}
+	if r := input.Reminder; r != nil {
+		if r.At == "" {
+			r.At = defaultRemindAt
+		}
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+		if r.Channel == ChannelWebhook && !learner.Can(PermissionTeach) {
+			return nil, apperrors.New(apperrors.CodeForbidden).WithData(map[string]string{
+				"permission": string(PermissionTeach),
+				"role":       learner.Role,
+			})
+		}
+		channel, address, at = r.Channel, r.Address, r.At
+	}
+
+	goal := Goal{UserID: learner.ID, Metric: input.Metric, Target: input.Target, Reminder: input.Reminder}
+	err := s.db.QueryRow(`
+		INSERT INTO goals (user_id, metric, target, reminder_channel, reminder_address, remind_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, created_at`,
+		learner.ID, input.Metric, input.Target, channel, address, at,
+	).Scan(&goal.ID, &goal.CreatedAt)
+	if err != nil {
+		return nil, translateDBError(s.db, err)
+	}
+	if goal.Progress, err = s.progress(&goal, now); err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+// DeleteGoal deletes a goal of learner with its notifications. It reports
+// false if learner has no such goal.
+func (s *GoalService) DeleteGoal(learner *User, id int) (bool, error) {
+	defer timeQuery(s.observer, "GoalService.DeleteGoal")()
+
+	return affectsRow(s.db.Exec("DELETE FROM goals WHERE id = ? AND user_id = ?", id, learner.ID))
+}
+
+// GetNotifications lists the latest reminders sent to learner, newest first
+func (s *GoalService) GetNotifications(learner *User, limit int) ([]Notification, error) {
+	defer timeQuery(s.observer, "GoalService.GetNotifications")()
+
+	rows, err := s.db.Query(`
+		SELECT id, goal_id, period, channel, address, subject, body, status, error, created_at
+		FROM notifications
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?`, learner.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var n Notification
+		var errorText sql.NullString
+		err := rows.Scan(&n.ID, &n.GoalID, &n.Period, &n.Channel, &n.Address,
+			&n.Subject, &n.Body, &n.Status, &errorText, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		n.Error = nullStringPtr(errorText)
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// CheckReminders notifies the learners whose goals with a reminder are at
+// risk at now, once per goal and period, and returns the number of reminders
+// sent or attempted. A failed reminder is recorded and not retried.
+func (s *GoalService) CheckReminders(ctx context.Context, now time.Time) (int, error) {
+	defer timeQuery(s.observer, "GoalService.CheckReminders")()
+
+	goals, err := s.loadGoals("reminder_channel IS NOT NULL")
+	if err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	for i := range goals {
+		goal := &goals[i]
+		if goal.Progress, err = s.progress(goal, now); err != nil {
+			return attempted, err
+		}
+		if !goal.Progress.AtRisk {
+			continue
+		}
+
+		msg := reminderMessage(goal)
+		period := goal.Progress.PeriodStart.Format(time.DateOnly)
+		result, err := s.db.Exec(`
+			INSERT OR IGNORE INTO notifications (goal_id, user_id, period, channel, address, subject, body, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			goal.ID, goal.UserID, period, goal.Reminder.Channel, msg.To, msg.Subject, msg.Body, NotificationSent,
+		)
+		if err != nil {
+			return attempted, err
+		}
+		if claimed, err := result.RowsAffected(); err != nil || claimed == 0 {
+			continue
+		}
+		notificationID, err := result.LastInsertId()
+		if err != nil {
+			return attempted, err
+		}
+
+		attempted++
+		sendErr := fmt.Errorf("channel %q is not configured", goal.Reminder.Channel)
+		if channel, ok := s.channels[goal.Reminder.Channel]; ok {
+			sendErr = s.send(ctx, goal, channel, msg)
+		}
+		if sendErr != nil {
+			_, err := s.db.Exec(
+				"UPDATE notifications SET status = ?, error = ? WHERE id = ?",
+				NotificationFailed, sendErr.Error(), notificationID,
+			)
+			if err != nil {
+				return attempted, err
+			}
+		}
+	}
+	return attempted, nil
+}
+
+// send delivers a reminder through channel. Webhook reminders are refused
+// once their learner no longer has the teach permission.
+func (s *GoalService) send(ctx context.Context, goal *Goal, channel notify.Channel, msg notify.Message) error {
+	if goal.Reminder.Channel == ChannelWebhook {
+		var learner User
+		if err := s.db.QueryRow("SELECT role FROM users WHERE id = ?", goal.UserID).Scan(&learner.Role); err != nil {
+			return err
+		}
+		if !learner.Can(PermissionTeach) {
+			return fmt.Errorf("webhook reminders need the %s permission", PermissionTeach)
+		}
+	}
+	return channel.Send(ctx, msg)
+}
+
+// RunReminders checks goal reminders every interval until ctx is cancelled
+func (s *GoalService) RunReminders(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			sent, err := s.CheckReminders(ctx, now)
+			if err != nil {
+				logger.Error("goal reminders failed", "error", err)
+				continue
+			}
+			if sent > 0 {
+				logger.Info("goal reminders sent", "reminders", sent)
+			}
+		}
+	}
+}
+
+// loadGoals loads the goals matching a condition on the goals table
+func (s *GoalService) loadGoals(condition string, args ...interface{}) ([]Goal, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, metric, target, reminder_channel, reminder_address, remind_at, created_at
+		FROM goals
+		WHERE `+condition+`
+		ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []Goal{}
+	for rows.Next() {
+		var g Goal
+		var channel, address, at sql.NullString
+		if err := rows.Scan(&g.ID, &g.UserID, &g.Metric, &g.Target, &channel, &address, &at, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		if channel.Valid {
+			g.Reminder = &Reminder{Channel: channel.String, Address: address.String, At: at.String}
+		}
+		goals = append(goals, g)
+	}
+	return goals, rows.Err()
+}
+
+// progress computes a goal's progress in the period containing now
+func (s *GoalService) progress(goal *Goal, now time.Time) (GoalProgress, error) {
+	start, end := goalPeriod(goal.Metric, now)
+	value, err := goalValue(s.db, goal.Metric, goal.UserID, start, end)
+	if err != nil {
+		return GoalProgress{}, err
+	}
+
+	p := GoalProgress{
+		Value:       value,
+		Percent:     math.Round(1000*float64(value)/float64(goal.Target)) / 10,
+		Met:         value >= goal.Target,
+		PeriodStart: start,
+		PeriodEnd:   end,
+	}
+	remindAt := defaultRemindAt
+	if goal.Reminder != nil && goal.Reminder.At != "" {
+		remindAt = goal.Reminder.At
+	}
+	if at, err := time.Parse("15:04", remindAt); err == nil {
+		due := end.AddDate(0, 0, -1).Add(time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute)
+		p.AtRisk = !p.Met && !now.UTC().Before(due)
+	}
+	return p, nil
+}
+
+// goalPeriod returns the UTC day or, for weekly metrics, the week starting
+// on Monday that contains now
+func goalPeriod(metric string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if metric == GoalNewWordsPerWeek {
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	}
+	return day, day.AddDate(0, 0, 1)
+}
+
+// goalValue computes a metric for the sessions of a user between start and
+// end. Minutes studied are the time from the start of each session, or of
+// the period, to its last review in the period.
+func goalValue(q queryer, metric string, userID int, start, end time.Time) (int, error) {
+	from, to := start.Format(timestampLayout), end.Format(timestampLayout)
+	var query string
+	args := []interface{}{userID, from, to}
+	switch metric {
+	case GoalReviewsPerDay:
+		query = `
+			SELECT COUNT(*)
+			FROM word_review_items r
+			JOIN study_sessions s ON s.id = r.study_session_id
+			WHERE s.user_id = ? AND r.created_at >= ? AND r.created_at < ?`
+	case GoalNewWordsPerWeek:
+		query = `
+			SELECT COUNT(*) FROM (
+				SELECT MIN(r.created_at) AS first_reviewed
+				FROM word_review_items r
+				JOIN study_sessions s ON s.id = r.study_session_id
+				WHERE s.user_id = ?
+				GROUP BY r.word_id
+			)
+			WHERE first_reviewed >= ? AND first_reviewed < ?`
+	case GoalMinutesPerDay:
+		query = `
+			SELECT CAST(ROUND(COALESCE(SUM(minutes), 0)) AS INTEGER) FROM (
+				SELECT MAX(0, (julianday(MAX(r.created_at)) - julianday(MAX(s.created_at, ?))) * 1440) AS minutes
+				FROM word_review_items r
+				JOIN study_sessions s ON s.id = r.study_session_id
+				WHERE s.user_id = ? AND r.created_at >= ? AND r.created_at < ?
+				GROUP BY s.id
+			)`
+		args = append([]interface{}{from}, args...)
+	default:
+		return 0, fmt.Errorf("unknown goal metric %q", metric)
+	}
+
+	var value int
+	err := q.QueryRow(query, args...).Scan(&value)
+	return value, err
+}
+
+// reminderMessage describes a goal at risk to its learner
+func reminderMessage(goal *Goal) notify.Message {
+	var subject, body string
+	switch goal.Metric {
+	case GoalReviewsPerDay:
+		subject = "Your daily review goal is at risk"
+		body = fmt.Sprintf("You have done %d of %d reviews today.", goal.Progress.Value, goal.Target)
+	case GoalMinutesPerDay:
+		subject = "Your daily study time goal is at risk"
+		body = fmt.Sprintf("You have studied %d of %d minutes today.", goal.Progress.Value, goal.Target)
+	case GoalNewWordsPerWeek:
+		subject = "Your weekly new words goal is at risk"
+		body = fmt.Sprintf("You have learned %d of %d new words this week.", goal.Progress.Value, goal.Target)
+	}
+	body += "\nThere is still time to reach it before midnight UTC."
+
+	return notify.Message{
+		To:      goal.Reminder.Address,
+		Subject: subject,
+		Body:    body,
+		Data: map[string]interface{}{
+			"goal_id": goal.ID,
+			"metric":  goal.Metric,
+			"target":  goal.Target,
+			"value":   goal.Progress.Value,
+			"period":  goal.Progress.PeriodStart.Format(time.DateOnly),
+		},
+	}
+}
+
+// validate checks that a reminder's address suits its channel and that its
+// time is valid
+func (r *Reminder) validate() error {
+	var message string
+	field, value := "reminder.address", r.Address
+	switch r.Channel {
+	case ChannelWebhook:
+		u, err := url.ParseRequestURI(r.Address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			message = "Webhook reminders need an http or https URL"
+		}
+	case ChannelEmail:
+		addr, err := mail.ParseAddress(r.Address)
+		if err != nil || addr.Address != r.Address {
+			message = "Email reminders need a plain email address"
+		}
+	default:
+		message = "Reminders are sent by webhook or email"
+	}
+	if _, err := time.Parse("15:04", r.At); err != nil && message == "" {
+		message = "Reminder times are given as HH:MM"
+		field, value = "reminder.at", r.At
+	}
+	if message == "" {
+		return nil
+	}
+	return apperrors.New(apperrors.CodeValidationFailed).WithData([]map[string]string{{
+		"field":   field,
+		"tag":     "reminder",
+		"value":   value,
+		"message": message,
+	}})
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+	"lang-portal/internal/notify"
+)
+
+// recordingChannel records the messages it is asked to send, failing with err
+// when it is set
+type recordingChannel struct {
+	messages []notify.Message
+	err      error
+}
+
+func (c *recordingChannel) Send(_ context.Context, msg notify.Message) error {
+	c.messages = append(c.messages, msg)
+	return c.err
+}
+
+// setupGoals creates two learners. Marcus studies on Sunday 18 and Monday 19
+// October 2026, Julia on Monday.
+func setupGoals(t *testing.T) (*GoalService, *User, *User) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { db.Close() })
+	_, err := db.Exec(`
+		INSERT INTO users (id, name) VALUES (1, 'Marcus'), (2, 'Julia');
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES
+			(1, 'la', 'amare', 'to love', '{}'),
+			(2, 'la', 'videre', 'to see', '{}'),
+			(3, 'la', 'puer', 'boy', '{}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Basics');
+		INSERT INTO study_sessions (id, group_id, user_id, created_at) VALUES
+			(1, 1, 1, '2026-10-18 23:50:00'),
+			(2, 1, 1, '2026-10-19 08:00:00'),
+			(3, 1, 2, '2026-10-19 08:00:00');
+		INSERT INTO word_review_items (word_id, study_session_id, correct, created_at) VALUES
+			(1, 1, 1, '2026-10-18 23:55:00'),
+			(3, 1, 1, '2026-10-19 00:05:00'),
+			(1, 2, 1, '2026-10-19 08:00:00'),
+			(2, 2, 0, '2026-10-19 08:10:00'),
+			(1, 2, 1, '2026-10-19 08:20:00'),
+			(1, 3, 1, '2026-10-19 09:00:00');
+	`)
+	assert.NoError(t, err)
+	return NewGoalService(db), &User{ID: 1, Name: "Marcus"}, &User{ID: 2, Name: "Julia"}
+}
+
+func TestGoalProgress(t *testing.T) {
+	goals, marcus, _ := setupGoals(t)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	for _, input := range []GoalInput{
+		{Metric: GoalReviewsPerDay, Target: 10},
+		{Metric: GoalMinutesPerDay, Target: 20},
+		{Metric: GoalNewWordsPerWeek, Target: 5},
+	} {
+		_, err := goals.CreateGoal(marcus, input, now)
+		assert.NoError(t, err)
+	}
+
+	list, err := goals.GetGoals(marcus, now)
+	assert.NoError(t, err)
+	if !assert.Len(t, list, 3) {
+		return
+	}
+
+	reviews, minutes, words := list[0].Progress, list[1].Progress, list[2].Progress
+	assert.Equal(t, 4, reviews.Value, "the review after midnight counts for Monday")
+	assert.Equal(t, 40.0, reviews.Percent)
+	assert.False(t, reviews.Met)
+	assert.False(t, reviews.AtRisk, "not before 18:00")
+	assert.Equal(t, now.Truncate(24*time.Hour), reviews.PeriodStart)
+
+	assert.Equal(t, 25, minutes.Value, "20 minutes in the morning and 5 after midnight")
+	assert.True(t, minutes.Met)
+
+	assert.Equal(t, 2, words.Value, "amare was first reviewed the week before")
+	assert.Equal(t, time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC), words.PeriodEnd)
+
+	evening, err := goals.GetGoals(marcus, now.Add(7*time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, evening[0].Progress.AtRisk)
+	assert.False(t, evening[2].Progress.AtRisk, "weekly goals are at risk on Sundays")
+}
+
+func TestCreateGoalValidation(t *testing.T) {
+	goals, marcus, julia := setupGoals(t)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	goal, err := goals.CreateGoal(marcus, GoalInput{
+		Metric:   GoalReviewsPerDay,
+		Target:   10,
+		Reminder: &Reminder{Channel: ChannelEmail, Address: "marcus@example.com"},
+	}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "18:00", goal.Reminder.At)
+
+	_, err = goals.CreateGoal(marcus, GoalInput{Metric: GoalReviewsPerDay, Target: 20}, now)
+	assertAppError(t, err, apperrors.CodeGoalAlreadyExists, http.StatusConflict)
+
+	_, err = goals.CreateGoal(julia, GoalInput{
+		Metric:   GoalReviewsPerDay,
+		Target:   10,
+		Reminder: &Reminder{Channel: ChannelEmail, Address: "Julia <julia@example.com>"},
+	}, now)
+	assertAppError(t, err, apperrors.CodeValidationFailed, http.StatusBadRequest)
+
+	_, err = goals.CreateGoal(julia, GoalInput{
+		Metric:   GoalReviewsPerDay,
+		Target:   10,
+		Reminder: &Reminder{Channel: ChannelWebhook, Address: "ftp://example.com/hook"},
+	}, now)
+	assertAppError(t, err, apperrors.CodeValidationFailed, http.StatusBadRequest)
+
+	_, err = goals.CreateGoal(julia, GoalInput{
+		Metric:   GoalReviewsPerDay,
+		Target:   10,
+		Reminder: &Reminder{Channel: ChannelEmail, Address: "julia@example.com", At: "25:00"},
+	}, now)
+	appErr := assertAppError(t, err, apperrors.CodeValidationFailed, http.StatusBadRequest)
+	assert.Equal(t, "reminder.at", appErr.Data.([]map[string]string)[0]["field"])
+
+	// Learners cannot make the server POST to arbitrary addresses
+	_, err = goals.CreateGoal(julia, GoalInput{
+		Metric:   GoalReviewsPerDay,
+		Target:   10,
+		Reminder: &Reminder{Channel: ChannelWebhook, Address: "http://169.254.169.254/latest"},
+	}, now)
+	assertAppError(t, err, apperrors.CodeForbidden, http.StatusForbidden)
+
+	deleted, err := goals.DeleteGoal(julia, goal.ID)
+	assert.NoError(t, err)
+	assert.False(t, deleted, "learners only delete their own goals")
+
+	deleted, err = goals.DeleteGoal(marcus, goal.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+}
+
+func TestCheckRemindersNotifiesOncePerPeriod(t *testing.T) {
+	goals, marcus, julia := setupGoals(t)
+	webhook := &recordingChannel{}
+	goals.SetChannel(ChannelWebhook, webhook)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	_, err := goals.db.Exec("UPDATE users SET role = ? WHERE id = ?", RoleTeacher, marcus.ID)
+	assert.NoError(t, err)
+	marcus.Role = RoleTeacher
+
+	create := func(learner *User, input GoalInput) {
+		_, err := goals.CreateGoal(learner, input, now)
+		assert.NoError(t, err)
+	}
+	create(marcus, GoalInput{
+		Metric:   GoalReviewsPerDay,
+		Target:   10,
+		Reminder: &Reminder{Channel: ChannelWebhook, Address: "https://example.com/marcus", At: "12:30"},
+	})
+	create(marcus, GoalInput{
+		Metric:   GoalMinutesPerDay,
+		Target:   10,
+		Reminder: &Reminder{Channel: ChannelWebhook, Address: "https://example.com/marcus", At: "12:30"},
+	})
+	create(marcus, GoalInput{
+		Metric:   GoalNewWordsPerWeek,
+		Target:   10,
+		Reminder: &Reminder{Channel: ChannelWebhook, Address: "https://example.com/marcus", At: "09:00"},
+	})
+	create(julia, GoalInput{
+		Metric:   GoalReviewsPerDay,
+		Target:   5,
+		Reminder: &Reminder{Channel: ChannelEmail, Address: "julia@example.com", At: "12:30"},
+	})
+
+	sent, err := goals.CheckReminders(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent, "no reminder is due yet")
+
+	sent, err = goals.CheckReminders(context.Background(), now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent, "the unmet daily goals of both learners")
+	if assert.Len(t, webhook.messages, 1) {
+		msg := webhook.messages[0]
+		assert.Equal(t, "https://example.com/marcus", msg.To)
+		assert.Equal(t, "Your daily review goal is at risk", msg.Subject)
+		assert.Contains(t, msg.Body, "You have done 4 of 10 reviews today.")
+	}
+
+	sent, err = goals.CheckReminders(context.Background(), now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent, "reminders are sent once per period")
+
+	notifications, err := goals.GetNotifications(julia, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, NotificationFailed, notifications[0].Status, "no email channel is configured")
+		assert.Equal(t, "2026-10-19", notifications[0].Period)
+		assert.NotNil(t, notifications[0].Error)
+	}
+
+	webhook.err = errors.New("connection refused")
+	sent, err = goals.CheckReminders(context.Background(), now.Add(25*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, sent, "both daily goals of Marcus and the one of Julia")
+	notifications, err = goals.GetNotifications(marcus, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, notifications, 3) {
+		assert.Equal(t, "2026-10-20", notifications[0].Period)
+		assert.Equal(t, NotificationFailed, notifications[0].Status)
+		assert.Equal(t, "connection refused", *notifications[0].Error)
+		assert.Equal(t, NotificationSent, notifications[2].Status)
+	}
+
+	// Webhook reminders stop once their learner loses the teach permission
+	webhook.err = nil
+	_, err = goals.db.Exec("UPDATE users SET role = ? WHERE id = ?", RoleLearner, marcus.ID)
+	assert.NoError(t, err)
+	_, err = goals.CheckReminders(context.Background(), now.Add(49*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, webhook.messages, 3)
+	notifications, err = goals.GetNotifications(marcus, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, NotificationFailed, notifications[0].Status)
+	assert.Equal(t, "webhook reminders need the teach permission", *notifications[0].Error)
+}
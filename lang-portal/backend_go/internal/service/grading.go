@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+
+	"lang-portal/internal/llm"
+)
+
+// Verdicts of a graded answer, from the strictest match to none. Every verdict
+// but VerdictIncorrect counts as correct. VerdictJudged is an answer only the
+// language model accepted.
+const (
+	VerdictExact      = "exact"
+	VerdictDiacritics = "diacritics"
+	VerdictWordOrder  = "word_order"
+	VerdictClose      = "close"
+	VerdictJudged     = "judged"
+	VerdictIncorrect  = "incorrect"
+)
+
+// closeSimilarity is the similarity from which an answer counts as a close
+// match, allowing a typo or two in a sentence
+const closeSimilarity = 0.85
+
+// GradingService grades free-text translations and records them as reviews
+// of the vocabulary they use
+type GradingService struct {
+	instrumented
+
+	db    *sql.DB
+	study *StudyService
+	judge llm.Judge
+}
+
+// GradeInput is an answer to grade. Expected lists the accepted
+// translations of Prompt into Language, which defaults to DefaultLanguage.
+// When SessionID is set, a review is recorded in that session, which must
+// belong to the grading actor, for every vocabulary word of the matched
+// answer. Judge asks the language model for a
+// second opinion, which decides the outcome.
+type GradeInput struct {
+	Prompt    string
+	Expected  []string
+	Answer    string
+	Language  string
+	SessionID int
+	Judge     bool
+}
+
+// GradeResult is the outcome of grading an answer. Similarity is one minus
+// the edit distance to the Matched expected answer, relative to its length.
+// Judgment holds the language model's opinion when one was asked for;
+// JudgeFailed reports that the model could not be reached, in which case the
+// outcome is the deterministic one.
+type GradeResult struct {
+	Correct     bool             `json:"correct"`
+	Score       float64          `json:"score"`
+	Verdict     string           `json:"verdict"`
+	Matched     string           `json:"matched"`
+	Similarity  float64          `json:"similarity"`
+	Judgment    *llm.Judgment    `json:"judgment,omitempty"`
+	JudgeFailed bool             `json:"judge_failed,omitempty"`
+	Words       []GroupWord      `json:"words"`
+	Reviews     []WordReviewItem `json:"reviews,omitempty"`
+}
+
+func NewGradingService(db *sql.DB, study *StudyService, judge llm.Judge) *GradingService {
+	return &GradingService{db: db, instrumented: instrumented{observer: nopObserver{}}, study: study, judge: judge}
+}
+
+// Grade compares an answer with the expected ones, then detects the
+// vocabulary of the matched answer and, within a session of actor, records a
+// review of each word with the outcome
+func (s *GradingService) Grade(ctx context.Context, actor *User, input GradeInput) (*GradeResult, error) {
+	defer timeQuery(s.observer, "GradingService.Grade")()
+
+	if input.SessionID != 0 {
+		if _, err := requireOpenSession(s.db, actor, input.SessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	result := gradeAnswer(input.Expected, input.Answer)
+	if input.Judge {
+		judgment, err := s.judge.JudgeAnswer(ctx, llm.GradeRequest{
+			Language: languageOrDefault(input.Language),
+			Prompt:   input.Prompt,
+			Expected: input.Expected,
+			Answer:   input.Answer,
+		})
+		if err != nil {
+			result.JudgeFailed = true
+		} else {
+			result.Judgment = judgment
+			result.Score = judgment.Score
+			if judgment.Correct != result.Correct {
+				result.Correct = judgment.Correct
+				result.Verdict = VerdictIncorrect
+				if judgment.Correct {
+					result.Verdict = VerdictJudged
+				}
+			}
+		}
+	}
+
+	words, err := s.detectWords(languageOrDefault(input.Language), result.Matched)
+	if err != nil {
+		return nil, err
+	}
+	result.Words = words
+
+	if input.SessionID != 0 && len(words) > 0 {
+		if err := s.recordReviews(actor, input.SessionID, words, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// recordReviews records a review of each word with the outcome of result in
+// one transaction, so that an answer is recorded whole or not at all
+func (s *GradingService) recordReviews(actor *User, sessionID int, words []GroupWord, result *GradeResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID *int
+	for _, w := range words {
+		var review *WordReviewItem
+		review, userID, err = s.study.recordReview(tx, actor, sessionID, w.ID, result.Correct)
+		if err != nil {
+			return err
+		}
+		result.Reviews = append(result.Reviews, *review)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for i := range result.Reviews {
+		s.study.reviewAdded(&result.Reviews[i], userID)
+	}
+	return nil
+}
+
+// detectWords returns the words of a language whose lemma a token of text
+// is a form of. A term of several words is detected when all of them appear
+// as written.
+func (s *GradingService) detectWords(language, text string) ([]GroupWord, error) {
+	words, err := loadGroupWords(s.db, "w.language_code = ?", language)
+	if err != nil {
+		return nil, err
+	}
+	lemmas, err := loadLemmatizer(s.db, language)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := map[string]bool{}
+	lemmaIDs := map[int]bool{}
+	for _, token := range tokenize(text) {
+		token = latinFold(token)
+		tokens[token] = true
+		if entry := lemmas.lookup(token); entry != nil && entry.WordID != nil {
+			lemmaIDs[*entry.WordID] = true
+		}
+	}
+
+	detected := []GroupWord{}
+	for _, w := range words {
+		parts := tokenize(w.Term)
+		found := len(parts) == 1 && lemmaIDs[w.ID]
+		if len(parts) > 1 {
+			found = true
+			for _, part := range parts {
+				if !tokens[latinFold(part)] {
+					found = false
+					break
+				}
+			}
+		}
+		if found {
+			detected = append(detected, w)
+		}
+	}
+	return detected, nil
+}
+
+// gradeAnswer compares answer with each expected answer and returns the best
+// match. Answers are compared after normalizeAnswer, then ignoring
+// diacritics such as macrons, then ignoring word order, and finally by edit
+// distance, taking the lower distance of the answer as written and with its
+// words sorted.
+func gradeAnswer(expected []string, answer string) *GradeResult {
+	best := &GradeResult{Verdict: VerdictIncorrect}
+	if len(expected) > 0 {
+		best.Matched = expected[0]
+	}
+	for _, e := range expected {
+		r := compareAnswer(e, answer)
+		if verdictRank(r.Verdict) < verdictRank(best.Verdict) ||
+			(r.Verdict == best.Verdict && r.Similarity > best.Similarity) {
+			best = r
+		}
+	}
+	best.Correct = best.Verdict != VerdictIncorrect
+	return best
+}
+
+func compareAnswer(expected, answer string) *GradeResult {
+	r := &GradeResult{Matched: expected, Score: 1, Similarity: 1}
+	if normalizeAnswer(expected) == normalizeAnswer(answer) {
+		r.Verdict = VerdictExact
+		return r
+	}
+
+	expectedWords, answerWords := splitWords(expected), splitWords(answer)
+	if strings.Join(expectedWords, " ") == strings.Join(answerWords, " ") {
+		r.Verdict = VerdictDiacritics
+		return r
+	}
+
+	sortedExpected, sortedAnswer := sortedWords(expectedWords), sortedWords(answerWords)
+	if sortedExpected == sortedAnswer {
+		r.Verdict = VerdictWordOrder
+		return r
+	}
+
+	r.Similarity = max(
+		similarity(strings.Join(expectedWords, " "), strings.Join(answerWords, " ")),
+		similarity(sortedExpected, sortedAnswer),
+	)
+	r.Score = r.Similarity
+	r.Verdict = VerdictIncorrect
+	if r.Similarity >= closeSimilarity {
+		r.Verdict = VerdictClose
+	}
+	return r
+}
+
+// verdictRank orders verdicts from the best match
+func verdictRank(verdict string) int {
+	switch verdict {
+	case VerdictExact:
+		return 0
+	case VerdictDiacritics:
+		return 1
+	case VerdictWordOrder:
+		return 2
+	case VerdictClose:
+		return 3
+	case VerdictJudged:
+		return 4
+	}
+	return 5
+}
+
+func sortedWords(words []string) string {
+	sorted := append([]string(nil), words...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, " ")
+}
+
+// similarity is one minus the Levenshtein distance between a and b relative
+// to the longer of the two, so 1 for identical strings
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein counts the insertions, deletions and substitutions turning a
+// into b
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+	"lang-portal/internal/llm"
+)
+
+// scriptedJudge returns a fixed judgment or an error
+type scriptedJudge struct {
+	judgment *llm.Judgment
+	err      error
+}
+
+func (j scriptedJudge) JudgeAnswer(context.Context, llm.GradeRequest) (*llm.Judgment, error) {
+	return j.judgment, j.err
+}
+
+func TestGradeAnswerVerdicts(t *testing.T) {
+	expected := []string{"Puella nautam amat.", "Rōma magna est."}
+	tests := []struct {
+		answer  string
+		verdict string
+		matched string
+	}{
+		{"puella  nautam amat", VerdictExact, "Puella nautam amat."},
+		{"Roma magna est", VerdictDiacritics, "Rōma magna est."},
+		{"Nautam puella amat!", VerdictWordOrder, "Puella nautam amat."},
+		{"Puela nautam amat.", VerdictClose, "Puella nautam amat."},
+		{"Amat nautam puela.", VerdictClose, "Puella nautam amat."},
+		{"Puer rosam videt.", VerdictIncorrect, "Puella nautam amat."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			result := gradeAnswer(expected, tt.answer)
+			assert.Equal(t, tt.verdict, result.Verdict)
+			assert.Equal(t, tt.matched, result.Matched)
+			assert.Equal(t, tt.verdict != VerdictIncorrect, result.Correct)
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, similarity("", ""))
+	assert.Equal(t, 1.0, similarity("amat", "amat"))
+	assert.Equal(t, 0.75, similarity("amat", "amet"))
+	assert.Equal(t, 0.0, similarity("abc", ""))
+	assert.Equal(t, 3, levenshtein([]rune("kitten"), []rune("sitting")))
+}
+
+func setupGrading(t *testing.T, judge llm.Judge) *GradingService {
+	db := setupTestDB(t)
+	t.Cleanup(func() { db.Close() })
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES
+			(1, 'la', 'amare', 'to love', '{}'),
+			(2, 'la', 'puella', 'girl', '{}'),
+			(3, 'la', 'nauta', 'sailor', '{}'),
+			(4, 'la', 'videre', 'to see', '{}'),
+			(5, 'ja', '猫', 'cat', '{}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Chapter 1');
+		INSERT INTO study_sessions (id, group_id) VALUES (1, 1);
+		INSERT INTO study_sessions (id, group_id, completed_at) VALUES (2, 1, datetime('now'));
+	`)
+	assert.NoError(t, err)
+	return NewGradingService(db, NewStudyService(db), judge)
+}
+
+func TestGradeRecordsReviewsOfDetectedWords(t *testing.T) {
+	grading := setupGrading(t, llm.Fake{})
+
+	result, err := grading.Grade(context.Background(), System, GradeInput{
+		Prompt:    "The girl loves the sailor.",
+		Expected:  []string{"Puella nautam amat."},
+		Answer:    "Nautam puela amat",
+		SessionID: 1,
+	})
+	assert.NoError(t, err)
+	assert.True(t, result.Correct)
+	assert.Equal(t, VerdictClose, result.Verdict)
+
+	var ids []int
+	for _, w := range result.Words {
+		ids = append(ids, w.ID)
+	}
+	assert.ElementsMatch(t, []int{1, 2, 3}, ids)
+	if assert.Len(t, result.Reviews, 3) {
+		assert.True(t, result.Reviews[0].Correct)
+		assert.Equal(t, 1, result.Reviews[0].StudySessionID)
+	}
+
+	var count int
+	assert.NoError(t, grading.db.QueryRow("SELECT COUNT(*) FROM word_review_items WHERE study_session_id = 1").Scan(&count))
+	assert.Equal(t, 3, count)
+
+	// Without a session nothing is recorded
+	result, err = grading.Grade(context.Background(), System, GradeInput{
+		Expected: []string{"Puella nautam amat."},
+		Answer:   "Puer videt.",
+	})
+	assert.NoError(t, err)
+	assert.False(t, result.Correct)
+	assert.Len(t, result.Words, 3)
+	assert.Empty(t, result.Reviews)
+}
+
+func TestGradeChecksSession(t *testing.T) {
+	grading := setupGrading(t, llm.Fake{})
+	input := GradeInput{Expected: []string{"Puella amat."}, Answer: "Puella amat.", SessionID: 99}
+
+	_, err := grading.Grade(context.Background(), System, input)
+	assertAppError(t, err, apperrors.CodeSessionNotFound, http.StatusNotFound)
+
+	input.SessionID = 2
+	_, err = grading.Grade(context.Background(), System, input)
+	assertAppError(t, err, apperrors.CodeSessionCompleted, http.StatusConflict)
+}
+
+func TestGradeWithJudge(t *testing.T) {
+	input := GradeInput{
+		Expected:  []string{"Puella nautam amat."},
+		Answer:    "Puella nautam diligit.",
+		SessionID: 1,
+		Judge:     true,
+	}
+
+	// The judge overrides the deterministic verdict
+	judgment := &llm.Judgment{Score: 0.9, Correct: true, Feedback: "Diligit is a synonym of amat."}
+	grading := setupGrading(t, scriptedJudge{judgment: judgment})
+	result, err := grading.Grade(context.Background(), System, input)
+	assert.NoError(t, err)
+	assert.Equal(t, VerdictJudged, result.Verdict)
+	assert.True(t, result.Correct)
+	assert.Equal(t, 0.9, result.Score)
+	assert.Equal(t, judgment, result.Judgment)
+	for _, r := range result.Reviews {
+		assert.True(t, r.Correct)
+	}
+
+	// Rejecting a matching answer makes it incorrect
+	input.Answer = "Puella nautam amat."
+	judgment = &llm.Judgment{Score: 0.2, Correct: false}
+	grading = setupGrading(t, scriptedJudge{judgment: judgment})
+	result, err = grading.Grade(context.Background(), System, input)
+	assert.NoError(t, err)
+	assert.Equal(t, VerdictIncorrect, result.Verdict)
+	assert.False(t, result.Correct)
+	input.Answer = "Puella nautam diligit."
+
+	// An unreachable judge falls back to the deterministic verdict
+	grading = setupGrading(t, scriptedJudge{err: errors.New("connection refused")})
+	result, err = grading.Grade(context.Background(), System, input)
+	assert.NoError(t, err)
+	assert.True(t, result.JudgeFailed)
+	assert.Nil(t, result.Judgment)
+	assert.False(t, result.Correct)
+}
+
+func TestDetectWordsMatchesLemmas(t *testing.T) {
+	grading := setupGrading(t, llm.Fake{})
+	_, err := grading.db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES
+			(6, 'la', 'amicus', 'friend', '{"type":"noun","declension":2}'),
+			(7, 'la', 'res publica', 'republic', '{}');
+	`)
+	assert.NoError(t, err)
+
+	detect := func(text string) []string {
+		words, err := grading.detectWords("la", text)
+		assert.NoError(t, err)
+		var terms []string
+		for _, w := range words {
+			terms = append(terms, w.Term)
+		}
+		return terms
+	}
+
+	assert.ElementsMatch(t, []string{"amare", "puella", "nauta"}, detect("Puellae nautās amant."))
+	// Words sharing the first letters of a stem are not forms of it
+	assert.ElementsMatch(t, []string{"amicus"}, detect("Amici amabiles."))
+	assert.ElementsMatch(t, []string{"puella"}, detect("Puellarum amicitia."))
+	assert.ElementsMatch(t, []string{"res publica"}, detect("Res publica magna est."))
+}
+
+func TestGradeRecordsAnAnswerWhole(t *testing.T) {
+	grading := setupGrading(t, llm.Fake{})
+	// The review of one of the detected words fails
+	_, err := grading.db.Exec(`
+		CREATE TRIGGER reject_nauta BEFORE INSERT ON word_review_items
+		WHEN NEW.word_id = 3 BEGIN SELECT RAISE(ABORT, 'rejected'); END;
+	`)
+	assert.NoError(t, err)
+
+	_, err = grading.Grade(context.Background(), System, GradeInput{
+		Expected:  []string{"Puella nautam amat."},
+		Answer:    "Puella nautam amat.",
+		SessionID: 1,
+	})
+	assert.Error(t, err)
+
+	var count int
+	assert.NoError(t, grading.db.QueryRow("SELECT COUNT(*) FROM word_review_items").Scan(&count))
+	assert.Zero(t, count, "no review of the answer is kept")
+}
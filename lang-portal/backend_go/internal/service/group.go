@@ -2,42 +2,77 @@ package service
 
 import (
 	"database/sql"
+	"errors"
+
+	apperrors "lang-portal/internal/errors"
 )
 
 type GroupService struct {
+	instrumented
+
 	db *sql.DB
 }
 
+// Group is a list of words. Smart groups have a Query instead of a fixed
+// list. Groups without an OwnerID are shared vocabulary.
 type Group struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
-	WordCount int `json:"word_count"`
+	ID        int         `json:"id"`
+	Language  string      `json:"language"`
+	Name      string      `json:"name"`
+	WordCount int         `json:"word_count"`
+	Query     *GroupQuery `json:"query,omitempty"`
+	OwnerID   *int        `json:"owner_id,omitempty"`
+}
+
+// GroupWord is a word as listed in a group
+type GroupWord struct {
+	ID          int     `json:"id"`
+	Term        string  `json:"term"`
+	Translation string  `json:"translation"`
+	Reading     *string `json:"reading,omitempty"`
 }
 
 type GroupWithWords struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Words []struct {
-		ID                int    `json:"id"`
-		LatinWord         string `json:"latin_word"`
-		EnglishTranslation string `json:"english_translation"`
-	} `json:"words"`
+	ID       int         `json:"id"`
+	Language string      `json:"language"`
+	Name     string      `json:"name"`
+	Query    *GroupQuery `json:"query,omitempty"`
+	OwnerID  *int        `json:"owner_id,omitempty"`
+	Words    []GroupWord `json:"words"`
+}
+
+// GroupFilter narrows the groups returned by GetGroups. Zero values match
+// everything.
+type GroupFilter struct {
+	Language string
 }
 
 func NewGroupService(db *sql.DB) *GroupService {
-	return &GroupService{db: db}
+	return &GroupService{db: db, instrumented: instrumented{observer: nopObserver{}}}
 }
 
-// GetGroups retrieves all groups with their word counts
-func (s *GroupService) GetGroups() ([]Group, error) {
+// GetGroups retrieves all groups with their word counts. The members of smart
+// groups are counted by evaluating their query.
+func (s *GroupService) GetGroups(filter GroupFilter) ([]Group, error) {
+	defer timeQuery(s.observer, "GroupService.GetGroups")()
+
+	where := "g.deleted_at IS NULL"
+	var args []interface{}
+	if filter.Language != "" {
+		where += " AND g.language_code = ?"
+		args = append(args, filter.Language)
+	}
+
 	query := `
-		SELECT g.id, g.name, COUNT(wg.word_id) as word_count
+		SELECT g.id, g.language_code, g.name, COUNT(w.id) as word_count, g.query, g.owner_id
 		FROM groups g
 		LEFT JOIN words_groups wg ON g.id = wg.group_id
+		LEFT JOIN words w ON w.id = wg.word_id AND w.deleted_at IS NULL
+		WHERE ` + where + `
 		GROUP BY g.id, g.name
 		ORDER BY g.name`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -46,89 +81,278 @@ func (s *GroupService) GetGroups() ([]Group, error) {
 	var groups []Group
 	for rows.Next() {
 		var g Group
-		if err := rows.Scan(&g.ID, &g.Name, &g.WordCount); err != nil {
+		var query sql.NullString
+		var owner sql.NullInt64
+		if err := rows.Scan(&g.ID, &g.Language, &g.Name, &g.WordCount, &query, &owner); err != nil {
+			return nil, err
+		}
+		g.OwnerID = nullIntPtr(owner)
+		if g.Query, err = parseGroupQuery(query); err != nil {
 			return nil, err
 		}
 		groups = append(groups, g)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i, g := range groups {
+		if g.Query == nil {
+			continue
+		}
+		condition, args := g.Query.condition(g.Language)
+		if groups[i].WordCount, err = countWords(s.db, condition, args...); err != nil {
+			return nil, err
+		}
+	}
 
 	return groups, nil
 }
 
-// GetGroupByID retrieves a single group with its words
+// GetGroupByID retrieves a single group with its words, evaluating the query
+// of smart groups
 func (s *GroupService) GetGroupByID(id int) (*GroupWithWords, error) {
+	defer timeQuery(s.observer, "GroupService.GetGroupByID")()
+
 	// First get the group
 	var group GroupWithWords
-	err := s.db.QueryRow("SELECT id, name FROM groups WHERE id = ?", id).Scan(&group.ID, &group.Name)
+	var query sql.NullString
+	var owner sql.NullInt64
+	err := s.db.QueryRow(
+		"SELECT id, language_code, name, query, owner_id FROM groups WHERE id = ? AND deleted_at IS NULL", id,
+	).Scan(&group.ID, &group.Language, &group.Name, &query, &owner)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
+	group.OwnerID = nullIntPtr(owner)
+	if group.Query, err = parseGroupQuery(query); err != nil {
+		return nil, err
+	}
 
 	// Then get its words
+	condition, args, err := groupWordsCondition(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	group.Words, err = loadGroupWords(s.db, condition, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// countWords counts the words outside the trash matching condition, a SQL
+// expression on words w
+func countWords(q queryer, condition string, args ...interface{}) (int, error) {
+	var count int
+	err := q.QueryRow("SELECT COUNT(*) FROM words w WHERE w.deleted_at IS NULL AND "+condition, args...).Scan(&count)
+	return count, err
+}
+
+// loadGroupWords lists the words outside the trash matching condition, a SQL
+// expression on words w, ordered by term
+func loadGroupWords(q queryer, condition string, args ...interface{}) ([]GroupWord, error) {
 	query := `
-		SELECT w.id, w.latin_word, w.english_translation
+		SELECT w.id, w.term, w.translation, w.reading
 		FROM words w
-		JOIN words_groups wg ON w.id = wg.word_id
-		WHERE wg.group_id = ?
-		ORDER BY w.latin_word`
+		WHERE w.deleted_at IS NULL AND ` + condition + `
+		ORDER BY w.term`
 
-	rows, err := s.db.Query(query, id)
+	rows, err := q.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var words []GroupWord
 	for rows.Next() {
-		var word struct {
-			ID                int    `json:"id"`
-			LatinWord         string `json:"latin_word"`
-			EnglishTranslation string `json:"english_translation"`
-		}
-		if err := rows.Scan(&word.ID, &word.LatinWord, &word.EnglishTranslation); err != nil {
+		var word GroupWord
+		var reading sql.NullString
+		if err := rows.Scan(&word.ID, &word.Term, &word.Translation, &reading); err != nil {
 			return nil, err
 		}
-		group.Words = append(group.Words, word)
+		word.Reading = nullStringPtr(reading)
+		words = append(words, word)
 	}
-
-	return &group, nil
+	return words, rows.Err()
 }
 
-// CreateGroup creates a new word group
-func (s *GroupService) CreateGroup(name string) (*Group, error) {
-	result, err := s.db.Exec("INSERT INTO groups (name) VALUES (?)", name)
+// CreateGroup creates a new word group owned by actor. An empty language
+// means DefaultLanguage. With a query the group is a smart group.
+func (s *GroupService) CreateGroup(actor *User, name, language string, query *GroupQuery) (*Group, error) {
+	defer timeQuery(s.observer, "GroupService.CreateGroup")()
+
+	language = languageOrDefault(language)
+	if query != nil {
+		if err := query.validate(); err != nil {
+			return nil, err
+		}
+	}
+	queryJSON, err := groupQueryJSON(query)
 	if err != nil {
 		return nil, err
 	}
-
-	id, err := result.LastInsertId()
+	id, err := auditedChange(s.db, actor, AuditEntityGroup, AuditCreate, 0, func(tx *sql.Tx) (int, error) {
+		if err := requireLanguage(tx, language); err != nil {
+			return 0, err
+		}
+		result, err := tx.Exec(
+			"INSERT INTO groups (language_code, name, query, owner_id) VALUES (?, ?, ?, ?)",
+			language, name, queryJSON, actorID(actor),
+		)
+		if err != nil {
+			return 0, translateDBError(tx, err)
+		}
+		id, err := result.LastInsertId()
+		return int(id), err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &Group{
-		ID:   int(id),
-		Name: name,
-		WordCount: 0,
-	}, nil
+	group := &Group{ID: id, Language: language, Name: name, Query: query, OwnerID: actorUserID(actor)}
+	if query != nil {
+		condition, args := query.condition(language)
+		if group.WordCount, err = countWords(s.db, condition, args...); err != nil {
+			return nil, err
+		}
+	}
+	return group, nil
 }
 
-// AddWordToGroup adds a word to a group
-func (s *GroupService) AddWordToGroup(wordID, groupID int) error {
-	_, err := s.db.Exec(
-		"INSERT INTO words_groups (word_id, group_id) VALUES (?, ?)",
-		wordID, groupID,
-	)
+// AddWordToGroup adds a word to a group on behalf of actor, who must be
+// allowed to edit it
+func (s *GroupService) AddWordToGroup(actor *User, wordID, groupID int) error {
+	defer timeQuery(s.observer, "GroupService.AddWordToGroup")()
+
+	_, err := auditedChange(s.db, actor, AuditEntityGroup, AuditAddWord, groupID, func(tx *sql.Tx) (int, error) {
+		err := requireActive(tx,
+			reference{"id", "groups", groupID, apperrors.CodeGroupNotFound},
+			reference{"word_id", "words", wordID, apperrors.CodeWordNotFound},
+		)
+		if err != nil {
+			return 0, err
+		}
+		if err := requireGroupEditor(tx, actor, groupID); err != nil {
+			return 0, err
+		}
+		if err := requireStaticGroup(tx, groupID); err != nil {
+			return 0, err
+		}
+		if err := requireSameLanguage(tx, wordID, groupID); err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO words_groups (word_id, group_id) VALUES (?, ?)",
+			wordID, groupID,
+		)
+		if err != nil {
+			return 0, translateDBError(tx, err,
+				reference{"id", "groups", groupID, apperrors.CodeGroupNotFound},
+				reference{"word_id", "words", wordID, apperrors.CodeWordNotFound},
+			)
+		}
+		return groupID, nil
+	})
 	return err
 }
 
-// RemoveWordFromGroup removes a word from a group
-func (s *GroupService) RemoveWordFromGroup(wordID, groupID int) error {
-	_, err := s.db.Exec(
-		"DELETE FROM words_groups WHERE word_id = ? AND group_id = ?",
-		wordID, groupID,
-	)
+// RemoveWordFromGroup removes a word from a group on behalf of actor, who
+// must be allowed to edit it. Removing a word that is not in the group is a
+// no-op.
+func (s *GroupService) RemoveWordFromGroup(actor *User, wordID, groupID int) error {
+	defer timeQuery(s.observer, "GroupService.RemoveWordFromGroup")()
+
+	_, err := auditedChange(s.db, actor, AuditEntityGroup, AuditRemoveWord, groupID, func(tx *sql.Tx) (int, error) {
+		if err := requireGroupEditor(tx, actor, groupID); err != nil {
+			return 0, err
+		}
+		if err := requireStaticGroup(tx, groupID); err != nil {
+			return 0, err
+		}
+		removed, err := affectsRow(tx.Exec(
+			"DELETE FROM words_groups WHERE word_id = ? AND group_id = ?",
+			wordID, groupID,
+		))
+		if err == nil && !removed {
+			err = errNoChange
+		}
+		return groupID, err
+	})
+	if errors.Is(err, errNoChange) {
+		return nil
+	}
 	return err
 }
+
+// DeleteGroup moves a group to the trash on behalf of actor, who must be
+// allowed to edit it. Its words and study sessions are kept. It reports false
+// if the group does not exist or is already in the trash.
+func (s *GroupService) DeleteGroup(actor *User, id int) (bool, error) {
+	defer timeQuery(s.observer, "GroupService.DeleteGroup")()
+
+	_, err := auditedChange(s.db, actor, AuditEntityGroup, AuditDelete, id, func(tx *sql.Tx) (int, error) {
+		if err := requireGroupEditor(tx, actor, id); err != nil {
+			return 0, err
+		}
+		deleted, err := softDelete(tx, "groups", id)
+		if err == nil && !deleted {
+			err = errNoChange
+		}
+		return id, err
+	})
+	if errors.Is(err, errNoChange) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// requireGroupEditor rejects changes to a group by anyone but its owner and
+// admins, or curators for shared groups. Anonymous actors are rejected with
+// UNAUTHORIZED; whether the group exists is left to the caller.
+func requireGroupEditor(q queryer, actor *User, groupID int) error {
+	if actor == nil {
+		return apperrors.New(apperrors.CodeUnauthorized)
+	}
+	if actor.Can(PermissionAdminister) {
+		return nil
+	}
+	var owner sql.NullInt64
+	err := q.QueryRow("SELECT owner_id FROM groups WHERE id = ?", groupID).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if owner.Valid && int(owner.Int64) == actor.ID || !owner.Valid && actor.Can(PermissionCurate) {
+		return nil
+	}
+	return apperrors.New(apperrors.CodeForbidden)
+}
+
+// requireSameLanguage rejects adding a word to a group of another language
+func requireSameLanguage(q queryer, wordID, groupID int) error {
+	var wordLanguage, groupLanguage string
+	err := q.QueryRow(`
+		SELECT w.language_code, g.language_code
+		FROM words w, groups g
+		WHERE w.id = ? AND g.id = ?`, wordID, groupID,
+	).Scan(&wordLanguage, &groupLanguage)
+	if err != nil {
+		return err
+	}
+	if wordLanguage != groupLanguage {
+		return apperrors.New(apperrors.CodeLanguageMismatch).WithData(map[string]string{
+			"word_language":  wordLanguage,
+			"group_language": groupLanguage,
+		})
+	}
+	return nil
+}
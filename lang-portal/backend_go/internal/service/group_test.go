@@ -0,0 +1,97 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+)
+
+func TestGroupOwnership(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	_, err := db.Exec(`
+		INSERT INTO users (id, name, role) VALUES
+			(1, 'Ms. Varro', 'admin'), (2, 'Mr. Cato', 'teacher'), (3, 'Marcus', 'learner'), (4, 'Julia', 'learner');
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES (1, 'la', 'amare', 'to love', '{}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Basics');
+	`)
+	assert.NoError(t, err)
+	admin := &User{ID: 1, Role: RoleAdmin}
+	teacher := &User{ID: 2, Role: RoleTeacher}
+	marcus := &User{ID: 3, Role: RoleLearner}
+	julia := &User{ID: 4, Role: RoleLearner}
+	groups := NewGroupService(db)
+
+	// Anonymous actors never pass; the server acts as System
+	err = groups.AddWordToGroup(nil, 1, 1)
+	assertAppError(t, err, apperrors.CodeUnauthorized, http.StatusUnauthorized)
+	_, err = groups.DeleteGroup(nil, 1)
+	assertAppError(t, err, apperrors.CodeUnauthorized, http.StatusUnauthorized)
+
+	// Shared groups are curated by teachers
+	err = groups.AddWordToGroup(marcus, 1, 1)
+	assertAppError(t, err, apperrors.CodeForbidden, http.StatusForbidden)
+	assert.NoError(t, groups.AddWordToGroup(teacher, 1, 1))
+
+	// Groups created by a learner belong to them
+	group, err := groups.CreateGroup(marcus, "My verbs", "la", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, marcus.ID, *group.OwnerID)
+	assert.NoError(t, groups.AddWordToGroup(marcus, 1, group.ID))
+	for _, other := range []*User{julia, teacher} {
+		err = groups.RemoveWordFromGroup(other, 1, group.ID)
+		assertAppError(t, err, apperrors.CodeForbidden, http.StatusForbidden)
+		_, err = groups.DeleteGroup(other, group.ID)
+		assertAppError(t, err, apperrors.CodeForbidden, http.StatusForbidden)
+	}
+
+	details, err := groups.GetGroupByID(group.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, marcus.ID, *details.OwnerID)
+	assert.Len(t, details.Words, 1)
+
+	deleted, err := groups.DeleteGroup(admin, group.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+}
+
+func TestGroupOwnershipCoversRevertAndRestore(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	_, err := db.Exec(`
+		INSERT INTO users (id, name, role) VALUES (1, 'Ms. Varro', 'admin'), (2, 'Mr. Cato', 'teacher'), (3, 'Marcus', 'learner');
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES (1, 'la', 'amare', 'to love', '{}');
+	`)
+	assert.NoError(t, err)
+	admin := &User{ID: 1, Role: RoleAdmin}
+	teacher := &User{ID: 2, Role: RoleTeacher}
+	marcus := &User{ID: 3, Role: RoleLearner}
+	groups := NewGroupService(db)
+	audit := NewAuditService(db)
+	trash := NewTrashService(db)
+
+	group, err := groups.CreateGroup(marcus, "My verbs", "la", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, groups.AddWordToGroup(marcus, 1, group.ID))
+	entries, err := audit.ListEntries(AuditFilter{Entity: AuditEntityGroup, EntityID: group.ID})
+	assert.NoError(t, err)
+
+	// A curator who may not edit the group cannot revert its changes
+	_, err = audit.Revert(teacher, entries[0].ID)
+	appErr := assertAppError(t, err, apperrors.CodeForbidden, http.StatusForbidden)
+	assert.Equal(t, apperrors.TypeForbidden, appErr.Type)
+	_, err = audit.Revert(admin, entries[0].ID)
+	assert.NoError(t, err)
+
+	// Nor restore it from the trash
+	_, err = groups.DeleteGroup(marcus, group.ID)
+	assert.NoError(t, err)
+	_, err = trash.Restore(teacher, TrashGroups, group.ID)
+	appErr = assertAppError(t, err, apperrors.CodeForbidden, http.StatusForbidden)
+	assert.Equal(t, apperrors.TypeForbidden, appErr.Type)
+	restored, err := trash.Restore(admin, TrashGroups, group.ID)
+	assert.NoError(t, err)
+	assert.True(t, restored)
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	apperrors "lang-portal/internal/errors"
+	"lang-portal/internal/media"
+)
+
+// DefaultMaxImageSize is the largest image file accepted for upload
+const DefaultMaxImageSize = 10 << 20
+
+// WordImage is a picture of a word. Width and Height are those of the
+// original; URLs maps each variant name to where it is served.
+type WordImage struct {
+	ID     int               `json:"id"`
+	Width  int               `json:"width"`
+	Height int               `json:"height"`
+	URLs   map[string]string `json:"urls"`
+}
+
+// SetMaxImageSize overrides the largest image file accepted, in bytes
+func (s *MediaService) SetMaxImageSize(size int64) {
+	s.maxImageSize = size
+}
+
+// AttachImage adds the image read from r to the pictures of a word. The image
+// is resized into every variant and re-encoded, which strips its EXIF data.
+func (s *MediaService) AttachImage(wordID int, r io.Reader) (*WordImage, error) {
+	defer timeQuery(s.observer, "MediaService.AttachImage")()
+
+	data, err := readLimited(r, s.maxImageSize)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := media.ProcessImage(data)
+	if errors.Is(err, media.ErrUnsupportedImage) {
+		return nil, apperrors.New(apperrors.CodeUnsupportedMediaFormat).
+			WithData(map[string]interface{}{"allowed": media.ImageFormats})
+	}
+	if errors.Is(err, media.ErrImageDimensions) {
+		return nil, apperrors.New(apperrors.CodeImageTooLarge)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := requireActive(tx, reference{"id", "words", wordID, apperrors.CodeWordNotFound}); err != nil {
+		return nil, err
+	}
+	result, err := tx.Exec("INSERT INTO word_images (word_id) VALUES (?)", wordID)
+	if err != nil {
+		return nil, err
+	}
+	imageID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	image := &WordImage{ID: int(imageID), URLs: make(map[string]string)}
+	for _, v := range variants {
+		hash, err := s.storeMedia(tx, v.ContentType, v.Data)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO word_image_variants (image_id, name, media_hash, width, height) VALUES (?, ?, ?, ?, ?)",
+			imageID, v.Name, hash, v.Width, v.Height,
+		); err != nil {
+			return nil, err
+		}
+		if v.Name == media.VariantOriginal {
+			image.Width, image.Height = v.Width, v.Height
+		}
+		image.URLs[v.Name] = mediaURL(hash)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// DeleteImage removes a picture from a word. It returns false if the word has
+// no such image or is in the trash. The stored files are kept, since other
+// images may share them.
+func (s *MediaService) DeleteImage(wordID, imageID int) (bool, error) {
+	defer timeQuery(s.observer, "MediaService.DeleteImage")()
+
+	return affectsRow(s.db.Exec(`
+		DELETE FROM word_images
+		WHERE id = ? AND word_id = ? AND word_id IN (SELECT id FROM words WHERE deleted_at IS NULL)`,
+		imageID, wordID,
+	))
+}
+
+// OpenMedia opens a stored file by its hash. It returns nil if there is none.
+func (s *MediaService) OpenMedia(hash string) (*MediaFile, error) {
+	defer timeQuery(s.observer, "MediaService.OpenMedia")()
+
+	file := MediaFile{Hash: hash}
+	err := s.db.QueryRow(
+		"SELECT content_type, size, created_at FROM media WHERE hash = ?", hash,
+	).Scan(&file.ContentType, &file.Size, &file.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file.Content, err = s.store.Open(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open media %s: %w", hash, err)
+	}
+	return &file, nil
+}
+
+// loadImages returns the pictures of each word, in upload order
+func loadImages(q queryer, wordIDs []int) (map[int][]WordImage, error) {
+	images := make(map[int][]WordImage)
+	if len(wordIDs) == 0 {
+		return images, nil
+	}
+	placeholders := make([]string, len(wordIDs))
+	args := make([]interface{}, len(wordIDs))
+	for i, id := range wordIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := q.Query(`
+		SELECT i.word_id, i.id, v.name, v.media_hash, v.width, v.height
+		FROM word_images i
+		JOIN word_image_variants v ON v.image_id = i.id
+		WHERE i.word_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY i.word_id, i.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var wordID, imageID, width, height int
+		var name, hash string
+		if err := rows.Scan(&wordID, &imageID, &name, &hash, &width, &height); err != nil {
+			return nil, err
+		}
+		list := images[wordID]
+		if len(list) == 0 || list[len(list)-1].ID != imageID {
+			list = append(list, WordImage{ID: imageID, URLs: make(map[string]string)})
+		}
+		image := &list[len(list)-1]
+		image.URLs[name] = mediaURL(hash)
+		if name == media.VariantOriginal {
+			image.Width, image.Height = width, height
+		}
+		images[wordID] = list
+	}
+	return images, rows.Err()
+}
+
+// mediaURL is where the API serves a stored file. The URL changes with the
+// content, so responses can be cached indefinitely.
+func mediaURL(hash string) string {
+	return "/api/media/" + hash
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"database/sql"
+
+	apperrors "lang-portal/internal/errors"
+)
+
+// DefaultLanguage is assigned to words and groups created without one, and
+// is the language of every row that predates multi-language support
+const DefaultLanguage = "la"
+
+// LanguageService manages the languages taught through the portal
+type LanguageService struct {
+	instrumented
+
+	db *sql.DB
+}
+
+// Language is a language words and groups belong to
+type Language struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	Script     string `json:"script"`
+	HasReading bool   `json:"has_reading"`
+}
+
+func NewLanguageService(db *sql.DB) *LanguageService {
+	return &LanguageService{db: db, instrumented: instrumented{observer: nopObserver{}}}
+}
+
+// GetLanguages retrieves every language ordered by name
+func (s *LanguageService) GetLanguages() ([]Language, error) {
+	defer timeQuery(s.observer, "LanguageService.GetLanguages")()
+
+	rows, err := s.db.Query("SELECT code, name, script, has_reading FROM languages ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	languages := []Language{}
+	for rows.Next() {
+		var l Language
+		if err := rows.Scan(&l.Code, &l.Name, &l.Script, &l.HasReading); err != nil {
+			return nil, err
+		}
+		languages = append(languages, l)
+	}
+	return languages, rows.Err()
+}
+
+// CreateLanguage adds a language
+func (s *LanguageService) CreateLanguage(language Language) (*Language, error) {
+	defer timeQuery(s.observer, "LanguageService.CreateLanguage")()
+
+	_, err := s.db.Exec(
+		"INSERT INTO languages (code, name, script, has_reading) VALUES (?, ?, ?, ?)",
+		language.Code, language.Name, language.Script, language.HasReading,
+	)
+	if err != nil {
+		return nil, translateDBError(s.db, err)
+	}
+	return &language, nil
+}
+
+func languageOrDefault(code string) string {
+	if code == "" {
+		return DefaultLanguage
+	}
+	return code
+}
+
+// requireLanguage returns a not-found error unless the language exists
+func requireLanguage(q queryer, code string) error {
+	var exists bool
+	if err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM languages WHERE code = ?)", code).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return apperrors.New(apperrors.CodeLanguageNotFound).
+			WithData(map[string]string{"field": "language", "value": code})
+	}
+	return nil
+}
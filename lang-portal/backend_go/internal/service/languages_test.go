@@ -0,0 +1,100 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+)
+
+func TestWordsAndGroupsAreScopedByLanguage(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	words := NewWordService(db)
+	groups := NewGroupService(db)
+
+	reading := "みず"
+	_, err := words.CreateWord(nil, WordInput{Term: "aqua", Translation: "water"})
+	assert.NoError(t, err)
+	mizu, err := words.CreateWord(nil, WordInput{Language: "ja", Term: "水", Translation: "water", Reading: &reading})
+	assert.NoError(t, err)
+	assert.Equal(t, "ja", mizu.Language)
+	assert.Equal(t, "みず", *mizu.Reading)
+
+	// The same term may exist once per language
+	_, err = words.CreateWord(nil, WordInput{Language: "grc", Term: "aqua", Translation: "water"})
+	assert.NoError(t, err)
+	_, err = words.CreateWord(nil, WordInput{Term: "aqua", Translation: "water"})
+	assertAppError(t, err, apperrors.CodeWordAlreadyExists, http.StatusConflict)
+
+	_, err = words.CreateWord(nil, WordInput{Language: "xx", Term: "aqua", Translation: "water"})
+	assertAppError(t, err, apperrors.CodeLanguageNotFound, http.StatusNotFound)
+
+	page, err := words.GetWords(1, 10, WordFilter{Language: "ja"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page.TotalItems)
+	assert.Equal(t, "水", page.Items[0].Term)
+
+	nature, err := groups.CreateGroup(nil, "Nature", "ja", nil)
+	assert.NoError(t, err)
+	_, err = groups.CreateGroup(nil, "Nature", "", nil)
+	assert.NoError(t, err)
+
+	list, err := groups.GetGroups(GroupFilter{Language: "ja"})
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, nature.ID, list[0].ID)
+	}
+
+	assert.NoError(t, groups.AddWordToGroup(System, mizu.ID, nature.ID))
+	err = groups.AddWordToGroup(System, 1, nature.ID)
+	assertAppError(t, err, apperrors.CodeLanguageMismatch, http.StatusUnprocessableEntity)
+
+	// A grouped word cannot move to another language
+	_, err = words.UpdateWord(nil, mizu.ID, WordInput{Language: "la", Term: "mizu", Translation: "water"})
+	assertAppError(t, err, apperrors.CodeLanguageMismatch, http.StatusUnprocessableEntity)
+}
+
+func TestDashboardLanguageFilter(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts)
+		VALUES (1, 'la', 'amare', 'to love', '{}'), (2, 'ja', '水', 'water', '{}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Verbs'), (2, 'ja', 'Nature');
+		INSERT INTO study_sessions (id, group_id, created_at) VALUES
+			(1, 1, datetime('now', '-1 day')),
+			(2, 2, datetime('now', '-2 days'));
+		INSERT INTO word_review_items (word_id, study_session_id, correct) VALUES
+			(1, 1, 1), (2, 2, 0);
+	`)
+	assert.NoError(t, err)
+
+	service := NewStudyService(db)
+
+	session, err := service.GetLastStudySession("ja")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, session.ID)
+	assert.Equal(t, "ja", session.Language)
+
+	progress, err := service.GetStudyProgress("ja")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, progress.TotalAvailableWords)
+
+	stats, err := service.GetQuickStats("ja")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.TotalStudySessions)
+	assert.Equal(t, 0.0, stats.SuccessRate)
+
+	stats, err = service.GetQuickStats("")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.TotalStudySessions)
+	assert.Equal(t, 50.0, stats.SuccessRate)
+
+	languages, err := NewLanguageService(db).GetLanguages()
+	assert.NoError(t, err)
+	assert.Len(t, languages, 3)
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// Latin endings attached to the stems of a lemma. Stems are derived from its
+// term and parts: declension, conjugation, genitive and principal_parts.
+var (
+	firstDeclension  = []string{"a", "ae", "am", "arum", "is", "as"}
+	secondDeclension = []string{"us", "i", "o", "um", "e", "orum", "is", "os", "a"}
+	thirdDeclension  = []string{"is", "i", "em", "e", "es", "um", "ibus", "a", "ia", "ium"}
+	fourthDeclension = []string{"us", "ui", "um", "u", "uum", "ibus", "ua"}
+	fifthDeclension  = []string{"es", "ei", "em", "e", "erum", "ebus"}
+
+	// adjectiveEndings inflect adjectives and participles of the first and
+	// second declensions
+	adjectiveEndings = append(append([]string{}, firstDeclension...), secondDeclension...)
+
+	// perfectEndings inflect the perfect stem, the third principal part
+	// without its final i
+	perfectEndings = []string{
+		"i", "isti", "it", "imus", "istis", "erunt", "ere",
+		"eram", "eras", "erat", "eramus", "eratis", "erant",
+		"ero", "eris", "erit", "erimus", "eritis", "erint",
+		"erim", "isse", "issem", "isses", "isset", "issemus", "issetis", "issent",
+	}
+
+	// presentEndings inflect the present stem, the infinitive without its
+	// thematic vowel and ending, in every conjugation
+	presentEndings = combineEndings(
+		[]string{"", "a", "e", "i", "u", "ie", "ia", "ea", "iu", "eba", "aba", "ieba", "ebi", "abi", "ibi", "are", "ere", "ire"},
+		[]string{"o", "m", "s", "t", "mus", "tis", "nt", "unt", "r", "ris", "tur", "mur", "mini", "ntur", "re", "ri", "te", "ns", "ntis", "nti", "ntem", "nte", "ntes", "ntium", "ntibus", ""},
+		combineEndings([]string{"nd"}, adjectiveEndings),
+	)
+)
+
+// combineEndings returns every prefix followed by every suffix, plus the
+// extra endings, without the empty ending
+func combineEndings(prefixes, suffixes []string, extra ...[]string) []string {
+	var endings []string
+	for _, p := range prefixes {
+		for _, s := range suffixes {
+			if p+s != "" {
+				endings = append(endings, p+s)
+			}
+		}
+	}
+	for _, e := range extra {
+		endings = append(endings, e...)
+	}
+	return endings
+}
+
+// lemmaEntry is a lemma the analyser can recognise: a word of the vocabulary,
+// a lemma of the dictionary, or both when they share a term
+type lemmaEntry struct {
+	Lemma        string
+	Translation  string
+	Parts        json.RawMessage
+	WordID       *int
+	InDictionary bool
+}
+
+// lemmaParts holds the morphology in parts used to derive forms
+type lemmaParts struct {
+	Type           string          `json:"type"`
+	Declension     json.RawMessage `json:"declension"`
+	Conjugation    json.RawMessage `json:"conjugation"`
+	Genitive       string          `json:"genitive"`
+	PrincipalParts []string        `json:"principal_parts"`
+}
+
+// stemEntry is a stem of a lemma with the endings it takes
+type stemEntry struct {
+	entry   *lemmaEntry
+	endings map[string]bool
+}
+
+// lemmatizer maps Latin tokens to lemmas, first through the forms listed for
+// them, then through their stems followed by a known ending
+type lemmatizer struct {
+	entries []*lemmaEntry
+	byKey   map[string]*lemmaEntry
+	forms   map[string]*lemmaEntry
+	stems   map[string][]stemEntry
+}
+
+func newLemmatizer() *lemmatizer {
+	return &lemmatizer{
+		byKey: map[string]*lemmaEntry{},
+		forms: map[string]*lemmaEntry{},
+		stems: map[string][]stemEntry{},
+	}
+}
+
+// add registers a lemma with its irregular forms. A lemma added under a term
+// already known merges into it, adding its forms and stems, so words take
+// precedence over the dictionary when added first.
+func (l *lemmatizer) add(entry lemmaEntry, forms []string) {
+	key := latinFold(entry.Lemma)
+	existing := l.byKey[key]
+	if existing == nil {
+		existing = &entry
+		l.byKey[key] = existing
+		l.entries = append(l.entries, existing)
+		l.addForm(key, existing)
+	} else if entry.InDictionary {
+		existing.InDictionary = true
+	}
+	l.addStems(key, entry.Parts, existing)
+	for _, f := range forms {
+		l.addForm(latinFold(f), existing)
+	}
+}
+
+// addForm maps a folded form to an entry unless it already names another
+func (l *lemmatizer) addForm(form string, entry *lemmaEntry) {
+	if _, ok := l.forms[form]; !ok && form != "" {
+		l.forms[form] = entry
+	}
+}
+
+func (l *lemmatizer) addStem(stem string, entry *lemmaEntry, endings []string) {
+	if len([]rune(stem)) < 2 {
+		return
+	}
+	set := make(map[string]bool, len(endings))
+	for _, e := range endings {
+		set[e] = true
+	}
+	l.stems[stem] = append(l.stems[stem], stemEntry{entry: entry, endings: set})
+}
+
+// addStems derives the stems of a lemma from its folded term and parts.
+// Lemmas without a type are treated as verbs or nouns by their ending.
+func (l *lemmatizer) addStems(term string, raw json.RawMessage, entry *lemmaEntry) {
+	var parts lemmaParts
+	_ = json.Unmarshal(raw, &parts)
+	for _, p := range parts.PrincipalParts {
+		l.addForm(latinFold(p), entry)
+	}
+
+	kind := parts.Type
+	if kind == "" {
+		switch {
+		case hasAnySuffix(term, "are", "ere", "ire"):
+			kind = "verb"
+		case hasAnySuffix(term, "a", "us", "um"):
+			kind = "noun"
+		}
+	}
+
+	switch kind {
+	case "verb":
+		l.addVerbStems(term, parts, entry)
+	case "noun":
+		l.addNounStems(term, parts, entry)
+	case "adjective":
+		l.addAdjectiveStems(term, parts, entry)
+	}
+}
+
+func (l *lemmatizer) addVerbStems(term string, parts lemmaParts, entry *lemmaEntry) {
+	if hasAnySuffix(term, "are", "ere", "ire", "ari", "eri", "iri") {
+		l.addStem(trimRunes(term, 3), entry, presentEndings)
+	} else if strings.HasSuffix(term, "i") {
+		l.addStem(trimRunes(term, 1), entry, presentEndings)
+	}
+
+	principal := make([]string, len(parts.PrincipalParts))
+	for i, p := range parts.PrincipalParts {
+		principal[i] = latinFold(p)
+	}
+	// Regular verbs of the first conjugation need no principal parts
+	if len(principal) < 3 && jsonNumber(parts.Conjugation) == "1" && strings.HasSuffix(term, "are") {
+		stem := trimRunes(term, 3)
+		principal = []string{stem + "o", term, stem + "aui", stem + "atus"}
+	}
+	if len(principal) >= 3 && strings.HasSuffix(principal[2], "i") {
+		l.addStem(trimRunes(principal[2], 1), entry, perfectEndings)
+	}
+	if len(principal) >= 4 && hasAnySuffix(principal[3], "us", "um") {
+		stem := trimRunes(principal[3], 2)
+		l.addStem(stem, entry, adjectiveEndings)
+		l.addStem(stem+"ur", entry, adjectiveEndings)
+	}
+}
+
+func (l *lemmatizer) addNounStems(term string, parts lemmaParts, entry *lemmaEntry) {
+	declension := jsonNumber(parts.Declension)
+	if declension == "" {
+		switch {
+		case strings.HasSuffix(term, "a"):
+			declension = "1"
+		case hasAnySuffix(term, "us", "um"):
+			declension = "2"
+		default:
+			declension = "3"
+		}
+	}
+
+	switch declension {
+	case "1":
+		if strings.HasSuffix(term, "a") {
+			l.addStem(trimRunes(term, 1), entry, firstDeclension)
+		}
+	case "2":
+		switch {
+		case hasAnySuffix(term, "us", "um"):
+			l.addStem(trimRunes(term, 2), entry, secondDeclension)
+		case strings.HasSuffix(term, "er"):
+			// puer keeps its e, ager drops it
+			l.addStem(term, entry, secondDeclension)
+			l.addStem(trimRunes(term, 2)+"r", entry, secondDeclension)
+		default:
+			l.addStem(term, entry, secondDeclension)
+		}
+	case "3":
+		if strings.HasSuffix(latinFold(parts.Genitive), "is") {
+			l.addStem(trimRunes(latinFold(parts.Genitive), 2), entry, thirdDeclension)
+		}
+		l.addStem(term, entry, thirdDeclension)
+	case "4":
+		if hasAnySuffix(term, "us") {
+			l.addStem(trimRunes(term, 2), entry, fourthDeclension)
+		} else if strings.HasSuffix(term, "u") {
+			l.addStem(trimRunes(term, 1), entry, fourthDeclension)
+		}
+	case "5":
+		if strings.HasSuffix(term, "es") {
+			l.addStem(trimRunes(term, 2), entry, fifthDeclension)
+		}
+	}
+}
+
+func (l *lemmatizer) addAdjectiveStems(term string, parts lemmaParts, entry *lemmaEntry) {
+	declension := jsonNumber(parts.Declension)
+	var stem string
+	var endings []string
+	switch {
+	case strings.HasPrefix(declension, "3"):
+		stem, endings = term, thirdDeclension
+		if strings.HasSuffix(latinFold(parts.Genitive), "is") {
+			stem = trimRunes(latinFold(parts.Genitive), 2)
+		} else if strings.HasSuffix(term, "is") {
+			stem = trimRunes(term, 2)
+		}
+	case hasAnySuffix(term, "us"):
+		stem, endings = trimRunes(term, 2), adjectiveEndings
+	case strings.HasSuffix(term, "er"):
+		stem, endings = term, adjectiveEndings
+		l.addStem(trimRunes(term, 2)+"r", entry, adjectiveEndings)
+	default:
+		return
+	}
+	l.addStem(stem, entry, endings)
+	// Comparatives and superlatives
+	l.addStem(stem+"ior", entry, append([]string{""}, thirdDeclension...))
+	l.addStem(stem+"ius", entry, []string{""})
+	l.addStem(stem+"issim", entry, adjectiveEndings)
+}
+
+// lookup returns the lemma of a folded token, or nil if it is not recognised.
+// Enclitics such as -que are split off when the token is not a form itself.
+func (l *lemmatizer) lookup(token string) *lemmaEntry {
+	if entry := l.match(token); entry != nil {
+		return entry
+	}
+	for _, enclitic := range []string{"que", "ne", "ue"} {
+		if base, ok := strings.CutSuffix(token, enclitic); ok && len([]rune(base)) >= 2 {
+			if entry := l.match(base); entry != nil {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// match prefers a listed form, then the longest stem taking the rest of the
+// token as an ending
+func (l *lemmatizer) match(token string) *lemmaEntry {
+	if entry, ok := l.forms[token]; ok {
+		return entry
+	}
+	runes := []rune(token)
+	for i := len(runes); i >= 2; i-- {
+		stem, ending := string(runes[:i]), string(runes[i:])
+		for _, s := range l.stems[stem] {
+			if s.endings[ending] {
+				return s.entry
+			}
+		}
+	}
+	return nil
+}
+
+// latinFold folds a Latin word for lookups: lowercase, without macrons, and
+// with the consonants j and v written i and u
+func latinFold(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case 'j':
+			return 'i'
+		case 'v':
+			return 'u'
+		}
+		return r
+	}, foldWord(strings.TrimSpace(s)))
+}
+
+// tokenize splits a text into its words as written
+func tokenize(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r)
+	})
+}
+
+func hasAnySuffix(s string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimRunes drops the last n runes of s
+func trimRunes(s string, n int) string {
+	runes := []rune(s)
+	return string(runes[:max(0, len(runes)-n)])
+}
+
+// jsonNumber returns a parts value such as 3, "3" or "1st/2nd" as text,
+// keeping only its leading digit for ordinals
+func jsonNumber(raw json.RawMessage) string {
+	s := strings.Trim(string(raw), `"`)
+	if s == "" || s == "null" {
+		return ""
+	}
+	if s[0] >= '0' && s[0] <= '9' {
+		return s[:1]
+	}
+	return s
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// MasteryLevel describes how well a learner knows a word
+type MasteryLevel string
+
+const (
+	MasteryNew       MasteryLevel = "new"
+	MasteryLearning  MasteryLevel = "learning"
+	MasteryReviewing MasteryLevel = "reviewing"
+	MasteryMastered  MasteryLevel = "mastered"
+)
+
+// MasteryConfig holds the thresholds used to classify a word's review history
+type MasteryConfig struct {
+	ReviewingStreak  int     // consecutive correct answers needed to reach "reviewing"
+	MasteredStreak   int     // consecutive correct answers needed to reach "mastered"
+	MasteredAccuracy float64 // minimum accuracy (0-1) over the window to reach "mastered"
+	Window           int     // number of most recent reviews used for accuracy
+}
+
+// DefaultMasteryConfig returns the thresholds used when none are configured
+func DefaultMasteryConfig() MasteryConfig {
+	return MasteryConfig{
+		ReviewingStreak:  2,
+		MasteredStreak:   4,
+		MasteredAccuracy: 0.8,
+		Window:           10,
+	}
+}
+
+// Level classifies a review history, ordered from most recent to oldest
+func (c MasteryConfig) Level(history []bool) MasteryLevel {
+	if len(history) == 0 {
+		return MasteryNew
+	}
+
+	streak := 0
+	for _, correct := range history {
+		if !correct {
+			break
+		}
+		streak++
+	}
+
+	window := history
+	if c.Window > 0 && len(window) > c.Window {
+		window = window[:c.Window]
+	}
+	correct := 0
+	for _, ok := range window {
+		if ok {
+			correct++
+		}
+	}
+	accuracy := float64(correct) / float64(len(window))
+
+	switch {
+	case streak >= c.MasteredStreak && accuracy >= c.MasteredAccuracy:
+		return MasteryMastered
+	case streak >= c.ReviewingStreak:
+		return MasteryReviewing
+	default:
+		return MasteryLearning
+	}
+}
+
+// IsLearned reports whether a level counts as learned
+func (l MasteryLevel) IsLearned() bool {
+	return l == MasteryMastered
+}
+
+// IsStudied reports whether a level shows the word is being recalled correctly
+func (l MasteryLevel) IsStudied() bool {
+	return l == MasteryReviewing || l == MasteryMastered
+}
+
+// loadReviewHistory returns each word's review outcomes, most recent first.
+// When wordIDs is empty the history of every reviewed word outside the trash
+// is returned, limited to language unless it is empty.
+func loadReviewHistory(db *sql.DB, wordIDs []int, language string) (map[int][]bool, error) {
+	query := "SELECT word_id, correct FROM word_review_items"
+	args := make([]interface{}, 0, len(wordIDs))
+	if len(wordIDs) > 0 {
+		placeholders := make([]string, len(wordIDs))
+		for i, id := range wordIDs {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		query += " WHERE word_id IN (" + strings.Join(placeholders, ", ") + ")"
+	} else if language != "" {
+		query += " WHERE word_id IN (SELECT id FROM words WHERE deleted_at IS NULL AND language_code = ?)"
+		args = append(args, language)
+	} else {
+		query += " WHERE word_id IN (SELECT id FROM words WHERE deleted_at IS NULL)"
+	}
+	query += " ORDER BY word_id, created_at DESC, id DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make(map[int][]bool)
+	for rows.Next() {
+		var wordID int
+		var correct bool
+		if err := rows.Scan(&wordID, &correct); err != nil {
+			return nil, err
+		}
+		history[wordID] = append(history[wordID], correct)
+	}
+	return history, rows.Err()
+}
+
+// loadLearnerHistory returns the review history of words, most recent first,
+// counting only the reviews of sessions started by userID. When wordIDs is
+// empty the history of every reviewed word outside the trash is returned. A
+// nil userID counts every review, as loadReviewHistory does.
+func loadLearnerHistory(db *sql.DB, userID *int, wordIDs []int) (map[int][]bool, error) {
+	if userID == nil {
+		return loadReviewHistory(db, wordIDs, "")
+	}
+
+	condition := "r.word_id IN (SELECT id FROM words WHERE deleted_at IS NULL)"
+	args := []interface{}{*userID}
+	if len(wordIDs) > 0 {
+		placeholders := make([]string, len(wordIDs))
+		for i, id := range wordIDs {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		condition = "r.word_id IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	rows, err := db.Query(`
+		SELECT r.word_id, r.correct
+		FROM word_review_items r
+		JOIN study_sessions s ON s.id = r.study_session_id
+		WHERE s.user_id = ? AND `+condition+`
+		ORDER BY r.word_id, r.created_at DESC, r.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make(map[int][]bool)
+	for rows.Next() {
+		var wordID int
+		var correct bool
+		if err := rows.Scan(&wordID, &correct); err != nil {
+			return nil, err
+		}
+		history[wordID] = append(history[wordID], correct)
+	}
+	return history, rows.Err()
+}
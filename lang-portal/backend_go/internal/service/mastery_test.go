@@ -0,0 +1,73 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMasteryLevel(t *testing.T) {
+	cfg := DefaultMasteryConfig()
+
+	tests := []struct {
+		name    string
+		history []bool
+		want    MasteryLevel
+	}{
+		{"no reviews", nil, MasteryNew},
+		{"single wrong answer", []bool{false}, MasteryLearning},
+		{"single correct answer", []bool{true}, MasteryLearning},
+		{"short streak", []bool{true, true, false}, MasteryReviewing},
+		{"long streak", []bool{true, true, true, true}, MasteryMastered},
+		{"long streak with poor accuracy", []bool{true, true, true, true, false, false, false, false, false, false}, MasteryReviewing},
+		{"streak broken by latest answer", []bool{false, true, true, true, true}, MasteryLearning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, cfg.Level(tt.history))
+		})
+	}
+}
+
+func TestGetWordsMastery(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service := NewWordService(db)
+	service.SetMasteryConfig(MasteryConfig{
+		ReviewingStreak:  1,
+		MasteredStreak:   2,
+		MasteredAccuracy: 1,
+		Window:           5,
+	})
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts)
+		VALUES 
+			(1, 'la', 'amare', 'to love', '{"type":"verb"}'),
+			(2, 'la', 'videre', 'to see', '{"type":"verb"}'),
+			(3, 'la', 'puer', 'boy', '{"type":"noun"}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Test Group');
+		INSERT INTO study_sessions (id, group_id) VALUES (1, 1);
+		INSERT INTO word_review_items (word_id, study_session_id, correct)
+		VALUES (1, 1, true), (1, 1, true), (2, 1, true);
+	`)
+	assert.NoError(t, err)
+
+	words, err := service.GetWords(1, 10, WordFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, words.Items, 3)
+
+	levels := map[string]MasteryLevel{}
+	for _, w := range words.Items {
+		levels[w.Term] = w.Mastery
+	}
+	assert.Equal(t, MasteryMastered, levels["amare"])
+	assert.Equal(t, MasteryReviewing, levels["videre"])
+	assert.Equal(t, MasteryNew, levels["puer"])
+
+	word, err := service.GetWordByID(1)
+	assert.NoError(t, err)
+	assert.True(t, word.Learned)
+}
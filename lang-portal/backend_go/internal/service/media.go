@@ -0,0 +1,229 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+
+	apperrors "lang-portal/internal/errors"
+	"lang-portal/internal/media"
+)
+
+// DefaultMaxAudioSize is the largest audio clip accepted for upload
+const DefaultMaxAudioSize = 5 << 20
+
+// MediaService attaches uploaded media to words and serves it back
+type MediaService struct {
+	instrumented
+
+	db           *sql.DB
+	store        media.Store
+	maxAudioSize int64
+	maxImageSize int64
+}
+
+// AudioClip is the pronunciation audio of a word, or of its example sentence
+// at position Example
+type AudioClip struct {
+	Example     *int   `json:"example,omitempty"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// MediaFile is stored media opened for reading. The caller closes Content.
+type MediaFile struct {
+	Hash        string
+	ContentType string
+	Size        int64
+	CreatedAt   time.Time
+	Content     io.ReadSeekCloser
+}
+
+func NewMediaService(db *sql.DB, store media.Store) *MediaService {
+	return &MediaService{
+		db:           db,
+		store:        store,
+		maxAudioSize: DefaultMaxAudioSize,
+		maxImageSize: DefaultMaxImageSize,
+		instrumented: instrumented{observer: nopObserver{}},
+	}
+}
+
+// SetMaxAudioSize overrides the largest audio clip accepted, in bytes
+func (s *MediaService) SetMaxAudioSize(size int64) {
+	s.maxAudioSize = size
+}
+
+// AttachAudio stores the audio read from r as the pronunciation of a word, or
+// of its example sentence at position example, replacing any earlier clip.
+// The format is detected from the content.
+func (s *MediaService) AttachAudio(wordID int, example *int, r io.Reader) (*AudioClip, error) {
+	defer timeQuery(s.observer, "MediaService.AttachAudio")()
+
+	data, err := readLimited(r, s.maxAudioSize)
+	if err != nil {
+		return nil, err
+	}
+	contentType, ok := media.DetectAudio(data)
+	if !ok {
+		return nil, apperrors.New(apperrors.CodeUnsupportedMediaFormat).
+			WithData(map[string]interface{}{"allowed": media.AudioTypes})
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := requireActive(tx, reference{"id", "words", wordID, apperrors.CodeWordNotFound}); err != nil {
+		return nil, err
+	}
+	var exampleID *int
+	if example != nil {
+		if exampleID, err = findExample(tx, wordID, *example); err != nil {
+			return nil, err
+		}
+	}
+
+	hash, err := s.storeMedia(tx, contentType, data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(
+		"DELETE FROM word_audio WHERE word_id = ? AND IFNULL(example_id, -1) = IFNULL(?, -1)",
+		wordID, exampleID,
+	); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO word_audio (word_id, example_id, media_hash) VALUES (?, ?, ?)",
+		wordID, exampleID, hash,
+	); err != nil {
+		return nil, translateDBError(tx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &AudioClip{
+		Example:     example,
+		URL:         audioURL(wordID, example),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}, nil
+}
+
+// OpenAudio opens the pronunciation audio of a word or of one of its example
+// sentences. It returns nil if none was uploaded or the word is in the trash.
+func (s *MediaService) OpenAudio(wordID int, example *int) (*MediaFile, error) {
+	defer timeQuery(s.observer, "MediaService.OpenAudio")()
+
+	var file MediaFile
+	err := s.db.QueryRow(`
+		SELECT m.hash, m.content_type, m.size, a.created_at
+		FROM word_audio a
+		JOIN media m ON m.hash = a.media_hash
+		JOIN words w ON w.id = a.word_id
+		LEFT JOIN word_examples e ON e.id = a.example_id
+		WHERE a.word_id = ? AND IFNULL(e.position, -1) = IFNULL(?, -1) AND w.deleted_at IS NULL`,
+		wordID, example,
+	).Scan(&file.Hash, &file.ContentType, &file.Size, &file.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file.Content, err = s.store.Open(file.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open media %s: %w", file.Hash, err)
+	}
+	return &file, nil
+}
+
+// storeMedia records content in the media table and writes it to the store,
+// unless identical content is already there, and returns its hash
+func (s *MediaService) storeMedia(q queryer, contentType string, data []byte) (string, error) {
+	hash := media.Hash(data)
+	inserted, err := affectsRow(q.Exec(
+		"INSERT INTO media (hash, content_type, size) VALUES (?, ?, ?) ON CONFLICT (hash) DO NOTHING",
+		hash, contentType, len(data),
+	))
+	if err != nil || !inserted {
+		return hash, err
+	}
+	return hash, s.store.Put(hash, bytes.NewReader(data))
+}
+
+// readLimited reads all of r, failing with MEDIA_TOO_LARGE past max bytes
+func readLimited(r io.Reader, max int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, apperrors.New(apperrors.CodeMediaTooLarge).
+			WithData(map[string]int64{"max_bytes": max})
+	}
+	return data, nil
+}
+
+// findExample returns the id of the word's example sentence at position, or
+// a not-found error
+func findExample(q queryer, wordID, position int) (*int, error) {
+	var id int
+	err := q.QueryRow(
+		"SELECT id FROM word_examples WHERE word_id = ? AND position = ? ORDER BY id LIMIT 1",
+		wordID, position,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.CodeExampleNotFound).
+			WithData(map[string]interface{}{"field": "example", "value": position})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// loadAudio lists the audio clips of a word, identifying those of example
+// sentences by the sentence's current position
+func loadAudio(q queryer, wordID int) ([]AudioClip, error) {
+	rows, err := q.Query(`
+		SELECT e.position, m.content_type, m.size
+		FROM word_audio a
+		JOIN media m ON m.hash = a.media_hash
+		LEFT JOIN word_examples e ON e.id = a.example_id
+		WHERE a.word_id = ?
+		ORDER BY IFNULL(e.position, -1)`, wordID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clips []AudioClip
+	for rows.Next() {
+		var clip AudioClip
+		var example sql.NullInt64
+		if err := rows.Scan(&example, &clip.ContentType, &clip.Size); err != nil {
+			return nil, err
+		}
+		clip.Example = nullIntPtr(example)
+		clip.URL = audioURL(wordID, clip.Example)
+		clips = append(clips, clip)
+	}
+	return clips, rows.Err()
+}
+
+// audioURL is where the API serves the audio of a word or example
+func audioURL(wordID int, example *int) string {
+	if example == nil {
+		return fmt.Sprintf("/api/words/%d/audio", wordID)
+	}
+	return fmt.Sprintf("/api/words/%d/audio?example=%d", wordID, *example)
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+	"lang-portal/internal/media"
+)
+
+func TestAttachAudio(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts)
+		VALUES (1, 'la', 'amare', 'to love', '{}'), (2, 'la', 'videre', 'to see', '{}');
+		INSERT INTO word_examples (word_id, sentence, position) VALUES (1, 'Vivamus atque amemus', 0);
+	`)
+	assert.NoError(t, err)
+
+	service := NewMediaService(db, media.NewFileStore(t.TempDir()))
+	service.SetMaxAudioSize(64)
+	clip := []byte("OggS pronunciation")
+
+	audio, err := service.AttachAudio(1, nil, bytes.NewReader(clip))
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/words/1/audio", audio.URL)
+	assert.Equal(t, media.AudioOgg, audio.ContentType)
+
+	// The same content attached elsewhere is stored once
+	example := 0
+	_, err = service.AttachAudio(1, &example, bytes.NewReader(clip))
+	assert.NoError(t, err)
+	_, err = service.AttachAudio(2, nil, bytes.NewReader(clip))
+	assert.NoError(t, err)
+	var stored int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM media").Scan(&stored))
+	assert.Equal(t, 1, stored)
+
+	file, err := service.OpenAudio(1, &example)
+	if assert.NoError(t, err) && assert.NotNil(t, file) {
+		content, _ := io.ReadAll(file.Content)
+		file.Content.Close()
+		assert.Equal(t, clip, content)
+		assert.Equal(t, media.Hash(clip), file.Hash)
+	}
+
+	word, err := NewWordService(db).GetWordByID(1)
+	assert.NoError(t, err)
+	if assert.Len(t, word.Audio, 2) {
+		assert.Equal(t, "/api/words/1/audio?example=0", word.Audio[1].URL)
+	}
+
+	missing := 3
+	_, err = service.AttachAudio(1, &missing, bytes.NewReader(clip))
+	assertAppError(t, err, apperrors.CodeExampleNotFound, http.StatusNotFound)
+
+	_, err = service.AttachAudio(99, nil, bytes.NewReader(clip))
+	assertAppError(t, err, apperrors.CodeWordNotFound, http.StatusNotFound)
+
+	_, err = service.AttachAudio(1, nil, bytes.NewReader([]byte("plain text")))
+	assertAppError(t, err, apperrors.CodeUnsupportedMediaFormat, http.StatusUnsupportedMediaType)
+
+	_, err = service.AttachAudio(1, nil, bytes.NewReader(append([]byte("OggS"), make([]byte, 64)...)))
+	assertAppError(t, err, apperrors.CodeMediaTooLarge, http.StatusRequestEntityTooLarge)
+
+	file, err = service.OpenAudio(2, &example)
+	assert.NoError(t, err)
+	assert.Nil(t, file)
+}
+
+func TestExampleAudioFollowsItsSentence(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	words := NewWordService(db)
+	word, err := words.CreateWord(nil, WordInput{Term: "amare", Translation: "to love", Examples: []Example{
+		{Sentence: "Vivamus atque amemus"},
+		{Sentence: "Odi et amo"},
+	}})
+	assert.NoError(t, err)
+
+	service := NewMediaService(db, media.NewFileStore(t.TempDir()))
+	second := 1
+	_, err = service.AttachAudio(word.ID, &second, bytes.NewReader([]byte("OggS odi et amo")))
+	assert.NoError(t, err)
+
+	// Moving the sentence to the front moves its clip with it
+	_, err = words.UpdateWord(nil, word.ID, WordInput{Term: "amare", Translation: "to love", Examples: []Example{
+		{Sentence: "Odi et amo"},
+		{Sentence: "Amor vincit omnia"},
+	}})
+	assert.NoError(t, err)
+	word, err = words.GetWordByID(word.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, word.Audio, 1) {
+		assert.Equal(t, 0, *word.Audio[0].Example)
+	}
+	file, err := service.OpenAudio(word.ID, &second)
+	assert.NoError(t, err)
+	assert.Nil(t, file)
+
+	// Removing the sentence removes its clip
+	_, err = words.UpdateWord(nil, word.ID, WordInput{Term: "amare", Translation: "to love", Examples: []Example{
+		{Sentence: "Amor vincit omnia"},
+	}})
+	assert.NoError(t, err)
+	word, err = words.GetWordByID(word.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, word.Audio)
+}
+
+func TestAttachImage(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("INSERT INTO words (id, language_code, term, translation, parts) VALUES (1, 'la', 'canis', 'dog', '{}')")
+	assert.NoError(t, err)
+
+	service := NewMediaService(db, media.NewFileStore(t.TempDir()))
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 400, 300))))
+
+	picture, err := service.AttachImage(1, bytes.NewReader(buf.Bytes()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 400, picture.Width)
+	assert.Len(t, picture.URLs, 4)
+	// Only the thumbnail is smaller than the upload
+	assert.Equal(t, picture.URLs[media.VariantOriginal], picture.URLs[media.VariantMedium])
+	assert.NotEqual(t, picture.URLs[media.VariantOriginal], picture.URLs[media.VariantThumb])
+
+	hash := picture.URLs[media.VariantThumb][len("/api/media/"):]
+	file, err := service.OpenMedia(hash)
+	if assert.NoError(t, err) && assert.NotNil(t, file) {
+		thumb, _, err := image.DecodeConfig(file.Content)
+		file.Content.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, 200, thumb.Width)
+		assert.Equal(t, 150, thumb.Height)
+	}
+
+	words, err := NewWordService(db).GetWords(1, 10, WordFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, words.Items[0].Images, 1) {
+		assert.Equal(t, *picture, words.Items[0].Images[0])
+	}
+
+	_, err = service.AttachImage(1, bytes.NewReader([]byte("not an image")))
+	assertAppError(t, err, apperrors.CodeUnsupportedMediaFormat, http.StatusUnsupportedMediaType)
+
+	deleted, err := service.DeleteImage(1, picture.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = service.DeleteImage(1, picture.ID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+
+	word, err := NewWordService(db).GetWordByID(1)
+	assert.NoError(t, err)
+	assert.Empty(t, word.Images)
+}
@@ -0,0 +1,38 @@
+package service
+
+import "time"
+
+// Observer receives instrumentation events from the services
+type Observer interface {
+	ObserveQuery(method string, duration time.Duration)
+	SessionCreated()
+	ReviewRecorded(correct bool)
+}
+
+// instrumented is embedded by every service to hold the Observer its
+// methods report to
+type instrumented struct {
+	observer Observer
+}
+
+// SetObserver registers an observer for query timings and domain events
+func (i *instrumented) SetObserver(o Observer) {
+	i.observer = o
+}
+
+// nopObserver discards all events; it is used until SetObserver is called
+type nopObserver struct{}
+
+func (nopObserver) ObserveQuery(string, time.Duration) {}
+func (nopObserver) SessionCreated()                    {}
+func (nopObserver) ReviewRecorded(bool)                {}
+
+// timeQuery starts timing a service method; call the returned func when done
+//
+//	defer timeQuery(s.observer, "WordService.GetWords")()
+func timeQuery(o Observer, method string) func() {
+	start := time.Now()
+	return func() {
+		o.ObserveQuery(method, time.Since(start))
+	}
+}
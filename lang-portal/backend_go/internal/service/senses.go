@@ -0,0 +1,179 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Sense is one meaning of a word, given as a gloss in the learner's language.
+// Senses are ordered by priority, lowest first, and the first one is the
+// word's translation.
+type Sense struct {
+	Gloss    string `json:"gloss"`
+	Priority int    `json:"priority"`
+}
+
+// Example is a sentence using a word, with its translation and the work it is
+// quoted from
+type Example struct {
+	Sentence    string  `json:"sentence"`
+	Translation *string `json:"translation,omitempty"`
+	Source      *string `json:"source,omitempty"`
+}
+
+// sensesOrDefault returns the senses to store for input, ordered by priority.
+// Without senses the translation is the only one.
+func sensesOrDefault(input WordInput) []Sense {
+	if len(input.Senses) == 0 {
+		return []Sense{{Gloss: input.Translation}}
+	}
+	senses := make([]Sense, len(input.Senses))
+	copy(senses, input.Senses)
+	sort.SliceStable(senses, func(i, j int) bool {
+		return senses[i].Priority < senses[j].Priority
+	})
+	return senses
+}
+
+// loadSenses returns the senses of a word, ordered by priority
+func loadSenses(q queryer, wordID int) ([]Sense, error) {
+	rows, err := q.Query(
+		"SELECT gloss, priority FROM word_senses WHERE word_id = ? ORDER BY priority, id",
+		wordID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	senses := []Sense{}
+	for rows.Next() {
+		var s Sense
+		if err := rows.Scan(&s.Gloss, &s.Priority); err != nil {
+			return nil, err
+		}
+		senses = append(senses, s)
+	}
+	return senses, rows.Err()
+}
+
+// loadExamples returns the example sentences of a word in their stored order
+func loadExamples(q queryer, wordID int) ([]Example, error) {
+	rows, err := q.Query(
+		"SELECT sentence, translation, source FROM word_examples WHERE word_id = ? ORDER BY position, id",
+		wordID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	examples := []Example{}
+	for rows.Next() {
+		var e Example
+		if err := rows.Scan(&e.Sentence, &e.Translation, &e.Source); err != nil {
+			return nil, err
+		}
+		examples = append(examples, e)
+	}
+	return examples, rows.Err()
+}
+
+// replaceSenses and replaceExamples overwrite the senses and examples of a
+// word. They must run in the transaction that writes the word.
+func replaceSenses(q queryer, wordID int, senses []Sense) error {
+	if _, err := q.Exec("DELETE FROM word_senses WHERE word_id = ?", wordID); err != nil {
+		return err
+	}
+	for _, s := range senses {
+		_, err := q.Exec(
+			"INSERT INTO word_senses (word_id, gloss, priority) VALUES (?, ?, ?)",
+			wordID, s.Gloss, s.Priority,
+		)
+		if err != nil {
+			return translateDBError(q, err)
+		}
+	}
+	return nil
+}
+
+// replaceExamples keeps the row of every sentence still present, so that its
+// audio follows it to its new position; the rows of removed sentences are
+// deleted along with their audio.
+func replaceExamples(q queryer, wordID int, examples []Example) error {
+	rows, err := q.Query("SELECT id, sentence FROM word_examples WHERE word_id = ? ORDER BY position, id", wordID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	existing := map[string][]int{}
+	for rows.Next() {
+		var id int
+		var sentence string
+		if err := rows.Scan(&id, &sentence); err != nil {
+			return err
+		}
+		existing[sentence] = append(existing[sentence], id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for i, e := range examples {
+		if ids := existing[e.Sentence]; len(ids) > 0 {
+			existing[e.Sentence] = ids[1:]
+			_, err := q.Exec(
+				"UPDATE word_examples SET translation = ?, source = ?, position = ? WHERE id = ?",
+				e.Translation, e.Source, i, ids[0],
+			)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := q.Exec(
+			"INSERT INTO word_examples (word_id, sentence, translation, source, position) VALUES (?, ?, ?, ?, ?)",
+			wordID, e.Sentence, e.Translation, e.Source, i,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, ids := range existing {
+		for _, id := range ids {
+			if _, err := q.Exec("DELETE FROM word_examples WHERE id = ?", id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeAnswer folds the differences that should not make an answer wrong:
+// case, Unicode composition, surrounding punctuation and repeated spaces
+func normalizeAnswer(s string) string {
+	s = norm.NFC.String(strings.ToLower(s))
+	s = strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// matchAnswer returns the gloss among senses that answer matches, or false
+// if it matches none
+func matchAnswer(senses []Sense, answer string) (string, bool) {
+	normalized := normalizeAnswer(answer)
+	if normalized == "" {
+		return "", false
+	}
+	for _, s := range senses {
+		if normalizeAnswer(s.Gloss) == normalized {
+			return s.Gloss, true
+		}
+	}
+	return "", false
+}
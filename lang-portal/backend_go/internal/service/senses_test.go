@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordSensesAndExamples(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	words := NewWordService(db)
+	source := "Catullus 5.1"
+	word, err := words.CreateWord(nil, WordInput{
+		Term: "amare",
+		Senses: []Sense{
+			{Gloss: "to like", Priority: 1},
+			{Gloss: "to love", Priority: 0},
+		},
+		Examples: []Example{
+			{Sentence: "Vivamus, mea Lesbia, atque amemus", Source: &source},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "to love", word.Translation, "the first sense is the translation")
+	assert.Equal(t, []Sense{{"to love", 0}, {"to like", 1}}, word.Senses)
+	if assert.Len(t, word.Examples, 1) {
+		assert.Equal(t, "Catullus 5.1", *word.Examples[0].Source)
+		assert.Nil(t, word.Examples[0].Translation)
+	}
+
+	// Without senses the translation replaces them
+	word, err = words.UpdateWord(nil, word.ID, WordInput{Term: "amare", Translation: "to be fond of"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Sense{{"to be fond of", 0}}, word.Senses)
+	assert.Empty(t, word.Examples)
+
+	// Reverting the update brings the senses and examples back
+	entries, err := NewAuditService(db).ListEntries(AuditFilter{Entity: AuditEntityWord, EntityID: word.ID})
+	assert.NoError(t, err)
+	_, err = NewAuditService(db).Revert(System, entries[0].ID)
+	assert.NoError(t, err)
+	word, err = words.GetWordByID(word.ID)
+	assert.NoError(t, err)
+	assert.Len(t, word.Senses, 2)
+	assert.Len(t, word.Examples, 1)
+}
+
+func TestAddAnswerReviewAcceptsAnyGloss(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	word, err := NewWordService(db).CreateWord(nil, WordInput{
+		Term:   "amare",
+		Senses: []Sense{{Gloss: "to love"}, {Gloss: "to like", Priority: 1}},
+	})
+	assert.NoError(t, err)
+	group, err := NewGroupService(db).CreateGroup(nil, "Verbs", "", nil)
+	assert.NoError(t, err)
+
+	service := NewStudyService(db)
+	session, err := service.CreateStudySession(nil, group.ID, nil)
+	assert.NoError(t, err)
+
+	for answer, correct := range map[string]bool{
+		"to love":     true,
+		"  To Like! ": true,
+		"to hate":     false,
+		"":            false,
+	} {
+		review, err := service.AddAnswerReview(System, session.ID, word.ID, answer)
+		if assert.NoError(t, err) {
+			assert.Equal(t, correct, review.Correct, "answer %q", answer)
+			assert.Equal(t, []string{"to love", "to like"}, review.Accepted)
+		}
+	}
+}
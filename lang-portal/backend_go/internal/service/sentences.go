@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	apperrors "lang-portal/internal/errors"
+	"lang-portal/internal/llm"
+)
+
+// SentenceService generates example sentences for words with a language
+// model, caching the results
+type SentenceService struct {
+	instrumented
+
+	db        *sql.DB
+	generator llm.Generator
+}
+
+// SentenceOptions selects the sentences generated for a word. The sentences
+// use only vocabulary from GroupID. Refresh bypasses the cache.
+type SentenceOptions struct {
+	GroupID    int
+	Count      int
+	Difficulty string
+	Refresh    bool
+}
+
+// GeneratedSentence is an example sentence written by a model. UnknownWords
+// lists the words of Text that do not look like forms of the word or of the
+// group's vocabulary, which models occasionally slip in.
+type GeneratedSentence struct {
+	Text         string   `json:"text"`
+	Translation  string   `json:"translation"`
+	Difficulty   string   `json:"difficulty"`
+	UnknownWords []string `json:"unknown_words,omitempty"`
+}
+
+// SentenceSet is the result of a generation request. Cached reports whether
+// it was served from the cache, generated at CreatedAt.
+type SentenceSet struct {
+	WordID     int                 `json:"word_id"`
+	GroupID    int                 `json:"group_id"`
+	Model      string              `json:"model"`
+	Difficulty string              `json:"difficulty"`
+	Cached     bool                `json:"cached"`
+	CreatedAt  time.Time           `json:"created_at"`
+	Sentences  []GeneratedSentence `json:"sentences"`
+}
+
+func NewSentenceService(db *sql.DB, generator llm.Generator) *SentenceService {
+	return &SentenceService{db: db, instrumented: instrumented{observer: nopObserver{}}, generator: generator}
+}
+
+// GenerateSentences returns example sentences using a word and the
+// vocabulary of a group of the same language. Results are cached per model
+// and request, so asking again returns the same sentences until the group's
+// words change or Refresh is set.
+func (s *SentenceService) GenerateSentences(ctx context.Context, wordID int, opts SentenceOptions) (*SentenceSet, error) {
+	defer timeQuery(s.observer, "SentenceService.GenerateSentences")()
+
+	err := requireActive(s.db,
+		reference{"id", "words", wordID, apperrors.CodeWordNotFound},
+		reference{"group_id", "groups", opts.GroupID, apperrors.CodeGroupNotFound},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSameLanguage(s.db, wordID, opts.GroupID); err != nil {
+		return nil, err
+	}
+	req, err := s.sentenceRequest(wordID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.cacheKey(req)
+	if err != nil {
+		return nil, err
+	}
+	set := &SentenceSet{
+		WordID:     wordID,
+		GroupID:    opts.GroupID,
+		Model:      s.generator.Model(),
+		Difficulty: opts.Difficulty,
+	}
+	if !opts.Refresh {
+		var sentences string
+		err := s.db.QueryRow("SELECT sentences, created_at FROM generated_sentences WHERE cache_key = ?", key).
+			Scan(&sentences, &set.CreatedAt)
+		if err == nil {
+			set.Cached = true
+			return set, json.Unmarshal([]byte(sentences), &set.Sentences)
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
+	generated, err := s.generator.GenerateSentences(ctx, req)
+	if err != nil {
+		return nil, apperrors.New(apperrors.CodeGenerationFailed).WithInternal(err)
+	}
+	set.Sentences = checkSentences(req, generated)
+	if len(set.Sentences) == 0 {
+		return nil, apperrors.New(apperrors.CodeGenerationFailed).WithInternal(llm.ErrInvalidResponse)
+	}
+
+	data, err := json.Marshal(set.Sentences)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.QueryRow(`
+		INSERT INTO generated_sentences (cache_key, word_id, group_id, model, difficulty, sentences)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (cache_key) DO UPDATE SET sentences = excluded.sentences, created_at = CURRENT_TIMESTAMP
+		RETURNING created_at`,
+		key, wordID, opts.GroupID, set.Model, opts.Difficulty, string(data),
+	).Scan(&set.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// sentenceRequest gathers the word and the rest of the group's vocabulary
+func (s *SentenceService) sentenceRequest(wordID int, opts SentenceOptions) (llm.SentenceRequest, error) {
+	req := llm.SentenceRequest{Difficulty: opts.Difficulty, Count: opts.Count, Vocabulary: []llm.Term{}}
+	err := s.db.QueryRow("SELECT language_code, term, translation FROM words WHERE id = ?", wordID).
+		Scan(&req.Language, &req.Word.Term, &req.Word.Translation)
+	if err != nil {
+		return req, err
+	}
+
+	condition, args, err := groupWordsCondition(s.db, opts.GroupID)
+	if err != nil {
+		return req, err
+	}
+	words, err := loadGroupWords(s.db, condition, args...)
+	if err != nil {
+		return req, err
+	}
+	for _, w := range words {
+		if w.ID != wordID {
+			req.Vocabulary = append(req.Vocabulary, llm.Term{Term: w.Term, Translation: w.Translation})
+		}
+	}
+	return req, nil
+}
+
+// cacheKey hashes the model and the request
+func (s *SentenceService) cacheKey(req llm.SentenceRequest) (string, error) {
+	data, err := json.Marshal(struct {
+		Model   string              `json:"model"`
+		Request llm.SentenceRequest `json:"request"`
+	}{s.generator.Model(), req})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkSentences drops empty sentences, caps them at the requested count and
+// flags the words of each that are not in the vocabulary
+func checkSentences(req llm.SentenceRequest, generated []llm.Sentence) []GeneratedSentence {
+	var vocabulary []string
+	for _, t := range append([]llm.Term{req.Word}, req.Vocabulary...) {
+		vocabulary = append(vocabulary, splitWords(t.Term)...)
+	}
+
+	sentences := []GeneratedSentence{}
+	for _, g := range generated {
+		if strings.TrimSpace(g.Text) == "" || len(sentences) == req.Count {
+			continue
+		}
+		sentence := GeneratedSentence{
+			Text:        strings.TrimSpace(g.Text),
+			Translation: strings.TrimSpace(g.Translation),
+			Difficulty:  g.Difficulty,
+		}
+		for _, token := range splitWords(sentence.Text) {
+			if !isFormOfAny(token, vocabulary) {
+				sentence.UnknownWords = append(sentence.UnknownWords, token)
+			}
+		}
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
+
+// splitWords returns the words of a text, folded with foldWord
+func splitWords(text string) []string {
+	return strings.FieldsFunc(foldWord(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// foldWord lowercases a text and strips its diacritics, so that "Rōma" and
+// "roma" compare equal
+func foldWord(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFC.String(b.String())
+}
+
+// isFormOf reports whether a folded token looks like an inflected form of a
+// folded word: words of up to three letters must match exactly, longer ones
+// only in their stem, the word without up to three letters of its ending
+func isFormOf(token, word string) bool {
+	runes := []rune(word)
+	if len(runes) <= 3 {
+		return token == word
+	}
+	stem := string(runes[:max(3, len(runes)-3)])
+	return strings.HasPrefix(token, stem)
+}
+
+// isFormOfAny reports whether a folded token is a form of one of words
+func isFormOfAny(token string, words []string) bool {
+	for _, w := range words {
+		if isFormOf(token, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+	"lang-portal/internal/llm"
+)
+
+// countingGenerator wraps a Generator, counting its calls
+type countingGenerator struct {
+	llm.Generator
+	calls int
+}
+
+func (g *countingGenerator) GenerateSentences(ctx context.Context, req llm.SentenceRequest) ([]llm.Sentence, error) {
+	g.calls++
+	return g.Generator.GenerateSentences(ctx, req)
+}
+
+// scriptedGenerator returns fixed sentences or an error
+type scriptedGenerator struct {
+	sentences []llm.Sentence
+	err       error
+}
+
+func (g scriptedGenerator) Model() string { return "scripted" }
+
+func (g scriptedGenerator) GenerateSentences(context.Context, llm.SentenceRequest) ([]llm.Sentence, error) {
+	return g.sentences, g.err
+}
+
+func setupSentenceWords(t *testing.T) *GroupService {
+	db := setupTestDB(t)
+	t.Cleanup(func() { db.Close() })
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES
+			(1, 'la', 'amare', 'to love', '{}'),
+			(2, 'la', 'puella', 'girl', '{}'),
+			(3, 'la', 'nauta', 'sailor', '{}'),
+			(4, 'ja', '猫', 'cat', '{}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Chapter 1');
+		INSERT INTO words_groups (word_id, group_id) VALUES (1, 1), (2, 1);
+	`)
+	assert.NoError(t, err)
+	return NewGroupService(db)
+}
+
+func TestGenerateSentencesCachesResults(t *testing.T) {
+	groups := setupSentenceWords(t)
+	generator := &countingGenerator{Generator: llm.Fake{}}
+	sentences := NewSentenceService(groups.db, generator)
+	ctx := context.Background()
+	opts := SentenceOptions{GroupID: 1, Count: 2, Difficulty: llm.DifficultyEasy}
+
+	set, err := sentences.GenerateSentences(ctx, 1, opts)
+	assert.NoError(t, err)
+	assert.False(t, set.Cached)
+	assert.Equal(t, "fake", set.Model)
+	assert.Equal(t, []GeneratedSentence{
+		{Text: "Amare puella.", Translation: "To love girl.", Difficulty: llm.DifficultyEasy},
+		{Text: "Amare puella.", Translation: "To love girl.", Difficulty: llm.DifficultyEasy},
+	}, set.Sentences)
+
+	cached, err := sentences.GenerateSentences(ctx, 1, opts)
+	assert.NoError(t, err)
+	assert.True(t, cached.Cached)
+	assert.Equal(t, set.Sentences, cached.Sentences)
+	assert.Equal(t, 1, generator.calls)
+
+	opts.Refresh = true
+	_, err = sentences.GenerateSentences(ctx, 1, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, generator.calls)
+
+	// Changing the group's vocabulary invalidates the cache
+	opts.Refresh = false
+	assert.NoError(t, groups.AddWordToGroup(System, 3, 1))
+	changed, err := sentences.GenerateSentences(ctx, 1, opts)
+	assert.NoError(t, err)
+	assert.False(t, changed.Cached)
+	assert.Equal(t, "Amare nauta.", changed.Sentences[0].Text)
+}
+
+func TestGenerateSentencesChecksInput(t *testing.T) {
+	groups := setupSentenceWords(t)
+	sentences := NewSentenceService(groups.db, llm.Fake{})
+	ctx := context.Background()
+
+	_, err := sentences.GenerateSentences(ctx, 99, SentenceOptions{GroupID: 1, Count: 1})
+	assertAppError(t, err, apperrors.CodeWordNotFound, http.StatusNotFound)
+	_, err = sentences.GenerateSentences(ctx, 1, SentenceOptions{GroupID: 99, Count: 1})
+	assertAppError(t, err, apperrors.CodeGroupNotFound, http.StatusNotFound)
+	_, err = sentences.GenerateSentences(ctx, 4, SentenceOptions{GroupID: 1, Count: 1})
+	assertAppError(t, err, apperrors.CodeLanguageMismatch, http.StatusUnprocessableEntity)
+}
+
+func TestGenerateSentencesFlagsUnknownWords(t *testing.T) {
+	groups := setupSentenceWords(t)
+	generator := scriptedGenerator{sentences: []llm.Sentence{
+		{Text: "Puellae amant.", Translation: "The girls love."},
+		{Text: " "},
+		{Text: "Puella rosam amat!", Translation: "The girl loves the rose!"},
+		{Text: "Amō.", Translation: "I love."},
+	}}
+	sentences := NewSentenceService(groups.db, generator)
+
+	set, err := sentences.GenerateSentences(context.Background(), 1, SentenceOptions{GroupID: 1, Count: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, set.Sentences, 2, "blank sentences are dropped and the rest capped at the count") {
+		assert.Empty(t, set.Sentences[0].UnknownWords, "inflected forms of the vocabulary are known")
+		assert.Equal(t, []string{"rosam"}, set.Sentences[1].UnknownWords)
+	}
+
+	refresh := SentenceOptions{GroupID: 1, Count: 2, Refresh: true}
+	failing := NewSentenceService(groups.db, scriptedGenerator{err: errors.New("connection refused")})
+	_, err = failing.GenerateSentences(context.Background(), 1, refresh)
+	assertAppError(t, err, apperrors.CodeGenerationFailed, http.StatusBadGateway)
+
+	empty := NewSentenceService(groups.db, scriptedGenerator{})
+	_, err = empty.GenerateSentences(context.Background(), 1, refresh)
+	assertAppError(t, err, apperrors.CodeGenerationFailed, http.StatusBadGateway)
+}
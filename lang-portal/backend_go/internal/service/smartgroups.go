@@ -0,0 +1,153 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	apperrors "lang-portal/internal/errors"
+)
+
+// GroupQuery defines the members of a smart group: the words of the group's
+// language matching every criterion set. Membership is evaluated whenever the
+// group is read, so it follows the words' tags and review history.
+type GroupQuery struct {
+	// Tags the words must all carry
+	Tags []string `json:"tags,omitempty"`
+	// Parts maps keys of the words' parts to the value they must have, such
+	// as {"type": "noun", "declension": 3}
+	Parts map[string]interface{} `json:"parts,omitempty"`
+	// AccuracyBelow selects reviewed words whose share of correct reviews is
+	// lower, between 0 and 1
+	AccuracyBelow *float64 `json:"accuracy_below,omitempty"`
+	// MissedWithinDays selects words answered wrong in the last days
+	MissedWithinDays *int `json:"missed_within_days,omitempty"`
+}
+
+// partsKey matches the parts keys a query may filter on
+var partsKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validate rejects parts criteria that cannot be matched against a word
+func (q GroupQuery) validate() error {
+	var failures []map[string]string
+	for key, value := range q.Parts {
+		if !partsKey.MatchString(key) {
+			failures = append(failures, map[string]string{
+				"field":   "query.parts",
+				"tag":     "key",
+				"value":   key,
+				"message": "Parts keys may only contain letters, digits and underscores",
+			})
+			continue
+		}
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			failures = append(failures, map[string]string{
+				"field":   "query.parts." + key,
+				"tag":     "scalar",
+				"value":   fmt.Sprint(value),
+				"message": "Parts values must be a string, number or boolean",
+			})
+		}
+	}
+	if len(failures) > 0 {
+		return apperrors.New(apperrors.CodeValidationFailed).WithData(failures)
+	}
+	return nil
+}
+
+// condition returns a SQL expression on words w selecting the members of a
+// smart group of language, with its arguments
+func (q GroupQuery) condition(language string) (string, []interface{}) {
+	conditions := []string{"w.language_code = ?"}
+	args := []interface{}{language}
+
+	if len(q.Tags) > 0 {
+		tagged, tagArgs := taggedWords(q.Tags)
+		conditions = append(conditions, "w.id IN ("+tagged+")")
+		args = append(args, tagArgs...)
+	}
+	for key, value := range q.Parts {
+		conditions = append(conditions, "json_extract(w.parts, ?) = ?")
+		args = append(args, "$."+key, value)
+	}
+	if q.AccuracyBelow != nil {
+		conditions = append(conditions, `w.id IN (
+			SELECT word_id FROM word_review_items
+			GROUP BY word_id
+			HAVING AVG(correct) < ?)`)
+		args = append(args, *q.AccuracyBelow)
+	}
+	if q.MissedWithinDays != nil {
+		conditions = append(conditions, `w.id IN (
+			SELECT word_id FROM word_review_items
+			WHERE correct = 0 AND created_at >= datetime('now', ?))`)
+		args = append(args, fmt.Sprintf("-%d days", *q.MissedWithinDays))
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// groupWordsCondition returns a SQL expression on words w selecting the
+// members of a group, evaluating the query of smart groups
+func groupWordsCondition(q queryer, groupID int) (string, []interface{}, error) {
+	var language string
+	var query sql.NullString
+	err := q.QueryRow("SELECT language_code, query FROM groups WHERE id = ?", groupID).Scan(&language, &query)
+	if err != nil {
+		return "", nil, err
+	}
+	if !query.Valid {
+		return "w.id IN (SELECT word_id FROM words_groups WHERE group_id = ?)", []interface{}{groupID}, nil
+	}
+	gq, err := parseGroupQuery(query)
+	if err != nil {
+		return "", nil, err
+	}
+	condition, args := gq.condition(language)
+	return condition, args, nil
+}
+
+// parseGroupQuery decodes groups.query, which is null for static groups
+func parseGroupQuery(query sql.NullString) (*GroupQuery, error) {
+	if !query.Valid {
+		return nil, nil
+	}
+	var gq GroupQuery
+	if err := json.Unmarshal([]byte(query.String), &gq); err != nil {
+		return nil, fmt.Errorf("invalid group query: %w", err)
+	}
+	return &gq, nil
+}
+
+// groupQueryJSON encodes a smart group query for groups.query, or returns nil
+// for a static group
+func groupQueryJSON(query *GroupQuery) (interface{}, error) {
+	if query == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// requireStaticGroup rejects adding or removing words by hand in a smart
+// group
+func requireStaticGroup(q queryer, groupID int) error {
+	var smart bool
+	err := q.QueryRow("SELECT query IS NOT NULL FROM groups WHERE id = ?", groupID).Scan(&smart)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if smart {
+		return apperrors.New(apperrors.CodeSmartGroupReadOnly)
+	}
+	return nil
+}
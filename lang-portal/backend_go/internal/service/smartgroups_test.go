@@ -0,0 +1,85 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+)
+
+func TestSmartGroups(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts)
+		VALUES
+			(1, 'la', 'rex', 'king', '{"type":"noun","declension":3}'),
+			(2, 'la', 'corpus', 'body', '{"type":"noun","declension":3}'),
+			(3, 'la', 'puer', 'boy', '{"type":"noun","declension":2}'),
+			(4, 'la', 'amare', 'to love', '{"type":"verb","conjugation":1}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Lesson 1');
+		INSERT INTO study_sessions (id, group_id, created_at) VALUES (1, 1, datetime('now', '-10 days'));
+		-- rex: 1 of 4 correct, corpus: 3 of 4 correct, amare: missed recently
+		INSERT INTO word_review_items (word_id, study_session_id, correct, created_at)
+		VALUES
+			(1, 1, 1, datetime('now', '-10 days')),
+			(1, 1, 0, datetime('now', '-10 days')),
+			(1, 1, 0, datetime('now', '-10 days')),
+			(1, 1, 0, datetime('now', '-10 days')),
+			(2, 1, 1, datetime('now', '-10 days')),
+			(2, 1, 1, datetime('now', '-10 days')),
+			(2, 1, 1, datetime('now', '-10 days')),
+			(2, 1, 0, datetime('now', '-10 days')),
+			(4, 1, 0, datetime('now', '-1 day'));
+	`)
+	assert.NoError(t, err)
+
+	groups := NewGroupService(db)
+	below := 0.6
+	weak, err := groups.CreateGroup(nil, "Weak 3rd declension nouns", "", &GroupQuery{
+		Parts:         map[string]interface{}{"type": "noun", "declension": float64(3)},
+		AccuracyBelow: &below,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, weak.WordCount)
+
+	week := 7
+	missed, err := groups.CreateGroup(nil, "Missed this week", "", &GroupQuery{MissedWithinDays: &week})
+	assert.NoError(t, err)
+
+	withWords, err := groups.GetGroupByID(weak.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, withWords.Words, 1) {
+		assert.Equal(t, "rex", withWords.Words[0].Term)
+	}
+	assert.NotNil(t, withWords.Query)
+
+	// Membership follows the review history
+	_, err = db.Exec("INSERT INTO word_review_items (word_id, study_session_id, correct) VALUES (3, 1, 0)")
+	assert.NoError(t, err)
+	list, err := groups.GetGroups(GroupFilter{})
+	assert.NoError(t, err)
+	counts := map[string]int{}
+	for _, g := range list {
+		counts[g.Name] = g.WordCount
+	}
+	assert.Equal(t, map[string]int{"Lesson 1": 0, "Weak 3rd declension nouns": 1, "Missed this week": 2}, counts)
+
+	// Smart groups can be studied like any other
+	study := NewStudyService(db)
+	session, err := study.CreateStudySession(nil, missed.ID, nil)
+	assert.NoError(t, err)
+	words, err := study.GetSessionWords(System, session.ID)
+	assert.NoError(t, err)
+	assert.Len(t, words, 2)
+
+	err = groups.AddWordToGroup(System, 3, missed.ID)
+	assertAppError(t, err, apperrors.CodeSmartGroupReadOnly, http.StatusConflict)
+
+	_, err = groups.CreateGroup(nil, "Broken", "", &GroupQuery{
+		Parts: map[string]interface{}{"type') OR 1=1 --": "noun"},
+	})
+	assertAppError(t, err, apperrors.CodeValidationFailed, http.StatusBadRequest)
+}
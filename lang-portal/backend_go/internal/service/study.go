@@ -2,24 +2,40 @@ package service
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
+
+	apperrors "lang-portal/internal/errors"
 )
 
 type StudyService struct {
-	db *sql.DB
+	instrumented
+
+	db      *sql.DB
+	mastery MasteryConfig
+	events  *EventBus
 }
 
+// StudySession studies either a group or, when Tags is set, the words
+// carrying all of the tags. Language is empty for tag sessions across every
+// language. UserID is the learner who started it, if identified.
 type StudySession struct {
 	ID              int        `json:"id"`
-	GroupID         int        `json:"group_id"`
+	GroupID         int        `json:"group_id,omitempty"`
+	Language        string     `json:"language"`
 	CreatedAt       time.Time  `json:"created_at"`
 	StudyActivityID *int       `json:"study_activity_id,omitempty"`
-	GroupName       string     `json:"group_name"`
+	GroupName       string     `json:"group_name,omitempty"`
+	Tags            []string   `json:"tags,omitempty"`
+	UserID          *int       `json:"user_id,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
 }
 
 type StudyProgress struct {
-	TotalWordsStudied    int `json:"total_words_studied"`
-	TotalAvailableWords  int `json:"total_available_words"`
+	TotalWordsStudied   int                  `json:"total_words_studied"`
+	TotalWordsMastered  int                  `json:"total_words_mastered"`
+	TotalAvailableWords int                  `json:"total_available_words"`
+	WordsByLevel        map[MasteryLevel]int `json:"words_by_level"`
 }
 
 type QuickStats struct {
@@ -30,81 +46,158 @@ type QuickStats struct {
 }
 
 type WordReviewItem struct {
-	ID              int       `json:"id"`
-	WordID          int       `json:"word_id"`
-	StudySessionID  int       `json:"study_session_id"`
-	Correct         bool      `json:"correct"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID             int       `json:"id"`
+	WordID         int       `json:"word_id"`
+	StudySessionID int       `json:"study_session_id"`
+	Correct        bool      `json:"correct"`
+	CreatedAt      time.Time `json:"created_at"`
+	// Accepted lists the glosses an answer was checked against. It is only
+	// set on reviews recorded by AddAnswerReview.
+	Accepted []string `json:"accepted,omitempty"`
 }
 
 func NewStudyService(db *sql.DB) *StudyService {
-	return &StudyService{db: db}
+	return &StudyService{db: db, mastery: DefaultMasteryConfig(), instrumented: instrumented{observer: nopObserver{}}}
 }
 
-// GetLastStudySession retrieves the most recent study session
-func (s *StudyService) GetLastStudySession() (*StudySession, error) {
-	query := `
-		SELECT s.id, s.group_id, s.created_at, s.study_activity_id, g.name
-		FROM study_sessions s
-		JOIN groups g ON s.group_id = g.id
-		ORDER BY s.created_at DESC
-		LIMIT 1`
+// SetMasteryConfig overrides the thresholds used to classify words
+func (s *StudyService) SetMasteryConfig(cfg MasteryConfig) {
+	s.mastery = cfg
+}
+
+// SetEventBus publishes session and review events on bus
+func (s *StudyService) SetEventBus(bus *EventBus) {
+	s.events = bus
+}
 
+// sessionColumns are the columns scanned by scanSession, selected from
+// study_sessions s LEFT JOIN groups g
+const sessionColumns = `
+	s.id, IFNULL(s.group_id, 0), COALESCE(g.language_code, s.language_code, ''), s.created_at,
+	s.study_activity_id, IFNULL(g.name, ''), s.tags, s.user_id, s.completed_at`
+
+// scanSession scans a row selected with sessionColumns
+func scanSession(row interface{ Scan(...interface{}) error }) (*StudySession, error) {
 	var session StudySession
-	var studyActivityID sql.NullInt64
-	err := s.db.QueryRow(query).Scan(
+	var studyActivityID, userID sql.NullInt64
+	var tags sql.NullString
+	var completedAt sql.NullTime
+	err := row.Scan(
 		&session.ID,
 		&session.GroupID,
+		&session.Language,
 		&session.CreatedAt,
 		&studyActivityID,
 		&session.GroupName,
+		&tags,
+		&userID,
+		&completedAt,
 	)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
 	if err != nil {
 		return nil, err
 	}
-	if studyActivityID.Valid {
-		session.StudyActivityID = new(int)
-		*session.StudyActivityID = int(studyActivityID.Int64)
+	session.StudyActivityID = nullIntPtr(studyActivityID)
+	session.UserID = nullIntPtr(userID)
+	if completedAt.Valid {
+		session.CompletedAt = &completedAt.Time
+	}
+	if tags.Valid {
+		if err := json.Unmarshal([]byte(tags.String), &session.Tags); err != nil {
+			return nil, err
+		}
 	}
 	return &session, nil
 }
 
-// GetStudyProgress retrieves the overall study progress
-func (s *StudyService) GetStudyProgress() (*StudyProgress, error) {
+// GetLastStudySession retrieves the most recent study session, limited to
+// sessions on language unless it is empty
+func (s *StudyService) GetLastStudySession(language string) (*StudySession, error) {
+	defer timeQuery(s.observer, "StudyService.GetLastStudySession")()
+
 	query := `
-		SELECT 
-			(SELECT COUNT(DISTINCT word_id) FROM word_review_items) as studied,
-			(SELECT COUNT(*) FROM words) as total`
+		SELECT ` + sessionColumns + `
+		FROM study_sessions s
+		LEFT JOIN groups g ON s.group_id = g.id
+		WHERE ? = '' OR COALESCE(g.language_code, s.language_code) = ?
+		ORDER BY s.created_at DESC
+		LIMIT 1`
+
+	session, err := scanSession(s.db.QueryRow(query, language, language))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return session, err
+}
+
+// GetStudyProgress retrieves the overall study progress, limited to words of
+// language unless it is empty. A word only counts as studied once its review
+// history reaches the "reviewing" level.
+func (s *StudyService) GetStudyProgress(language string) (*StudyProgress, error) {
+	defer timeQuery(s.observer, "StudyService.GetStudyProgress")()
 
-	var progress StudyProgress
-	err := s.db.QueryRow(query).Scan(&progress.TotalWordsStudied, &progress.TotalAvailableWords)
+	progress := StudyProgress{
+		WordsByLevel: map[MasteryLevel]int{
+			MasteryNew:       0,
+			MasteryLearning:  0,
+			MasteryReviewing: 0,
+			MasteryMastered:  0,
+		},
+	}
+	err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM words WHERE deleted_at IS NULL AND (? = '' OR language_code = ?)",
+		language, language,
+	).Scan(&progress.TotalAvailableWords)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := loadReviewHistory(s.db, nil, language)
 	if err != nil {
 		return nil, err
 	}
+	for _, reviews := range history {
+		level := s.mastery.Level(reviews)
+		progress.WordsByLevel[level]++
+		if level.IsStudied() {
+			progress.TotalWordsStudied++
+		}
+		if level.IsLearned() {
+			progress.TotalWordsMastered++
+		}
+	}
+	progress.WordsByLevel[MasteryNew] = progress.TotalAvailableWords - len(history)
+
 	return &progress, nil
 }
 
-// GetQuickStats retrieves quick overview statistics
-func (s *StudyService) GetQuickStats() (*QuickStats, error) {
+// GetQuickStats retrieves quick overview statistics, limited to sessions on
+// language unless it is empty
+func (s *StudyService) GetQuickStats(language string) (*QuickStats, error) {
+	defer timeQuery(s.observer, "StudyService.GetQuickStats")()
+
 	query := `
-		WITH stats AS (
+		WITH sessions AS (
+			SELECT s.id, s.group_id, s.created_at
+			FROM study_sessions s
+			LEFT JOIN groups g ON g.id = s.group_id
+			WHERE ? = '' OR COALESCE(g.language_code, s.language_code) = ?
+		),
+		stats AS (
 			SELECT 
 				CAST(SUM(CASE WHEN correct = 1 THEN 1 ELSE 0 END) AS FLOAT) / 
 				CAST(COUNT(*) AS FLOAT) * 100 as success_rate,
 				COUNT(DISTINCT study_session_id) as total_sessions
 			FROM word_review_items
+			WHERE study_session_id IN (SELECT id FROM sessions)
 		),
 		active_groups AS (
 			SELECT COUNT(DISTINCT group_id) as active_groups
-			FROM study_sessions
+			FROM sessions
 			WHERE created_at >= datetime('now', '-30 days')
 		),
 		streak AS (
 			SELECT COUNT(DISTINCT date(created_at)) as streak_days
-			FROM study_sessions
+			FROM sessions
 			WHERE created_at >= datetime('now', '-30 days')
 		)
 		SELECT 
@@ -115,7 +208,7 @@ func (s *StudyService) GetQuickStats() (*QuickStats, error) {
 		FROM stats, active_groups, streak`
 
 	var stats QuickStats
-	err := s.db.QueryRow(query).Scan(
+	err := s.db.QueryRow(query, language, language).Scan(
 		&stats.SuccessRate,
 		&stats.TotalStudySessions,
 		&stats.TotalActiveGroups,
@@ -127,56 +220,272 @@ func (s *StudyService) GetQuickStats() (*QuickStats, error) {
 	return &stats, nil
 }
 
-// CreateStudySession creates a new study session
-func (s *StudyService) CreateStudySession(groupID int) (*StudySession, error) {
-	query := `
-		INSERT INTO study_sessions (group_id, created_at)
-		VALUES (?, datetime('now'))
-		RETURNING id, group_id, created_at`
+// CreateStudySession creates a new study session on a group for actor,
+// studied in the activity studyActivityID if it is not nil. Assignments
+// limited to an activity only count sessions in that activity.
+func (s *StudyService) CreateStudySession(actor *User, groupID int, studyActivityID *int) (*StudySession, error) {
+	defer timeQuery(s.observer, "StudyService.CreateStudySession")()
 
-	var session StudySession
-	err := s.db.QueryRow(query, groupID).Scan(
-		&session.ID,
-		&session.GroupID,
-		&session.CreatedAt,
-	)
+	err := requireActive(s.db, reference{"group_id", "groups", groupID, apperrors.CodeGroupNotFound})
+	if err != nil {
+		return nil, err
+	}
+
+	var id int
+	err = s.db.QueryRow(
+		`INSERT INTO study_sessions (group_id, study_activity_id, user_id, created_at)
+		VALUES (?, ?, ?, datetime('now')) RETURNING id`,
+		groupID, studyActivityID, actorID(actor),
+	).Scan(&id)
+	if err != nil {
+		return nil, translateDBError(s.db, err,
+			reference{"group_id", "groups", groupID, apperrors.CodeGroupNotFound},
+		)
+	}
+	return s.sessionCreated(id)
+}
+
+// CreateTagStudySession creates a new study session for actor on the words
+// carrying all of tags, limited to language unless it is empty. The words are
+// looked up when the session is studied, so words tagged later are included.
+func (s *StudyService) CreateTagStudySession(actor *User, tags []string, language string) (*StudySession, error) {
+	defer timeQuery(s.observer, "StudyService.CreateTagStudySession")()
+
+	var languageCode interface{}
+	if language != "" {
+		if err := requireLanguage(s.db, language); err != nil {
+			return nil, err
+		}
+		languageCode = language
+	}
+	tagsJSON, err := json.Marshal(normalizeTags(tags))
 	if err != nil {
 		return nil, err
 	}
 
-	// Get the group name
-	err = s.db.QueryRow("SELECT name FROM groups WHERE id = ?", groupID).Scan(&session.GroupName)
+	var id int
+	err = s.db.QueryRow(
+		"INSERT INTO study_sessions (tags, language_code, user_id, created_at) VALUES (?, ?, ?, datetime('now')) RETURNING id",
+		string(tagsJSON), languageCode, actorID(actor),
+	).Scan(&id)
 	if err != nil {
 		return nil, err
 	}
+	return s.sessionCreated(id)
+}
 
-	return &session, nil
+// sessionCreated loads a newly created session and reports it
+func (s *StudyService) sessionCreated(id int) (*StudySession, error) {
+	session, err := s.getSession(id)
+	if err != nil {
+		return nil, err
+	}
+	s.observer.SessionCreated()
+	s.events.Publish(Event{Type: EventSessionCreated, UserID: session.UserID, SessionID: id, Data: session})
+	return session, nil
+}
+
+func (s *StudyService) getSession(id int) (*StudySession, error) {
+	return scanSession(s.db.QueryRow(`
+		SELECT `+sessionColumns+`
+		FROM study_sessions s
+		LEFT JOIN groups g ON s.group_id = g.id
+		WHERE s.id = ?`, id))
+}
+
+// CompleteStudySession marks a session of actor as finished; no more
+// reviews can be added to it
+func (s *StudyService) CompleteStudySession(actor *User, id int) (*StudySession, error) {
+	defer timeQuery(s.observer, "StudyService.CompleteStudySession")()
+
+	if _, err := requireOpenSession(s.db, actor, id); err != nil {
+		return nil, err
+	}
+	completed, err := affectsRow(s.db.Exec(
+		"UPDATE study_sessions SET completed_at = datetime('now') WHERE id = ? AND completed_at IS NULL", id,
+	))
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, apperrors.New(apperrors.CodeSessionCompleted)
+	}
+
+	session, err := s.getSession(id)
+	if err != nil {
+		return nil, err
+	}
+	s.events.Publish(Event{Type: EventSessionCompleted, UserID: session.UserID, SessionID: id, Data: session})
+	return session, nil
 }
 
-// AddWordReview adds a word review item to a study session
-func (s *StudyService) AddWordReview(sessionID, wordID int, correct bool) (*WordReviewItem, error) {
+// requireSessionOwner returns the user of a session and whether it has been
+// completed. It fails with SESSION_NOT_FOUND if the session does not exist,
+// and unless actor started it or is System with UNAUTHORIZED for anonymous
+// actors and FORBIDDEN for others.
+func requireSessionOwner(q queryer, actor *User, id int) (*int, bool, error) {
+	var userID sql.NullInt64
+	var completed bool
+	err := q.QueryRow(
+		"SELECT user_id, completed_at IS NOT NULL FROM study_sessions WHERE id = ?", id,
+	).Scan(&userID, &completed)
+	if err == sql.ErrNoRows {
+		return nil, false, apperrors.New(apperrors.CodeSessionNotFound)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if actor == nil {
+		return nil, false, apperrors.New(apperrors.CodeUnauthorized)
+	}
+	if actor != System && (!userID.Valid || int(userID.Int64) != actor.ID) {
+		return nil, false, apperrors.New(apperrors.CodeForbidden)
+	}
+	return nullIntPtr(userID), completed, nil
+}
+
+// requireOpenSession returns the user of a session, failing unless actor
+// owns it and it has not been completed
+func requireOpenSession(q queryer, actor *User, id int) (*int, error) {
+	userID, completed, err := requireSessionOwner(q, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if completed {
+		return nil, apperrors.New(apperrors.CodeSessionCompleted)
+	}
+	return userID, nil
+}
+
+// GetSessionWords lists the words studied in a session of actor: the words
+// of its group, evaluated for smart groups, or those carrying its tags
+func (s *StudyService) GetSessionWords(actor *User, sessionID int) ([]GroupWord, error) {
+	defer timeQuery(s.observer, "StudyService.GetSessionWords")()
+
+	if _, _, err := requireSessionOwner(s.db, actor, sessionID); err != nil {
+		return nil, err
+	}
+	var groupID sql.NullInt64
+	var tags, language sql.NullString
+	err := s.db.QueryRow(
+		"SELECT group_id, tags, language_code FROM study_sessions WHERE id = ?", sessionID,
+	).Scan(&groupID, &tags, &language)
+	if err != nil {
+		return nil, err
+	}
+
+	var condition string
+	var args []interface{}
+	if groupID.Valid {
+		condition, args, err = groupWordsCondition(s.db, int(groupID.Int64))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var names []string
+		if err := json.Unmarshal([]byte(tags.String), &names); err != nil {
+			return nil, err
+		}
+		tagged, tagArgs := taggedWords(names)
+		condition, args = "w.id IN ("+tagged+")", tagArgs
+		if language.Valid {
+			condition += " AND w.language_code = ?"
+			args = append(args, language.String)
+		}
+	}
+	words, err := loadGroupWords(s.db, condition, args...)
+	if err != nil {
+		return nil, err
+	}
+	if words == nil {
+		words = []GroupWord{}
+	}
+	return words, nil
+}
+
+// AddWordReview adds a word review item to a study session of actor that has
+// not been completed
+func (s *StudyService) AddWordReview(actor *User, sessionID, wordID int, correct bool) (*WordReviewItem, error) {
+	defer timeQuery(s.observer, "StudyService.AddWordReview")()
+
+	review, userID, err := s.recordReview(s.db, actor, sessionID, wordID, correct)
+	if err != nil {
+		return nil, err
+	}
+	s.reviewAdded(review, userID)
+	return review, nil
+}
+
+// recordReview inserts a review through q and returns it with the session's
+// user
+func (s *StudyService) recordReview(q queryer, actor *User, sessionID, wordID int, correct bool) (*WordReviewItem, *int, error) {
+	userID, err := requireOpenSession(q, actor, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = requireActive(q, reference{"word_id", "words", wordID, apperrors.CodeWordNotFound})
+	if err != nil {
+		return nil, nil, err
+	}
+
 	query := `
 		INSERT INTO word_review_items (word_id, study_session_id, correct, created_at)
 		VALUES (?, ?, ?, datetime('now'))
 		RETURNING id, word_id, study_session_id, correct, created_at`
 
 	var review WordReviewItem
-	err := s.db.QueryRow(query, wordID, sessionID, correct).Scan(
+	err = q.QueryRow(query, wordID, sessionID, correct).Scan(
 		&review.ID,
 		&review.WordID,
 		&review.StudySessionID,
 		&review.Correct,
 		&review.CreatedAt,
 	)
+	if err != nil {
+		return nil, nil, translateDBError(q, err,
+			reference{"id", "study_sessions", sessionID, apperrors.CodeSessionNotFound},
+			reference{"word_id", "words", wordID, apperrors.CodeWordNotFound},
+		)
+	}
+	return &review, userID, nil
+}
+
+// reviewAdded reports a recorded review
+func (s *StudyService) reviewAdded(review *WordReviewItem, userID *int) {
+	s.observer.ReviewRecorded(review.Correct)
+	s.events.Publish(Event{Type: EventReviewAdded, UserID: userID, SessionID: review.StudySessionID, Data: review})
+}
+
+// AddAnswerReview checks answer against every gloss of the word and records
+// the review in a session of actor as correct if it matches any of them
+func (s *StudyService) AddAnswerReview(actor *User, sessionID, wordID int, answer string) (*WordReviewItem, error) {
+	defer timeQuery(s.observer, "StudyService.AddAnswerReview")()
+
+	senses, err := loadSenses(s.db, wordID)
 	if err != nil {
 		return nil, err
 	}
+	_, correct := matchAnswer(senses, answer)
 
-	return &review, nil
+	review, userID, err := s.recordReview(s.db, actor, sessionID, wordID, correct)
+	if err != nil {
+		return nil, err
+	}
+	for _, sense := range senses {
+		review.Accepted = append(review.Accepted, sense.Gloss)
+	}
+	s.reviewAdded(review, userID)
+	return review, nil
 }
 
-// GetSessionReviews retrieves all word reviews for a specific study session
-func (s *StudyService) GetSessionReviews(sessionID int) ([]WordReviewItem, error) {
+// GetSessionReviews retrieves all word reviews for a study session of actor
+func (s *StudyService) GetSessionReviews(actor *User, sessionID int) ([]WordReviewItem, error) {
+	defer timeQuery(s.observer, "StudyService.GetSessionReviews")()
+
+	if _, _, err := requireSessionOwner(s.db, actor, sessionID); err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, word_id, study_session_id, correct, created_at
 		FROM word_review_items
@@ -1,53 +1,29 @@
 package service
 
 import (
+	"context"
 	"database/sql"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	_ "github.com/mattn/go-sqlite3"
+	apperrors "lang-portal/internal/errors"
+	"lang-portal/internal/llm"
+	"lang-portal/internal/migrate"
 )
 
 func setupTestDB(t *testing.T) *sql.DB {
-	db, err := sql.Open("sqlite3", ":memory:")
+	db, err := sql.Open("sqlite3", ":memory:?_foreign_keys=on")
 	if err != nil {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
+	// Every connection to :memory: gets its own database
+	db.SetMaxOpenConns(1)
 
-	// Create tables
-	_, err = db.Exec(`
-		CREATE TABLE words (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			latin_word TEXT NOT NULL UNIQUE,
-			english_translation TEXT NOT NULL,
-			parts TEXT NOT NULL
-		);
-
-		CREATE TABLE groups (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL UNIQUE
-		);
-
-		CREATE TABLE study_sessions (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			group_id INTEGER NOT NULL,
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			study_activity_id INTEGER,
-			FOREIGN KEY (group_id) REFERENCES groups(id)
-		);
-
-		CREATE TABLE word_review_items (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			word_id INTEGER NOT NULL,
-			study_session_id INTEGER NOT NULL,
-			correct BOOLEAN NOT NULL,
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (word_id) REFERENCES words(id),
-			FOREIGN KEY (study_session_id) REFERENCES study_sessions(id)
-		);
-	`)
-	if err != nil {
+	// Create tables from the real migrations
+	if _, err := migrate.Apply(db, "../../db/migrations"); err != nil {
 		t.Fatalf("Failed to create test tables: %v", err)
 	}
 
@@ -61,7 +37,7 @@ func TestGetLastStudySession(t *testing.T) {
 	service := NewStudyService(db)
 
 	// Insert test data
-	_, err := db.Exec("INSERT INTO groups (id, name) VALUES (1, 'Test Group')")
+	_, err := db.Exec("INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Test Group')")
 	assert.NoError(t, err)
 
 	testTime := time.Now().Add(-24 * time.Hour)
@@ -73,7 +49,7 @@ func TestGetLastStudySession(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test getting last session
-	session, err := service.GetLastStudySession()
+	session, err := service.GetLastStudySession("")
 	assert.NoError(t, err)
 	assert.NotNil(t, session)
 	assert.Equal(t, 1, session.ID)
@@ -90,17 +66,17 @@ func TestGetStudyProgress(t *testing.T) {
 
 	// Insert test data
 	_, err := db.Exec(`
-		INSERT INTO words (id, latin_word, english_translation, parts)
+		INSERT INTO words (id, language_code, term, translation, parts)
 		VALUES 
-			(1, 'amare', 'to love', '{"type":"verb"}'),
-			(2, 'videre', 'to see', '{"type":"verb"}'),
-			(3, 'puer', 'boy', '{"type":"noun"}')
+			(1, 'la', 'amare', 'to love', '{"type":"verb"}'),
+			(2, 'la', 'videre', 'to see', '{"type":"verb"}'),
+			(3, 'la', 'puer', 'boy', '{"type":"noun"}')
 	`)
 	assert.NoError(t, err)
 
 	_, err = db.Exec(`
-		INSERT INTO groups (id, name)
-		VALUES (1, 'Test Group')
+		INSERT INTO groups (id, language_code, name)
+		VALUES (1, 'la', 'Test Group')
 	`)
 	assert.NoError(t, err)
 
@@ -110,21 +86,29 @@ func TestGetStudyProgress(t *testing.T) {
 	`)
 	assert.NoError(t, err)
 
+	// amare: recalled four times in a row, videre: answered once and missed,
+	// puer: never reviewed
 	_, err = db.Exec(`
 		INSERT INTO word_review_items (word_id, study_session_id, correct)
 		VALUES 
 			(1, 1, true),
-			(2, 1, false),
-			(3, 1, true)
+			(1, 1, true),
+			(1, 1, true),
+			(1, 1, true),
+			(2, 1, false)
 	`)
 	assert.NoError(t, err)
 
 	// Test getting progress
-	progress, err := service.GetStudyProgress()
+	progress, err := service.GetStudyProgress("")
 	assert.NoError(t, err)
 	assert.NotNil(t, progress)
-	assert.Equal(t, 3, progress.TotalWordsStudied)
+	assert.Equal(t, 1, progress.TotalWordsStudied)
+	assert.Equal(t, 1, progress.TotalWordsMastered)
 	assert.Equal(t, 3, progress.TotalAvailableWords)
+	assert.Equal(t, 1, progress.WordsByLevel[MasteryNew])
+	assert.Equal(t, 1, progress.WordsByLevel[MasteryLearning])
+	assert.Equal(t, 1, progress.WordsByLevel[MasteryMastered])
 }
 
 func TestGetQuickStats(t *testing.T) {
@@ -135,16 +119,16 @@ func TestGetQuickStats(t *testing.T) {
 
 	// Insert test data
 	_, err := db.Exec(`
-		INSERT INTO words (id, latin_word, english_translation, parts)
+		INSERT INTO words (id, language_code, term, translation, parts)
 		VALUES 
-			(1, 'amare', 'to love', '{"type":"verb"}'),
-			(2, 'videre', 'to see', '{"type":"verb"}'),
-			(3, 'puer', 'boy', '{"type":"noun"}'),
-			(4, 'puella', 'girl', '{"type":"noun"}')
+			(1, 'la', 'amare', 'to love', '{"type":"verb"}'),
+			(2, 'la', 'videre', 'to see', '{"type":"verb"}'),
+			(3, 'la', 'puer', 'boy', '{"type":"noun"}'),
+			(4, 'la', 'puella', 'girl', '{"type":"noun"}')
 	`)
 	assert.NoError(t, err)
 
-	_, err = db.Exec("INSERT INTO groups (id, name) VALUES (1, 'Test Group')")
+	_, err = db.Exec("INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Test Group')")
 	assert.NoError(t, err)
 
 	_, err = db.Exec(`
@@ -166,7 +150,7 @@ func TestGetQuickStats(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test getting stats
-	stats, err := service.GetQuickStats()
+	stats, err := service.GetQuickStats("")
 	assert.NoError(t, err)
 	assert.NotNil(t, stats)
 	assert.Equal(t, 2, stats.TotalStudySessions)
@@ -174,3 +158,56 @@ func TestGetQuickStats(t *testing.T) {
 	assert.Equal(t, 2, stats.StudyStreakDays)
 	assert.InDelta(t, 75.0, stats.SuccessRate, 0.1) // 3 correct out of 4 = 75%
 }
+
+func TestStudySessionsBelongToTheirLearner(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	_, err := db.Exec(`
+		INSERT INTO users (id, name, role) VALUES (1, 'Marcus', 'learner'), (2, 'Julia', 'learner');
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES (1, 'la', 'puella', 'girl', '{}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Basics');
+	`)
+	assert.NoError(t, err)
+	marcus := &User{ID: 1, Role: RoleLearner}
+	julia := &User{ID: 2, Role: RoleLearner}
+	study := NewStudyService(db)
+	grading := NewGradingService(db, study, llm.Fake{})
+
+	session, err := study.CreateStudySession(marcus, 1, nil)
+	assert.NoError(t, err)
+
+	for _, tt := range []struct {
+		actor  *User
+		code   string
+		status int
+	}{
+		{nil, apperrors.CodeUnauthorized, http.StatusUnauthorized},
+		{julia, apperrors.CodeForbidden, http.StatusForbidden},
+	} {
+		_, err = study.AddWordReview(tt.actor, session.ID, 1, true)
+		assertAppError(t, err, tt.code, tt.status)
+		_, err = study.AddAnswerReview(tt.actor, session.ID, 1, "girl")
+		assertAppError(t, err, tt.code, tt.status)
+		_, err = study.GetSessionReviews(tt.actor, session.ID)
+		assertAppError(t, err, tt.code, tt.status)
+		_, err = study.GetSessionWords(tt.actor, session.ID)
+		assertAppError(t, err, tt.code, tt.status)
+		_, err = study.CompleteStudySession(tt.actor, session.ID)
+		assertAppError(t, err, tt.code, tt.status)
+		_, err = grading.Grade(context.Background(), tt.actor, GradeInput{
+			Expected:  []string{"Puella."},
+			Answer:    "Puella.",
+			SessionID: session.ID,
+		})
+		assertAppError(t, err, tt.code, tt.status)
+	}
+
+	_, err = study.AddWordReview(marcus, session.ID, 1, true)
+	assert.NoError(t, err)
+	reviews, err := study.GetSessionReviews(marcus, session.ID)
+	assert.NoError(t, err)
+	assert.Len(t, reviews, 1)
+	completed, err := study.CompleteStudySession(marcus, session.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, completed.CompletedAt)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// TagService lists the tags put on words
+type TagService struct {
+	instrumented
+
+	db *sql.DB
+}
+
+// Tag is a free-form label on words, with the number of words outside the
+// trash carrying it
+type Tag struct {
+	Name      string `json:"name"`
+	WordCount int    `json:"word_count"`
+}
+
+func NewTagService(db *sql.DB) *TagService {
+	return &TagService{db: db, instrumented: instrumented{observer: nopObserver{}}}
+}
+
+// SearchTags returns up to limit tags in use whose name starts with prefix,
+// ignoring case, most used first
+func (s *TagService) SearchTags(prefix string, limit int) ([]Tag, error) {
+	defer timeQuery(s.observer, "TagService.SearchTags")()
+
+	rows, err := s.db.Query(`
+		SELECT t.name, COUNT(w.id) AS word_count
+		FROM tags t
+		JOIN word_tags wt ON wt.tag_id = t.id
+		JOIN words w ON w.id = wt.word_id AND w.deleted_at IS NULL
+		WHERE t.name LIKE ? ESCAPE '\'
+		GROUP BY t.id
+		ORDER BY word_count DESC, t.name
+		LIMIT ?`,
+		escapeLike(normalizeTag(prefix))+"%", limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.Name, &t.WordCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// normalizeTag trims a tag name and collapses its inner whitespace
+func normalizeTag(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// normalizeTags normalizes names, dropping empty ones and duplicates that
+// differ only in case
+func normalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		name = normalizeTag(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, name)
+	}
+	return tags
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// taggedWords returns a query selecting the ids of the words that carry every
+// one of tags, with its arguments
+func taggedWords(tags []string) (string, []interface{}) {
+	tags = normalizeTags(tags)
+	placeholders := make([]string, len(tags))
+	args := make([]interface{}, len(tags))
+	for i, tag := range tags {
+		placeholders[i] = "?"
+		args[i] = tag
+	}
+	query := fmt.Sprintf(`
+		SELECT wt.word_id
+		FROM word_tags wt
+		JOIN tags t ON t.id = wt.tag_id
+		WHERE t.name IN (%s)
+		GROUP BY wt.word_id
+		HAVING COUNT(*) = %d`, strings.Join(placeholders, ", "), len(tags))
+	return query, args
+}
+
+// replaceTags overwrites the tags of a word, creating tags that do not exist
+// yet. It must run in the transaction that writes the word.
+func replaceTags(q queryer, wordID int, names []string) error {
+	if _, err := q.Exec("DELETE FROM word_tags WHERE word_id = ?", wordID); err != nil {
+		return err
+	}
+	for _, name := range normalizeTags(names) {
+		if _, err := q.Exec("INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING", name); err != nil {
+			return err
+		}
+		_, err := q.Exec(
+			"INSERT INTO word_tags (word_id, tag_id) SELECT ?, id FROM tags WHERE name = ?",
+			wordID, name,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags returns the tag names of each word, in alphabetical order
+func loadTags(q queryer, wordIDs []int) (map[int][]string, error) {
+	tags := make(map[int][]string)
+	if len(wordIDs) == 0 {
+		return tags, nil
+	}
+	placeholders := make([]string, len(wordIDs))
+	args := make([]interface{}, len(wordIDs))
+	for i, id := range wordIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := q.Query(`
+		SELECT wt.word_id, t.name
+		FROM word_tags wt
+		JOIN tags t ON t.id = wt.tag_id
+		WHERE wt.word_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY wt.word_id, t.name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var wordID int
+		var name string
+		if err := rows.Scan(&wordID, &name); err != nil {
+			return nil, err
+		}
+		tags[wordID] = append(tags[wordID], name)
+	}
+	return tags, rows.Err()
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+)
+
+func TestWordTags(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	words := NewWordService(db)
+	amare, err := words.CreateWord(nil, WordInput{Term: "amare", Translation: "to love", Tags: []string{"Caesar  Book 1", "needs audio"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Caesar Book 1", "needs audio"}, amare.Tags)
+	_, err = words.CreateWord(nil, WordInput{Term: "bellum", Translation: "war", Tags: []string{"caesar book 1", "Caesar Book 1"}})
+	assert.NoError(t, err)
+	_, err = words.CreateWord(nil, WordInput{Term: "puer", Translation: "boy"})
+	assert.NoError(t, err)
+
+	// Tag names match regardless of case, and filters require every tag
+	page, err := words.GetWords(1, 10, WordFilter{Tags: []string{"CAESAR BOOK 1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, page.TotalItems)
+	assert.Equal(t, []string{"Caesar Book 1"}, page.Items[1].Tags)
+	page, err = words.GetWords(1, 10, WordFilter{Tags: []string{"caesar book 1", "needs audio"}})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, "amare", page.Items[0].Term)
+	}
+
+	tags, err := NewTagService(db).SearchTags("caes", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{{Name: "Caesar Book 1", WordCount: 2}}, tags)
+
+	// Removing the tags of a word leaves it out of autocompletion
+	_, err = words.UpdateWord(nil, amare.ID, WordInput{Term: "amare", Translation: "to love"})
+	assert.NoError(t, err)
+	tags, err = NewTagService(db).SearchTags("", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{{Name: "Caesar Book 1", WordCount: 1}}, tags)
+}
+
+func TestTagStudySession(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	words := NewWordService(db)
+	_, err := words.CreateWord(nil, WordInput{Term: "amare", Translation: "to love", Tags: []string{"verbs"}})
+	assert.NoError(t, err)
+
+	study := NewStudyService(db)
+	session, err := study.CreateTagStudySession(nil, []string{"Verbs"}, "la")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Verbs"}, session.Tags)
+	assert.Equal(t, "la", session.Language)
+	assert.Zero(t, session.GroupID)
+
+	// Words tagged after the session started are studied too
+	_, err = words.CreateWord(nil, WordInput{Term: "videre", Translation: "to see", Tags: []string{"verbs"}})
+	assert.NoError(t, err)
+	_, err = words.CreateWord(nil, WordInput{Language: "grc", Term: "λέγω", Translation: "to say", Tags: []string{"verbs"}})
+	assert.NoError(t, err)
+
+	list, err := study.GetSessionWords(System, session.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "amare", list[0].Term)
+		assert.Equal(t, "videre", list[1].Term)
+	}
+
+	last, err := study.GetLastStudySession("la")
+	assert.NoError(t, err)
+	assert.Equal(t, session.ID, last.ID)
+
+	_, err = study.GetSessionWords(System, 99)
+	assertAppError(t, err, apperrors.CodeSessionNotFound, http.StatusNotFound)
+}
@@ -0,0 +1,654 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+
+	apperrors "lang-portal/internal/errors"
+	"lang-portal/internal/llm"
+)
+
+// TextService keeps a library of reading texts and analyses the vocabulary
+// of texts
+type TextService struct {
+	instrumented
+
+	db      *sql.DB
+	mastery MasteryConfig
+	words   *WordService
+}
+
+// AnalyzeInput is a text to analyse. Language defaults to DefaultLanguage.
+// With a GroupName, a group of that name is created from the unknown lemmas
+// found in the dictionary, adding them as words.
+type AnalyzeInput struct {
+	Text      string
+	Language  string
+	GroupName string
+}
+
+// TextAnalysis reports which words of a text are known. A token is known
+// when its lemma is a word of the vocabulary; Coverage is the percentage of
+// known tokens. Lemmas lists each lemma once, in order of appearance.
+type TextAnalysis struct {
+	Language     string          `json:"language"`
+	TokenCount   int             `json:"token_count"`
+	KnownCount   int             `json:"known_count"`
+	UnknownCount int             `json:"unknown_count"`
+	Coverage     float64         `json:"coverage"`
+	Tokens       []TextToken     `json:"tokens"`
+	Lemmas       []AnalyzedLemma `json:"lemmas"`
+	Group        *Group          `json:"group,omitempty"`
+	Skipped      []string        `json:"skipped,omitempty"`
+}
+
+// TextToken is a word of a text as written, with its lemma. Tokens that could
+// not be lemmatised are their own lemma, lowercased and without macrons. The
+// tokens of stored texts carry the learner's Mastery of their word.
+type TextToken struct {
+	Text    string       `json:"text"`
+	Lemma   string       `json:"lemma"`
+	WordID  *int         `json:"word_id,omitempty"`
+	Known   bool         `json:"known"`
+	Mastery MasteryLevel `json:"mastery,omitempty"`
+}
+
+// AnalyzedLemma is a lemma found in a text. Recognized reports whether it was
+// found in the vocabulary or the dictionary rather than assumed from the
+// token; Translation is set for recognised lemmas.
+type AnalyzedLemma struct {
+	Lemma       string  `json:"lemma"`
+	Translation *string `json:"translation,omitempty"`
+	WordID      *int    `json:"word_id,omitempty"`
+	Known       bool    `json:"known"`
+	Recognized  bool    `json:"recognized"`
+	Count       int     `json:"count"`
+}
+
+func NewTextService(db *sql.DB, words *WordService) *TextService {
+	return &TextService{db: db, mastery: DefaultMasteryConfig(), instrumented: instrumented{observer: nopObserver{}}, words: words}
+}
+
+// AnalyzeText tokenizes a text, lemmatizes its tokens against the vocabulary
+// and the dictionary of its language and reports its coverage. Creating a
+// group on behalf of actor adds every unknown lemma of the dictionary as a
+// word; unknown lemmas missing from the dictionary are listed as skipped.
+func (s *TextService) AnalyzeText(actor *User, input AnalyzeInput) (*TextAnalysis, error) {
+	defer timeQuery(s.observer, "TextService.AnalyzeText")()
+
+	language := languageOrDefault(input.Language)
+	if err := requireLanguage(s.db, language); err != nil {
+		return nil, err
+	}
+	lemmas, err := loadLemmatizer(s.db, language)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &TextAnalysis{Language: language, Tokens: []TextToken{}, Lemmas: []AnalyzedLemma{}}
+	index := map[string]int{}
+	for _, text := range tokenize(input.Text) {
+		token := TextToken{Text: text, Lemma: foldWord(text)}
+		lemma := AnalyzedLemma{Lemma: token.Lemma}
+		if entry := lemmas.lookup(latinFold(text)); entry != nil {
+			translation := entry.Translation
+			token.Lemma, token.WordID, token.Known = entry.Lemma, entry.WordID, entry.WordID != nil
+			lemma = AnalyzedLemma{
+				Lemma:       entry.Lemma,
+				Translation: &translation,
+				WordID:      entry.WordID,
+				Known:       token.Known,
+				Recognized:  true,
+			}
+		}
+		analysis.Tokens = append(analysis.Tokens, token)
+		if token.Known {
+			analysis.KnownCount++
+		}
+
+		i, seen := index[token.Lemma]
+		if !seen {
+			i = len(analysis.Lemmas)
+			index[token.Lemma] = i
+			analysis.Lemmas = append(analysis.Lemmas, lemma)
+		}
+		analysis.Lemmas[i].Count++
+	}
+	analysis.TokenCount = len(analysis.Tokens)
+	analysis.UnknownCount = analysis.TokenCount - analysis.KnownCount
+	if analysis.TokenCount > 0 {
+		coverage := 100 * float64(analysis.KnownCount) / float64(analysis.TokenCount)
+		analysis.Coverage = math.Round(coverage*10) / 10
+	}
+
+	if input.GroupName != "" {
+		if err := s.createGroup(actor, language, input.GroupName, lemmas, analysis); err != nil {
+			return nil, err
+		}
+	}
+	return analysis, nil
+}
+
+// createGroup creates a group of the unknown lemmas of an analysis in one
+// transaction, adding those of the dictionary as words and auditing each
+// creation. A lemma whose word is in the trash has that word restored rather
+// than a second one created, so its reviews are kept. The lemmas of the
+// analysis are given the ids of the new or restored words.
+func (s *TextService) createGroup(actor *User, language, name string, lemmas *lemmatizer, analysis *TextAnalysis) error {
+	var created, restored []int
+	id, err := auditedChange(s.db, actor, AuditEntityGroup, AuditCreate, 0, func(tx *sql.Tx) (int, error) {
+		result, err := tx.Exec(
+			"INSERT INTO groups (language_code, name, owner_id) VALUES (?, ?, ?)", language, name, actorID(actor),
+		)
+		if err != nil {
+			return 0, translateDBError(tx, err)
+		}
+		groupID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+
+		for i := range analysis.Lemmas {
+			lemma := &analysis.Lemmas[i]
+			if lemma.Known {
+				continue
+			}
+			entry := lemmas.byKey[latinFold(lemma.Lemma)]
+			if entry == nil || !entry.InDictionary {
+				analysis.Skipped = append(analysis.Skipped, lemma.Lemma)
+				continue
+			}
+			wordID, err := restoreWordFromTrash(tx, actor, language, entry.Lemma)
+			if err != nil {
+				return 0, err
+			}
+			if wordID != 0 {
+				restored = append(restored, wordID)
+			} else {
+				if wordID, err = createWordFromLemma(tx, actor, language, entry); err != nil {
+					return 0, err
+				}
+				created = append(created, wordID)
+			}
+			if _, err := tx.Exec("INSERT INTO words_groups (word_id, group_id) VALUES (?, ?)", wordID, groupID); err != nil {
+				return 0, err
+			}
+			entry.WordID = &wordID
+			lemma.WordID = &wordID
+		}
+		return int(groupID), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, wordID := range created {
+		if _, err := s.words.wordChanged(actor, EventWordCreated, wordID); err != nil {
+			return err
+		}
+	}
+	for _, wordID := range restored {
+		if _, err := s.words.wordChanged(actor, EventWordUpdated, wordID); err != nil {
+			return err
+		}
+	}
+	analysis.Group = &Group{ID: id, Language: language, Name: name, WordCount: len(created) + len(restored)}
+	return nil
+}
+
+// restoreWordFromTrash takes the most recently trashed word with a term out
+// of the trash, recording the restore in the audit log. It returns 0 if no
+// word with that term is in the trash.
+func restoreWordFromTrash(tx *sql.Tx, actor *User, language, term string) (int, error) {
+	var id int
+	err := tx.QueryRow(`
+		SELECT id FROM words
+		WHERE language_code = ? AND term = ? AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC LIMIT 1`,
+		language, term,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	before, err := loadSnapshot(tx, AuditEntityWord, id)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE words SET deleted_at = NULL WHERE id = ?", id); err != nil {
+		return 0, translateDBError(tx, err)
+	}
+	after, err := loadSnapshot(tx, AuditEntityWord, id)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := recordAudit(tx, actor, AuditEntityWord, id, AuditRestore, before, after, nil); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// createWordFromLemma inserts a dictionary lemma as a word with its
+// translation as the only sense, recording the creation in the audit log
+func createWordFromLemma(tx *sql.Tx, actor *User, language string, entry *lemmaEntry) (int, error) {
+	result, err := tx.Exec(
+		"INSERT INTO words (language_code, term, translation, parts) VALUES (?, ?, ?, ?)",
+		language, entry.Lemma, entry.Translation, partsJSON(entry.Parts),
+	)
+	if err != nil {
+		return 0, translateDBError(tx, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := writeWordDetails(tx, int(id), []Sense{{Gloss: entry.Translation}}, nil, nil); err != nil {
+		return 0, err
+	}
+	after, err := loadSnapshot(tx, AuditEntityWord, int(id))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := recordAudit(tx, actor, AuditEntityWord, int(id), AuditCreate, nil, after, nil); err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// loadLemmatizer builds a lemmatizer from the active words of a language,
+// then the lemmas of its dictionary
+func loadLemmatizer(q queryer, language string) (*lemmatizer, error) {
+	lemmas := newLemmatizer()
+
+	rows, err := q.Query(
+		"SELECT id, term, translation, parts FROM words WHERE language_code = ? AND deleted_at IS NULL ORDER BY id",
+		language,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var entry lemmaEntry
+		var parts string
+		if err := rows.Scan(&id, &entry.Lemma, &entry.Translation, &parts); err != nil {
+			return nil, err
+		}
+		entry.WordID, entry.Parts = &id, json.RawMessage(parts)
+		lemmas.add(entry, nil)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(
+		"SELECT lemma, translation, parts, forms FROM lemmas WHERE language_code = ? ORDER BY id",
+		language,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		entry := lemmaEntry{InDictionary: true}
+		var parts, formsJSON string
+		if err := rows.Scan(&entry.Lemma, &entry.Translation, &parts, &formsJSON); err != nil {
+			return nil, err
+		}
+		var forms []string
+		if err := json.Unmarshal([]byte(formsJSON), &forms); err != nil {
+			return nil, err
+		}
+		entry.Parts = json.RawMessage(parts)
+		lemmas.add(entry, forms)
+	}
+	return lemmas, rows.Err()
+}
+
+// Text is a reading passage with a learner's progress through its vocabulary
+type Text struct {
+	ID         int          `json:"id"`
+	Language   string       `json:"language"`
+	Title      string       `json:"title"`
+	Author     *string      `json:"author,omitempty"`
+	Difficulty string       `json:"difficulty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	Progress   TextProgress `json:"progress"`
+}
+
+// TextDetails is a text with its body and tokens
+type TextDetails struct {
+	Text
+	Body   string      `json:"body"`
+	Tokens []TextToken `json:"tokens"`
+}
+
+// TextProgress measures how much of a text a learner knows. Known tokens
+// are linked to a word; Mastered is the percentage of tokens whose word the
+// learner has mastered, and UnknownWords counts the distinct lemmas they have
+// not.
+type TextProgress struct {
+	TokenCount    int     `json:"token_count"`
+	KnownCount    int     `json:"known_count"`
+	MasteredCount int     `json:"mastered_count"`
+	Mastered      float64 `json:"mastered"`
+	UnknownWords  int     `json:"unknown_words"`
+}
+
+// TextInput holds the fields of a new text. An empty Language means
+// DefaultLanguage.
+type TextInput struct {
+	Language   string
+	Title      string
+	Author     *string
+	Difficulty string
+	Body       string
+}
+
+// TextFilter narrows the texts returned by GetTexts. Zero values match
+// everything.
+type TextFilter struct {
+	Language string
+}
+
+// SetMasteryConfig sets the thresholds used to classify the words of texts
+func (s *TextService) SetMasteryConfig(cfg MasteryConfig) {
+	s.mastery = cfg
+}
+
+// CreateText stores a text with its tokens, lemmatized against the
+// vocabulary and the dictionary of its language, and returns it with the
+// progress of learner
+func (s *TextService) CreateText(learner *User, input TextInput) (*TextDetails, error) {
+	defer timeQuery(s.observer, "TextService.CreateText")()
+
+	language := languageOrDefault(input.Language)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := requireLanguage(tx, language); err != nil {
+		return nil, err
+	}
+	lemmas, err := loadLemmatizer(tx, language)
+	if err != nil {
+		return nil, err
+	}
+	var id int
+	err = tx.QueryRow(
+		"INSERT INTO texts (language_code, title, author, difficulty, body) VALUES (?, ?, ?, ?, ?) RETURNING id",
+		language, input.Title, input.Author, input.Difficulty, input.Body,
+	).Scan(&id)
+	if err != nil {
+		return nil, translateDBError(tx, err)
+	}
+	for i, token := range tokenize(input.Body) {
+		lemma := foldWord(token)
+		if entry := lemmas.lookup(latinFold(token)); entry != nil {
+			lemma = entry.Lemma
+		}
+		_, err := tx.Exec(
+			"INSERT INTO text_tokens (text_id, position, token, lemma) VALUES (?, ?, ?, ?)",
+			id, i, token, lemma,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.getText(actorUserID(learner), id)
+}
+
+// GetTexts lists texts, newest first, with the progress of learner, or of
+// all learners when it is nil
+func (s *TextService) GetTexts(learner *User, filter TextFilter) ([]Text, error) {
+	defer timeQuery(s.observer, "TextService.GetTexts")()
+
+	return s.loadTexts(actorUserID(learner), filter.Language, 0)
+}
+
+// GetText returns a text with its tokens and the progress of learner. It
+// returns nil if the text does not exist.
+func (s *TextService) GetText(learner *User, id int) (*TextDetails, error) {
+	defer timeQuery(s.observer, "TextService.GetText")()
+
+	return s.getText(actorUserID(learner), id)
+}
+
+func (s *TextService) getText(userID *int, id int) (*TextDetails, error) {
+	texts, err := s.loadTexts(userID, "", id)
+	if err != nil || len(texts) == 0 {
+		return nil, err
+	}
+	details := &TextDetails{Text: texts[0]}
+	if err := s.db.QueryRow("SELECT body FROM texts WHERE id = ?", id).Scan(&details.Body); err != nil {
+		return nil, err
+	}
+
+	tokens, err := loadTextTokens(s.db, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	levels, err := s.learnerLevels(userID, tokens)
+	if err != nil {
+		return nil, err
+	}
+	details.Tokens = make([]TextToken, len(tokens))
+	for i, t := range tokens {
+		details.Tokens[i] = TextToken{Text: t.token, Lemma: t.lemma, WordID: t.wordID, Known: t.wordID != nil}
+		if t.wordID != nil {
+			details.Tokens[i].Mastery = levels[*t.wordID]
+		}
+	}
+	return details, nil
+}
+
+// DeleteText deletes a text and its tokens. It reports false if the text
+// does not exist.
+func (s *TextService) DeleteText(id int) (bool, error) {
+	defer timeQuery(s.observer, "TextService.DeleteText")()
+
+	return affectsRow(s.db.Exec("DELETE FROM texts WHERE id = ?", id))
+}
+
+// RecommendText returns the text of a language that learner should read
+// next: among those with between minUnknown and maxUnknown words they have
+// not mastered, the one with the fewest, easier texts first on a tie. It
+// fails with NO_TEXT_RECOMMENDATION when no text is in the band.
+func (s *TextService) RecommendText(learner *User, language string, minUnknown, maxUnknown int) (*Text, error) {
+	defer timeQuery(s.observer, "TextService.RecommendText")()
+
+	texts, err := s.loadTexts(actorUserID(learner), languageOrDefault(language), 0)
+	if err != nil {
+		return nil, err
+	}
+	var best *Text
+	for i := range texts {
+		t := &texts[i]
+		if t.Progress.UnknownWords < minUnknown || t.Progress.UnknownWords > maxUnknown {
+			continue
+		}
+		if best == nil || t.Progress.UnknownWords < best.Progress.UnknownWords ||
+			(t.Progress.UnknownWords == best.Progress.UnknownWords && difficultyRank(t.Difficulty) < difficultyRank(best.Difficulty)) {
+			best = t
+		}
+	}
+	if best == nil {
+		return nil, apperrors.New(apperrors.CodeNoTextRecommendation).WithData(map[string]interface{}{
+			"language":    languageOrDefault(language),
+			"min_unknown": minUnknown,
+			"max_unknown": maxUnknown,
+		})
+	}
+	return best, nil
+}
+
+// loadTexts loads texts of a language, or the one with the given id, with
+// the progress of a learner
+func (s *TextService) loadTexts(userID *int, language string, id int) ([]Text, error) {
+	query := "SELECT id, language_code, title, author, difficulty, created_at FROM texts"
+	var conditions []string
+	var args []interface{}
+	if language != "" {
+		conditions = append(conditions, "language_code = ?")
+		args = append(args, language)
+	}
+	if id != 0 {
+		conditions = append(conditions, "id = ?")
+		args = append(args, id)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	texts := []Text{}
+	var ids []int
+	for rows.Next() {
+		var t Text
+		var author sql.NullString
+		if err := rows.Scan(&t.ID, &t.Language, &t.Title, &author, &t.Difficulty, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		t.Author = nullStringPtr(author)
+		texts = append(texts, t)
+		ids = append(ids, t.ID)
+	}
+	if err := rows.Err(); err != nil || len(texts) == 0 {
+		return texts, err
+	}
+
+	tokens, err := loadTextTokens(s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := s.learnerLevels(userID, tokens)
+	if err != nil {
+		return nil, err
+	}
+	byText := map[int][]linkedToken{}
+	for _, t := range tokens {
+		byText[t.textID] = append(byText[t.textID], t)
+	}
+	for i := range texts {
+		texts[i].Progress = textProgress(byText[texts[i].ID], levels)
+	}
+	return texts, nil
+}
+
+// learnerLevels classifies the words linked from tokens by the reviews of a
+// learner, or of everyone when userID is nil
+func (s *TextService) learnerLevels(userID *int, tokens []linkedToken) (map[int]MasteryLevel, error) {
+	seen := map[int]bool{}
+	var wordIDs []int
+	for _, t := range tokens {
+		if t.wordID != nil && !seen[*t.wordID] {
+			seen[*t.wordID] = true
+			wordIDs = append(wordIDs, *t.wordID)
+		}
+	}
+	levels := make(map[int]MasteryLevel, len(wordIDs))
+	if len(wordIDs) == 0 {
+		return levels, nil
+	}
+
+	history, err := loadLearnerHistory(s.db, userID, wordIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range wordIDs {
+		levels[id] = s.mastery.Level(history[id])
+	}
+	return levels, nil
+}
+
+// linkedToken is a stored token with the word its lemma links to
+type linkedToken struct {
+	textID int
+	token  string
+	lemma  string
+	wordID *int
+}
+
+// loadTextTokens returns the tokens of texts in order, linked to the active
+// words of the text's language whose term is their lemma
+func loadTextTokens(q queryer, textIDs []int) ([]linkedToken, error) {
+	placeholders := make([]string, len(textIDs))
+	args := make([]interface{}, len(textIDs))
+	for i, id := range textIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	rows, err := q.Query(`
+		SELECT tt.text_id, tt.token, tt.lemma, w.id
+		FROM text_tokens tt
+		JOIN texts t ON t.id = tt.text_id
+		LEFT JOIN words w ON w.language_code = t.language_code AND w.term = tt.lemma AND w.deleted_at IS NULL
+		WHERE tt.text_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY tt.text_id, tt.position`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []linkedToken
+	for rows.Next() {
+		var t linkedToken
+		var wordID sql.NullInt64
+		if err := rows.Scan(&t.textID, &t.token, &t.lemma, &wordID); err != nil {
+			return nil, err
+		}
+		t.wordID = nullIntPtr(wordID)
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// textProgress computes the progress through the tokens of one text
+func textProgress(tokens []linkedToken, levels map[int]MasteryLevel) TextProgress {
+	progress := TextProgress{TokenCount: len(tokens)}
+	unknown := map[string]bool{}
+	for _, t := range tokens {
+		if t.wordID != nil {
+			progress.KnownCount++
+			if levels[*t.wordID].IsLearned() {
+				progress.MasteredCount++
+				continue
+			}
+		}
+		unknown[t.lemma] = true
+	}
+	progress.UnknownWords = len(unknown)
+	if progress.TokenCount > 0 {
+		mastered := 100 * float64(progress.MasteredCount) / float64(progress.TokenCount)
+		progress.Mastered = math.Round(mastered*10) / 10
+	}
+	return progress
+}
+
+// difficultyRank orders text difficulties from the easiest
+func difficultyRank(difficulty string) int {
+	for i, d := range llm.Difficulties {
+		if d == difficulty {
+			return i
+		}
+	}
+	return len(llm.Difficulties)
+}
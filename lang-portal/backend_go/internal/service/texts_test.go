@@ -0,0 +1,249 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+)
+
+func TestLemmatizerDerivesForms(t *testing.T) {
+	lemmas := newLemmatizer()
+	add := func(term, parts string, forms ...string) {
+		lemmas.add(lemmaEntry{Lemma: term, Parts: json.RawMessage(parts)}, forms)
+	}
+	add("amare", `{"type": "verb", "conjugation": 1}`)
+	add("videre", `{"type": "verb", "principal_parts": ["video", "videre", "vidi", "visus"]}`)
+	add("puella", `{"type": "noun", "declension": 1}`)
+	add("puer", `{"type": "noun", "declension": 2}`)
+	add("ager", `{"type": "noun", "declension": 2}`)
+	add("rex", `{"type": "noun", "declension": 3, "genitive": "rēgis"}`)
+	add("bonus", `{"type": "adjective", "declension": "1st/2nd"}`)
+	add("nauta", `{}`)
+	add("esse", `{"type": "verb"}`, "est", "sunt")
+
+	tests := map[string]string{
+		"amat":      "amare",
+		"amabant":   "amare",
+		"amavit":    "amare",
+		"amatus":    "amare",
+		"video":     "videre",
+		"vidisti":   "videre",
+		"videbamus": "videre",
+		"visam":     "videre",
+		"puellarum": "puella",
+		"pueri":     "puer",
+		"agros":     "ager",
+		"regem":     "rex",
+		"rex":       "rex",
+		"bonae":     "bonus",
+		"optimus":   "",
+		"nautae":    "nauta",
+		"puellaque": "puella",
+		"est":       "esse",
+		"sunt":      "esse",
+		"carthago":  "",
+		"am":        "",
+	}
+	for token, want := range tests {
+		entry := lemmas.lookup(latinFold(token))
+		if want == "" {
+			assert.Nil(t, entry, token)
+		} else if assert.NotNil(t, entry, token) {
+			assert.Equal(t, want, entry.Lemma, token)
+		}
+	}
+}
+
+func setupTexts(t *testing.T) *TextService {
+	db := setupTestDB(t)
+	t.Cleanup(func() { db.Close() })
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts) VALUES
+			(1, 'la', 'amare', 'to love', '{"type": "verb", "conjugation": 1}'),
+			(2, 'la', 'puella', 'girl', '{"type": "noun", "declension": 1}'),
+			(3, 'la', 'et', 'and', '{"type": "conjunction"}');
+	`)
+	assert.NoError(t, err)
+	return NewTextService(db, NewWordService(db))
+}
+
+func TestAnalyzeTextReportsCoverage(t *testing.T) {
+	texts := setupTexts(t)
+
+	analysis, err := texts.AnalyzeText(nil, AnalyzeInput{Text: "Puellae rēgem amant, et rēx puellās amat. Carthāgō!"})
+	assert.NoError(t, err)
+	assert.Equal(t, "la", analysis.Language)
+	assert.Equal(t, 8, analysis.TokenCount)
+	assert.Equal(t, 5, analysis.KnownCount)
+	assert.Equal(t, 3, analysis.UnknownCount)
+	assert.Equal(t, 62.5, analysis.Coverage)
+	assert.Equal(t, TextToken{Text: "rēgem", Lemma: "rex"}, analysis.Tokens[1])
+
+	var order []string
+	byLemma := map[string]AnalyzedLemma{}
+	for _, l := range analysis.Lemmas {
+		order = append(order, l.Lemma)
+		byLemma[l.Lemma] = l
+	}
+	assert.Equal(t, []string{"puella", "rex", "amare", "et", "carthago"}, order)
+	assert.Equal(t, 2, byLemma["puella"].Count)
+	assert.True(t, byLemma["amare"].Known)
+	assert.True(t, byLemma["rex"].Recognized, "rex is in the dictionary")
+	assert.False(t, byLemma["rex"].Known)
+	assert.Equal(t, "king", *byLemma["rex"].Translation)
+	assert.False(t, byLemma["carthago"].Recognized)
+	assert.Nil(t, byLemma["carthago"].Translation)
+
+	_, err = texts.AnalyzeText(nil, AnalyzeInput{Text: "salve", Language: "xx"})
+	assertAppError(t, err, apperrors.CodeLanguageNotFound, http.StatusNotFound)
+}
+
+func TestAnalyzeTextCreatesGroupOfUnknownLemmas(t *testing.T) {
+	texts := setupTexts(t)
+	admin := &User{ID: 1, Name: "admin"}
+	_, err := texts.db.Exec("INSERT INTO users (id, name) VALUES (1, 'admin')")
+	assert.NoError(t, err)
+
+	analysis, err := texts.AnalyzeText(admin, AnalyzeInput{Text: "Rēx deōs et puellam videt.", GroupName: "Reading 1"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, analysis.Group) {
+		assert.Equal(t, "Reading 1", analysis.Group.Name)
+		assert.Equal(t, 2, analysis.Group.WordCount, "rex and deus are added")
+	}
+	assert.Equal(t, []string{"videt"}, analysis.Skipped)
+
+	group, err := NewGroupService(texts.db).GetGroupByID(analysis.Group.ID)
+	assert.NoError(t, err)
+	var terms []string
+	for _, w := range group.Words {
+		terms = append(terms, w.Term)
+	}
+	assert.ElementsMatch(t, []string{"rex", "deus"}, terms)
+
+	word, err := texts.words.GetWordByID(*analysis.Lemmas[0].WordID)
+	assert.NoError(t, err)
+	assert.Equal(t, "king", word.Translation)
+	assert.JSONEq(t, `{"type": "noun", "declension": 3, "genitive": "regis"}`, word.Parts)
+
+	var audited int
+	assert.NoError(t, texts.db.QueryRow("SELECT COUNT(*) FROM audit_log WHERE user_id = 1").Scan(&audited))
+	assert.Equal(t, 3, audited, "the group and both words")
+
+	// The new words are now known
+	again, err := texts.AnalyzeText(nil, AnalyzeInput{Text: "Rēx deōs et puellam videt."})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, again.KnownCount)
+
+	_, err = texts.AnalyzeText(admin, AnalyzeInput{Text: "Rēx deōs videt.", GroupName: "Reading 1"})
+	assertAppError(t, err, apperrors.CodeGroupNameTaken, http.StatusConflict)
+}
+
+func TestAnalyzeTextRestoresTrashedLemmas(t *testing.T) {
+	texts := setupTexts(t)
+	_, err := texts.db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts, deleted_at)
+		VALUES (4, 'la', 'rex', 'ruler', '{"type": "noun", "declension": 3}', '2024-01-02 10:00:00');
+	`)
+	assert.NoError(t, err)
+
+	analysis, err := texts.AnalyzeText(System, AnalyzeInput{Text: "Rēx deōs videt.", GroupName: "Reading 1"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, analysis.Group) {
+		assert.Equal(t, 2, analysis.Group.WordCount)
+	}
+	if assert.NotNil(t, analysis.Lemmas[0].WordID) {
+		assert.Equal(t, 4, *analysis.Lemmas[0].WordID, "the trashed word is restored rather than duplicated")
+	}
+
+	word, err := texts.words.GetWordByID(4)
+	assert.NoError(t, err)
+	if assert.NotNil(t, word) {
+		assert.Equal(t, "ruler", word.Translation)
+	}
+
+	var action string
+	assert.NoError(t, texts.db.QueryRow("SELECT action FROM audit_log WHERE entity = 'word' AND entity_id = 4").Scan(&action))
+	assert.Equal(t, AuditRestore, action)
+}
+
+func TestTextsTrackLearnerMastery(t *testing.T) {
+	texts := setupTexts(t)
+	_, err := texts.db.Exec(`
+		INSERT INTO users (id, name) VALUES (1, 'marcus'), (2, 'julia');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Chapter 1');
+		INSERT INTO study_sessions (id, group_id, user_id) VALUES (1, 1, 1), (2, 1, 2);
+		INSERT INTO word_review_items (word_id, study_session_id, correct) VALUES
+			(1, 1, true), (1, 1, true), (1, 1, true), (1, 1, true),
+			(2, 2, true), (2, 2, true), (2, 2, true), (2, 2, true);
+	`)
+	assert.NoError(t, err)
+	marcus := &User{ID: 1, Name: "marcus"}
+	author := "Anonymous"
+
+	text, err := texts.CreateText(marcus, TextInput{
+		Title:      "Puella et nauta",
+		Author:     &author,
+		Difficulty: "easy",
+		Body:       "Puella nautam amat. Nauta puellam amat.",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "la", text.Language)
+	assert.Equal(t, TextProgress{TokenCount: 6, KnownCount: 4, MasteredCount: 2, Mastered: 33.3, UnknownWords: 3}, text.Progress)
+	assert.Equal(t, TextToken{Text: "amat", Lemma: "amare", WordID: text.Tokens[2].WordID, Known: true, Mastery: MasteryMastered}, text.Tokens[2])
+	assert.Equal(t, "nautam", text.Tokens[1].Lemma, "nauta is not a word yet")
+
+	// Each learner sees their own progress
+	details, err := texts.GetText(&User{ID: 2}, text.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, details.Progress.MasteredCount)
+	assert.Equal(t, MasteryMastered, details.Tokens[0].Mastery)
+	assert.Equal(t, MasteryNew, details.Tokens[2].Mastery)
+
+	// Adding a word links the tokens with its term
+	_, err = texts.words.CreateWord(nil, WordInput{Term: "nauta", Translation: "sailor", Parts: json.RawMessage(`{"type": "noun", "declension": 1}`)})
+	assert.NoError(t, err)
+	list, err := texts.GetTexts(marcus, TextFilter{Language: "la"})
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, 5, list[0].Progress.KnownCount, "nauta links but nautam was stored with its own lemma")
+	}
+
+	missing, err := texts.GetText(marcus, 99)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+	deleted, err := texts.DeleteText(text.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = texts.DeleteText(text.ID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestRecommendTextWithinBand(t *testing.T) {
+	texts := setupTexts(t)
+	create := func(title, difficulty, body string) int {
+		text, err := texts.CreateText(nil, TextInput{Title: title, Difficulty: difficulty, Body: body})
+		assert.NoError(t, err)
+		return text.ID
+	}
+	create("Too hard", "hard", "Gallia est omnis divisa in partes tres, quarum unam incolunt Belgae.")
+	medium := create("Medium", "medium", "Puella rosam et lilium amat.")
+	easy := create("Easy", "easy", "Puella rosam et lilium amat.")
+	short := create("Short", "hard", "Puella amat.")
+
+	text, err := texts.RecommendText(nil, "la", 3, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, easy, text.ID, "easier texts win a tie")
+	assert.NotEqual(t, medium, text.ID)
+	assert.Equal(t, 5, text.Progress.UnknownWords)
+
+	text, err = texts.RecommendText(nil, "la", 1, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, short, text.ID, "the text with the fewest unknown words wins")
+
+	_, err = texts.RecommendText(nil, "la", 20, 30)
+	assertAppError(t, err, apperrors.CodeNoTextRecommendation, http.StatusNotFound)
+}
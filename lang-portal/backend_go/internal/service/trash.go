@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	apperrors "lang-portal/internal/errors"
+)
+
+// Trash item types, as used in /api/trash/:type/:id/restore
+const (
+	TrashWords  = "words"
+	TrashGroups = "groups"
+)
+
+// DefaultTrashRetention is how long deleted items stay restorable
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// sqliteTimeFormat matches the text written by SQLite's datetime('now')
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// trashTables maps each trash item type onto its table, display column and
+// audited entity
+var trashTables = map[string]struct{ table, nameColumn, entity string }{
+	TrashWords:  {"words", "term", AuditEntityWord},
+	TrashGroups: {"groups", "name", AuditEntityGroup},
+}
+
+// TrashService lists, restores and purges soft-deleted words and groups
+type TrashService struct {
+	instrumented
+
+	db        *sql.DB
+	retention time.Duration
+}
+
+// TrashItem is a soft-deleted word or group
+type TrashItem struct {
+	Type      string    `json:"type"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+func NewTrashService(db *sql.DB) *TrashService {
+	return &TrashService{db: db, retention: DefaultTrashRetention, instrumented: instrumented{observer: nopObserver{}}}
+}
+
+// SetRetention overrides how long deleted items are kept before purging
+func (s *TrashService) SetRetention(retention time.Duration) {
+	s.retention = retention
+}
+
+// ListTrash returns every item in the trash, most recently deleted first
+func (s *TrashService) ListTrash() ([]TrashItem, error) {
+	defer timeQuery(s.observer, "TrashService.ListTrash")()
+
+	items := []TrashItem{}
+	for itemType, t := range trashTables {
+		query := fmt.Sprintf(
+			"SELECT id, %s, deleted_at FROM %s WHERE deleted_at IS NOT NULL",
+			t.nameColumn, t.table,
+		)
+		rows, err := s.db.Query(query)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			item := TrashItem{Type: itemType}
+			if err := rows.Scan(&item.ID, &item.Name, &item.DeletedAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			item.PurgeAt = item.DeletedAt.Add(s.retention)
+			items = append(items, item)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].DeletedAt.Equal(items[j].DeletedAt) {
+			return items[i].DeletedAt.After(items[j].DeletedAt)
+		}
+		if items[i].Type != items[j].Type {
+			return items[i].Type < items[j].Type
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+// Restore takes an item out of the trash. It reports false if no item of
+// that type and id is in the trash. Groups may only be restored by those
+// allowed to edit them, and an item cannot be restored while an active one
+// has taken its term or name.
+func (s *TrashService) Restore(actor *User, itemType string, id int) (bool, error) {
+	defer timeQuery(s.observer, "TrashService.Restore")()
+
+	t, ok := trashTables[itemType]
+	if !ok {
+		return false, fmt.Errorf("unknown trash item type %q", itemType)
+	}
+	query := fmt.Sprintf("UPDATE %s SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", t.table)
+
+	_, err := auditedChange(s.db, actor, t.entity, AuditRestore, id, func(tx *sql.Tx) (int, error) {
+		if t.entity == AuditEntityGroup {
+			if err := requireGroupEditor(tx, actor, id); err != nil {
+				return 0, err
+			}
+		}
+		restored, err := affectsRow(tx.Exec(query, id))
+		if err != nil {
+			return 0, translateDBError(tx, err)
+		}
+		if !restored {
+			return id, errNoChange
+		}
+		return id, nil
+	})
+	if errors.Is(err, errNoChange) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Purge permanently deletes the items that were deleted more than the
+// retention period before now, and returns how many were removed. The schema
+// cascades, so purging a word removes its reviews and purging a group removes
+// its study sessions.
+func (s *TrashService) Purge(now time.Time) (int64, error) {
+	defer timeQuery(s.observer, "TrashService.Purge")()
+
+	cutoff := now.Add(-s.retention).UTC().Format(sqliteTimeFormat)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var purged int64
+	for _, itemType := range []string{TrashWords, TrashGroups} {
+		query := fmt.Sprintf(
+			"DELETE FROM %s WHERE deleted_at IS NOT NULL AND deleted_at < ?",
+			trashTables[itemType].table,
+		)
+		result, err := tx.Exec(query, cutoff)
+		if err != nil {
+			return 0, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		purged += n
+	}
+	return purged, tx.Commit()
+}
+
+// RunPurger purges expired items every interval until ctx is cancelled
+func (s *TrashService) RunPurger(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			purged, err := s.Purge(now)
+			if err != nil {
+				logger.Error("trash purge failed", "error", err)
+				continue
+			}
+			if purged > 0 {
+				logger.Info("trash purged", "items", purged, "retention", s.retention.String())
+			}
+		}
+	}
+}
+
+// softDelete moves a row of a soft-deletable table to the trash. It reports
+// false if the row does not exist or is already in the trash.
+func softDelete(db queryer, table string, id int) (bool, error) {
+	query := fmt.Sprintf("UPDATE %s SET deleted_at = datetime('now') WHERE id = ? AND deleted_at IS NULL", table)
+	return affectsRow(db.Exec(query, id))
+}
+
+// requireActive checks that each referenced row exists and is not in the
+// trash, returning the reference's not-found error otherwise. Foreign keys
+// alone cannot catch rows that are only soft-deleted.
+func requireActive(db queryer, refs ...reference) error {
+	for _, ref := range refs {
+		var active bool
+		query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE id = ? AND deleted_at IS NULL)", ref.table)
+		if err := db.QueryRow(query, ref.id).Scan(&active); err != nil {
+			return err
+		}
+		if !active {
+			return apperrors.New(ref.code).
+				WithData(map[string]interface{}{"field": ref.field, "value": ref.id})
+		}
+	}
+	return nil
+}
+
+func affectsRow(result sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apperrors "lang-portal/internal/errors"
+)
+
+func TestSoftDeleteHidesWordsAndGroups(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts)
+		VALUES 
+			(1, 'la', 'amare', 'to love', '{"type":"verb"}'),
+			(2, 'la', 'videre', 'to see', '{"type":"verb"}');
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Verbs'), (2, 'la', 'Nouns');
+		INSERT INTO words_groups (word_id, group_id) VALUES (1, 1), (2, 1);
+	`)
+	assert.NoError(t, err)
+
+	words := NewWordService(db)
+	groups := NewGroupService(db)
+
+	deleted, err := words.DeleteWord(nil, 1)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = words.DeleteWord(nil, 1)
+	assert.NoError(t, err)
+	assert.False(t, deleted, "a word already in the trash cannot be deleted again")
+
+	page, err := words.GetWords(1, 10, WordFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page.TotalItems)
+	assert.Equal(t, "videre", page.Items[0].Term)
+
+	word, err := words.GetWordByID(1)
+	assert.NoError(t, err)
+	assert.Nil(t, word)
+
+	group, err := groups.GetGroupByID(1)
+	assert.NoError(t, err)
+	assert.Len(t, group.Words, 1)
+
+	deleted, err = groups.DeleteGroup(System, 2)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	list, err := groups.GetGroups(GroupFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, 1, list[0].WordCount)
+
+	err = groups.AddWordToGroup(System, 2, 2)
+	assertAppError(t, err, apperrors.CodeGroupNotFound, http.StatusNotFound)
+
+	_, err = NewStudyService(db).CreateStudySession(nil, 2, nil)
+	assertAppError(t, err, apperrors.CodeGroupNotFound, http.StatusNotFound)
+}
+
+func TestTrashListAndRestore(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts, deleted_at)
+		VALUES (1, 'la', 'amare', 'to love', '{"type":"verb"}', '2024-01-02 10:00:00');
+		INSERT INTO groups (id, language_code, name, deleted_at) VALUES (1, 'la', 'Verbs', '2024-01-03 10:00:00');
+	`)
+	assert.NoError(t, err)
+
+	service := NewTrashService(db)
+	service.SetRetention(24 * time.Hour)
+
+	items, err := service.ListTrash()
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, TrashGroups, items[0].Type)
+	assert.Equal(t, "Verbs", items[0].Name)
+	assert.Equal(t, TrashWords, items[1].Type)
+	assert.Equal(t, time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC), items[1].PurgeAt.UTC())
+
+	restored, err := service.Restore(System, TrashWords, 1)
+	assert.NoError(t, err)
+	assert.True(t, restored)
+
+	restored, err = service.Restore(System, TrashWords, 1)
+	assert.NoError(t, err)
+	assert.False(t, restored)
+
+	word, err := NewWordService(db).GetWordByID(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, word)
+}
+
+func TestTrashPurge(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts, deleted_at)
+		VALUES 
+			(1, 'la', 'amare', 'to love', '{"type":"verb"}', '2024-01-01 00:00:00'),
+			(2, 'la', 'videre', 'to see', '{"type":"verb"}', '2024-01-09 00:00:00'),
+			(3, 'la', 'puer', 'boy', '{"type":"noun"}', NULL);
+		INSERT INTO groups (id, language_code, name) VALUES (1, 'la', 'Test Group');
+		INSERT INTO study_sessions (id, group_id) VALUES (1, 1);
+		INSERT INTO word_review_items (word_id, study_session_id, correct)
+		VALUES (1, 1, true), (3, 1, true);
+	`)
+	assert.NoError(t, err)
+
+	service := NewTrashService(db)
+	service.SetRetention(7 * 24 * time.Hour)
+
+	purged, err := service.Purge(time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var words, reviews int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM words").Scan(&words))
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM word_review_items").Scan(&reviews))
+	assert.Equal(t, 2, words)
+	assert.Equal(t, 1, reviews, "reviews of the purged word are removed with it")
+}
+
+func TestTrashedNamesCanBeReused(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO words (id, language_code, term, translation, parts, deleted_at)
+		VALUES (1, 'la', 'amare', 'to love', '{"type":"verb"}', '2024-01-02 10:00:00');
+		INSERT INTO groups (id, language_code, name, deleted_at) VALUES (1, 'la', 'Verbs', '2024-01-03 10:00:00');
+	`)
+	assert.NoError(t, err)
+
+	word, err := NewWordService(db).CreateWord(System, WordInput{Language: "la", Term: "amare", Translation: "to love"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, 1, word.ID)
+
+	group, err := NewGroupService(db).CreateGroup(System, "Verbs", "la", nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, 1, group.ID)
+
+	service := NewTrashService(db)
+
+	_, err = service.Restore(System, TrashWords, 1)
+	assertAppError(t, err, apperrors.CodeWordAlreadyExists, http.StatusConflict)
+
+	_, err = service.Restore(System, TrashGroups, 1)
+	assertAppError(t, err, apperrors.CodeGroupNameTaken, http.StatusConflict)
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+)
+
+// UserService manages the users that changes are attributed to
+type UserService struct {
+	instrumented
+
+	db *sql.DB
+}
+
+// User is a person using the portal. A nil *User stands for an anonymous
+// caller wherever an actor is expected. Token is only returned when a token
+// is issued.
+type User struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	Token     string    `json:"token,omitempty"`
+}
+
+// System is the actor for changes the server makes on its own behalf, such
+// as seeding. It is allowed everything and recorded as no user.
+var System = &User{Name: "system", Role: RoleAdmin}
+
+// The roles of users. The first admin is created with `mage admin`; the
+// users they create are learners unless given another role.
+const (
+	RoleAdmin   = "admin"
+	RoleTeacher = "teacher"
+	RoleLearner = "learner"
+)
+
+// Roles lists the valid roles
+var Roles = []string{RoleAdmin, RoleTeacher, RoleLearner}
+
+// Permission is something a role allows users to do
+type Permission string
+
+const (
+	// PermissionStudy allows studying, setting goals and creating groups of
+	// one's own
+	PermissionStudy Permission = "study"
+	// PermissionCurate allows changing the shared vocabulary: languages,
+	// words, their media, texts, groups without an owner and the trash
+	PermissionCurate Permission = "curate"
+	// PermissionTeach allows creating and running classes
+	PermissionTeach Permission = "teach"
+	// PermissionAdminister allows managing users, webhooks and the groups of
+	// other users
+	PermissionAdminister Permission = "administer"
+)
+
+// rolePermissions maps each role to the permissions it grants
+var rolePermissions = map[string][]Permission{
+	RoleAdmin:   {PermissionStudy, PermissionCurate, PermissionTeach, PermissionAdminister},
+	RoleTeacher: {PermissionStudy, PermissionCurate, PermissionTeach},
+	RoleLearner: {PermissionStudy},
+}
+
+// Can reports whether the role of u grants a permission. Anonymous callers
+// have no permissions.
+func (u *User) Can(permission Permission) bool {
+	if u == nil {
+		return false
+	}
+	for _, p := range rolePermissions[u.Role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func NewUserService(db *sql.DB) *UserService {
+	return &UserService{db: db, instrumented: instrumented{observer: nopObserver{}}}
+}
+
+// GetUsers retrieves every user ordered by name
+func (s *UserService) GetUsers() ([]User, error) {
+	defer timeQuery(s.observer, "UserService.GetUsers")()
+
+	rows, err := s.db.Query("SELECT id, name, role, created_at FROM users ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Role, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// GetUserByID retrieves a single user, or nil if it does not exist
+func (s *UserService) GetUserByID(id int) (*User, error) {
+	defer timeQuery(s.observer, "UserService.GetUserByID")()
+
+	var u User
+	err := s.db.QueryRow("SELECT id, name, role, created_at FROM users WHERE id = ?", id).
+		Scan(&u.ID, &u.Name, &u.Role, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Authenticate returns the user a token was issued to, or nil if it matches
+// none
+func (s *UserService) Authenticate(token string) (*User, error) {
+	defer timeQuery(s.observer, "UserService.Authenticate")()
+
+	var u User
+	err := s.db.QueryRow("SELECT id, name, role, created_at FROM users WHERE token_hash = ?", hashToken(token)).
+		Scan(&u.ID, &u.Name, &u.Role, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// CreateUser creates a user with role and issues their first token
+func (s *UserService) CreateUser(name, role string) (*User, error) {
+	defer timeQuery(s.observer, "UserService.CreateUser")()
+
+	token, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	u := User{Token: token}
+	err = s.db.QueryRow(`
+		INSERT INTO users (name, role, token_hash) VALUES (?, ?, ?)
+		RETURNING id, name, role, created_at`, name, role, hashToken(token),
+	).Scan(&u.ID, &u.Name, &u.Role, &u.CreatedAt)
+	if err != nil {
+		return nil, translateDBError(s.db, err)
+	}
+	return &u, nil
+}
+
+// IssueToken replaces the token of a user, so that the previous one no
+// longer authenticates, returning nil if the user does not exist
+func (s *UserService) IssueToken(id int) (*User, error) {
+	defer timeQuery(s.observer, "UserService.IssueToken")()
+
+	token, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	u := User{Token: token}
+	err = s.db.QueryRow(
+		"UPDATE users SET token_hash = ? WHERE id = ? RETURNING id, name, role, created_at", hashToken(token), id,
+	).Scan(&u.ID, &u.Name, &u.Role, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// SetRole changes the role of a user, returning nil if it does not exist
+func (s *UserService) SetRole(id int, role string) (*User, error) {
+	defer timeQuery(s.observer, "UserService.SetRole")()
+
+	var u User
+	err := s.db.QueryRow(
+		"UPDATE users SET role = ? WHERE id = ? RETURNING id, name, role, created_at", role, id,
+	).Scan(&u.ID, &u.Name, &u.Role, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// hashToken returns the hex SHA-256 of a token, as stored in users
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// actorID returns the id of actor to store in a user_id column, or nil for
+// anonymous and System changes
+func actorID(actor *User) interface{} {
+	if actor == nil || actor == System {
+		return nil
+	}
+	return actor.ID
+}
+
+// actorUserID returns the id of actor, or nil for anonymous and System
+// changes
+func actorUserID(actor *User) *int {
+	if actor == nil || actor == System {
+		return nil
+	}
+	return &actor.ID
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRoles(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	users := NewUserService(db)
+
+	admin, err := users.CreateUser("Ms. Varro", RoleAdmin)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleAdmin, admin.Role)
+	learner, err := users.CreateUser("Marcus", RoleLearner)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleLearner, learner.Role)
+
+	assert.True(t, learner.Can(PermissionStudy))
+	assert.False(t, learner.Can(PermissionCurate))
+	assert.True(t, admin.Can(PermissionAdminister))
+	var anonymous *User
+	assert.False(t, anonymous.Can(PermissionStudy))
+
+	teacher, err := users.SetRole(learner.ID, RoleTeacher)
+	assert.NoError(t, err)
+	assert.True(t, teacher.Can(PermissionTeach))
+	assert.False(t, teacher.Can(PermissionAdminister))
+	found, err := users.GetUserByID(learner.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleTeacher, found.Role)
+
+	missing, err := users.SetRole(99, RoleTeacher)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestUserTokens(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	users := NewUserService(db)
+
+	created, err := users.CreateUser("Ms. Varro", RoleLearner)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, created.Token)
+
+	found, err := users.Authenticate(created.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, found.ID)
+	assert.Empty(t, found.Token)
+
+	reissued, err := users.IssueToken(created.ID)
+	assert.NoError(t, err)
+	assert.NotEqual(t, created.Token, reissued.Token)
+	stale, err := users.Authenticate(created.Token)
+	assert.NoError(t, err)
+	assert.Nil(t, stale)
+	found, err = users.Authenticate(reissued.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, found.ID)
+
+	unknown, err := users.Authenticate("guess")
+	assert.NoError(t, err)
+	assert.Nil(t, unknown)
+	missing, err := users.IssueToken(99)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
@@ -0,0 +1,479 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Headers sent with every webhook delivery. The signature is the hex HMAC-SHA256
+// of the timestamp, a dot and the body, keyed by the webhook secret, so that
+// receivers can check both the sender and the freshness of a request.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	DefaultWebhookMaxAttempts = 8
+	DefaultWebhookTimeout     = 10 * time.Second
+	// webhookRetryDelay is the wait before the first retry; it doubles with
+	// every failed attempt up to webhookMaxRetryDelay
+	webhookRetryDelay    = 30 * time.Second
+	webhookMaxRetryDelay = time.Hour
+	// webhookBatchSize caps the deliveries attempted in one pass
+	webhookBatchSize = 50
+	// maxWebhookError caps the stored error or response excerpt
+	maxWebhookError = 500
+)
+
+// WebhookService manages webhook subscriptions and delivers queued events
+type WebhookService struct {
+	instrumented
+
+	db          *sql.DB
+	client      *http.Client
+	maxAttempts int
+}
+
+// Webhook posts the events listed in Events, or every event if it is empty,
+// to URL. Secret is only returned when the webhook is created.
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	UserID    *int      `json:"user_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is an event queued for a webhook, with the outcome of its
+// latest attempt. NextAttemptAt is only set while it is pending.
+type WebhookDelivery struct {
+	ID            int             `json:"id"`
+	WebhookID     int             `json:"webhook_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastStatus    *int            `json:"last_status,omitempty"`
+	LastError     *string         `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+func NewWebhookService(db *sql.DB) *WebhookService {
+	return &WebhookService{
+		db:           db,
+		instrumented: instrumented{observer: nopObserver{}},
+		client:       &http.Client{Timeout: DefaultWebhookTimeout},
+		maxAttempts:  DefaultWebhookMaxAttempts,
+	}
+}
+
+// SetDeliveryPolicy overrides how many times a delivery is attempted before
+// it is marked failed, and how long each attempt may take
+func (s *WebhookService) SetDeliveryPolicy(maxAttempts int, timeout time.Duration) {
+	s.maxAttempts = maxAttempts
+	s.client = &http.Client{Timeout: timeout}
+}
+
+// GetWebhooks retrieves every webhook, without their secrets
+func (s *WebhookService) GetWebhooks() ([]Webhook, error) {
+	defer timeQuery(s.observer, "WebhookService.GetWebhooks")()
+
+	rows, err := s.db.Query("SELECT id, url, events, user_id, created_at FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		var w Webhook
+		var events sql.NullString
+		var userID sql.NullInt64
+		if err := rows.Scan(&w.ID, &w.URL, &events, &userID, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		w.Events = []string{}
+		if events.Valid {
+			if err := json.Unmarshal([]byte(events.String), &w.Events); err != nil {
+				return nil, err
+			}
+		}
+		w.UserID = nullIntPtr(userID)
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// CreateWebhook subscribes url to events on behalf of actor. An empty secret
+// is replaced by a random one.
+func (s *WebhookService) CreateWebhook(actor *User, url string, events []string, secret string) (*Webhook, error) {
+	defer timeQuery(s.observer, "WebhookService.CreateWebhook")()
+
+	if secret == "" {
+		var err error
+		if secret, err = newSecret(); err != nil {
+			return nil, err
+		}
+	}
+	var eventsJSON interface{}
+	if len(events) > 0 {
+		data, err := json.Marshal(events)
+		if err != nil {
+			return nil, err
+		}
+		eventsJSON = string(data)
+	} else {
+		events = []string{}
+	}
+
+	w := Webhook{URL: url, Events: events, Secret: secret, UserID: actorUserID(actor)}
+	err := s.db.QueryRow(
+		"INSERT INTO webhooks (url, secret, events, user_id) VALUES (?, ?, ?, ?) RETURNING id, created_at",
+		url, secret, eventsJSON, actorID(actor),
+	).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		return nil, translateDBError(s.db, err)
+	}
+	return &w, nil
+}
+
+// DeleteWebhook removes a webhook and its deliveries. It reports false if the
+// webhook does not exist.
+func (s *WebhookService) DeleteWebhook(id int) (bool, error) {
+	defer timeQuery(s.observer, "WebhookService.DeleteWebhook")()
+
+	return affectsRow(s.db.Exec("DELETE FROM webhooks WHERE id = ?", id))
+}
+
+// GetDeliveries lists the latest deliveries of a webhook, newest first. It
+// returns nil if the webhook does not exist.
+func (s *WebhookService) GetDeliveries(webhookID, limit int) ([]WebhookDelivery, error) {
+	defer timeQuery(s.observer, "WebhookService.GetDeliveries")()
+
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM webhooks WHERE id = ?)", webhookID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, webhook_id, event_type, payload, status, attempts, last_status, last_error,
+			next_attempt_at, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = ?
+		ORDER BY id DESC
+		LIMIT ?`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload string
+		var lastStatus sql.NullInt64
+		var lastError sql.NullString
+		var nextAttempt, delivered sql.NullTime
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&lastStatus, &lastError, &nextAttempt, &d.CreatedAt, &delivered)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = json.RawMessage(payload)
+		d.LastStatus = nullIntPtr(lastStatus)
+		d.LastError = nullStringPtr(lastError)
+		if nextAttempt.Valid && d.Status == DeliveryPending {
+			d.NextAttemptAt = &nextAttempt.Time
+		}
+		if delivered.Valid {
+			d.DeliveredAt = &delivered.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Enqueue queues an event for every webhook subscribed to its type, due at
+// once. It returns the number of deliveries queued.
+func (s *WebhookService) Enqueue(event Event) (int, error) {
+	defer timeQuery(s.observer, "WebhookService.Enqueue")()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	result, err := s.db.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload, next_attempt_at)
+		SELECT w.id, ?, ?, ?
+		FROM webhooks w
+		WHERE w.events IS NULL
+			OR EXISTS (SELECT 1 FROM json_each(w.events) WHERE json_each.value = ?)`,
+		event.Type, string(payload), time.Now().UTC(), event.Type,
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// dueDelivery is a pending delivery with what is needed to send it
+type dueDelivery struct {
+	id        int
+	eventType string
+	payload   string
+	attempts  int
+	url       string
+	secret    string
+}
+
+// DeliverDue attempts every pending delivery due at now, oldest first, and
+// records the outcome: delivered on a 2xx response, otherwise retried after
+// an exponential backoff until the attempts run out. It returns the number
+// of deliveries attempted.
+func (s *WebhookService) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	defer timeQuery(s.observer, "WebhookService.DeliverDue")()
+
+	rows, err := s.db.Query(`
+		SELECT d.id, d.event_type, d.payload, d.attempts, w.url, w.secret
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= ?
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT ?`, now.UTC(), webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var due []dueDelivery
+	for rows.Next() {
+		var d dueDelivery
+		if err := rows.Scan(&d.id, &d.eventType, &d.payload, &d.attempts, &d.url, &d.secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, d := range due {
+		status, sendErr := s.send(ctx, d, now)
+		if err := s.recordAttempt(d, now, status, sendErr); err != nil {
+			return 0, err
+		}
+	}
+	return len(due), nil
+}
+
+// send posts a delivery to its webhook and returns the response status
+func (s *WebhookService) send(ctx context.Context, d dueDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader([]byte(d.payload)))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lang-portal-webhooks")
+	req.Header.Set(WebhookEventHeader, d.eventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.Itoa(d.id))
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(d.secret, timestamp, []byte(d.payload)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookError))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, excerpt)
+	}
+	return resp.StatusCode, nil
+}
+
+// recordAttempt stores the outcome of a delivery attempt
+func (s *WebhookService) recordAttempt(d dueDelivery, now time.Time, status int, sendErr error) error {
+	attempts := d.attempts + 1
+	var lastStatus interface{}
+	if status != 0 {
+		lastStatus = status
+	}
+
+	if sendErr == nil {
+		_, err := s.db.Exec(`
+			UPDATE webhook_deliveries
+			SET status = 'delivered', attempts = ?, last_status = ?, last_error = NULL,
+				next_attempt_at = NULL, delivered_at = ?
+			WHERE id = ?`, attempts, lastStatus, now.UTC(), d.id)
+		return err
+	}
+
+	message := truncateUTF8(sendErr.Error(), maxWebhookError)
+	if attempts >= s.maxAttempts {
+		_, err := s.db.Exec(`
+			UPDATE webhook_deliveries
+			SET status = 'failed', attempts = ?, last_status = ?, last_error = ?, next_attempt_at = NULL
+			WHERE id = ?`, attempts, lastStatus, message, d.id)
+		return err
+	}
+	_, err := s.db.Exec(`
+		UPDATE webhook_deliveries
+		SET attempts = ?, last_status = ?, last_error = ?, next_attempt_at = ?
+		WHERE id = ?`, attempts, lastStatus, message, now.Add(retryDelay(attempts)).UTC(), d.id)
+	return err
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// retryDelay is the backoff after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := webhookRetryDelay
+	for i := 1; i < attempts && delay < webhookMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxRetryDelay)
+}
+
+// RunDispatcher queues the events published on bus, and delivers them and
+// retries pending deliveries every interval, until ctx is cancelled. Events
+// are only queued here; the deliveries are sent by a separate worker, so
+// that a slow endpoint cannot hold up the subscription.
+func (s *WebhookService) RunDispatcher(ctx context.Context, bus *EventBus, interval time.Duration, logger *slog.Logger) {
+	wake := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.runDeliveries(ctx, wake, interval, logger)
+	}()
+	defer func() { <-done }()
+
+	sub := bus.Subscribe(EventFilter{}, 0)
+	defer func() { sub.Close() }()
+
+	var lastID uint64
+	enqueue := func(event Event) {
+		lastID = event.ID
+		queued, err := s.Enqueue(event)
+		if err != nil {
+			logger.Error("webhook enqueue failed", "event", event.Type, "error", err)
+			return
+		}
+		if queued > 0 {
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events:
+			if ok {
+				enqueue(event)
+				continue
+			}
+			// Dropped for falling behind; resubscribe, queueing the events
+			// missed in the meantime
+			var missed []Event
+			var lost uint64
+			sub, missed, lost = bus.Resume(EventFilter{}, lastID)
+			if lost > 0 {
+				logger.Warn("webhook events lost while the dispatcher fell behind", "events", lost, "after", lastID)
+			}
+			for _, event := range missed {
+				enqueue(event)
+			}
+		}
+	}
+}
+
+// runDeliveries sends the due deliveries whenever it is woken and every
+// interval, until ctx is cancelled
+func (s *WebhookService) runDeliveries(ctx context.Context, wake <-chan struct{}, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+		s.deliver(ctx, logger)
+	}
+}
+
+func (s *WebhookService) deliver(ctx context.Context, logger *slog.Logger) {
+	attempted, err := s.DeliverDue(ctx, time.Now())
+	if err != nil {
+		logger.Error("webhook delivery failed", "error", err)
+		return
+	}
+	if attempted > 0 {
+		logger.Debug("webhook deliveries attempted", "deliveries", attempted)
+	}
+}
+
+// SignWebhook returns the signature header of a delivery, "sha256=" followed
+// by the hex HMAC-SHA256 of timestamp + "." + body keyed by secret
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newSecret returns 32 random bytes, hex encoded, for webhook secrets and
+// user tokens
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
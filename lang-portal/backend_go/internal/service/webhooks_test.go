@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receivedHook is a request captured by a webhook stand-in
+type receivedHook struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver starts a local HTTP stand-in answering each request with
+// the next status, then 200
+func webhookReceiver(t *testing.T, statuses ...int) (*httptest.Server, func() []receivedHook) {
+	var mu sync.Mutex
+	var received []receivedHook
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, receivedHook{header: r.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []receivedHook {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedHook(nil), received...)
+	}
+}
+
+func TestWebhookDeliversSignedEvents(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	server, received := webhookReceiver(t)
+	webhooks := NewWebhookService(db)
+	hook, err := webhooks.CreateWebhook(nil, server.URL, []string{EventSessionCompleted}, "")
+	assert.NoError(t, err)
+	assert.Len(t, hook.Secret, 64, "a secret is generated")
+	everything, err := webhooks.CreateWebhook(nil, server.URL, nil, "a-shared-secret-value")
+	assert.NoError(t, err)
+
+	// Only the catch-all webhook subscribes to review events
+	queued, err := webhooks.Enqueue(Event{ID: 1, Type: EventReviewAdded, SessionID: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, queued)
+	queued, err = webhooks.Enqueue(Event{ID: 2, Type: EventSessionCompleted, SessionID: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, queued)
+
+	attempted, err := webhooks.DeliverDue(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempted)
+
+	requests := received()
+	if assert.Len(t, requests, 3) {
+		first := requests[0]
+		assert.Equal(t, EventReviewAdded, first.header.Get(WebhookEventHeader))
+		assert.Equal(t, "application/json", first.header.Get("Content-Type"))
+		assert.JSONEq(t, `{"id":1,"type":"review.added","session_id":3,"time":"0001-01-01T00:00:00Z","data":null}`, string(first.body))
+		timestamp := first.header.Get(WebhookTimestampHeader)
+		assert.Equal(t, SignWebhook("a-shared-secret-value", timestamp, first.body), first.header.Get(WebhookSignatureHeader))
+	}
+
+	deliveries, err := webhooks.GetDeliveries(everything.ID, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 2) {
+		assert.Equal(t, EventSessionCompleted, deliveries[0].EventType)
+		assert.Equal(t, DeliveryDelivered, deliveries[0].Status)
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.Equal(t, http.StatusOK, *deliveries[0].LastStatus)
+		assert.NotNil(t, deliveries[0].DeliveredAt)
+		assert.Nil(t, deliveries[0].NextAttemptAt)
+	}
+
+	// Nothing is left to deliver
+	attempted, err = webhooks.DeliverDue(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Zero(t, attempted)
+
+	// Deleting a webhook drops its deliveries
+	deleted, err := webhooks.DeleteWebhook(everything.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deliveries, err = webhooks.GetDeliveries(everything.ID, 10)
+	assert.NoError(t, err)
+	assert.Nil(t, deliveries)
+
+	list, err := webhooks.GetWebhooks()
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, []string{EventSessionCompleted}, list[0].Events)
+		assert.Empty(t, list[0].Secret, "secrets are not listed")
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	server, received := webhookReceiver(t, http.StatusInternalServerError, http.StatusBadGateway)
+	webhooks := NewWebhookService(db)
+	webhooks.SetDeliveryPolicy(3, time.Second)
+	hook, err := webhooks.CreateWebhook(nil, server.URL, nil, "")
+	assert.NoError(t, err)
+	_, err = webhooks.Enqueue(Event{ID: 1, Type: EventWordCreated})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	_, err = webhooks.DeliverDue(context.Background(), now)
+	assert.NoError(t, err)
+	deliveries, err := webhooks.GetDeliveries(hook.ID, 10)
+	assert.NoError(t, err)
+	failed := deliveries[0]
+	assert.Equal(t, DeliveryPending, failed.Status)
+	assert.Equal(t, http.StatusInternalServerError, *failed.LastStatus)
+	assert.Contains(t, *failed.LastError, "unexpected status 500")
+	assert.WithinDuration(t, now.Add(webhookRetryDelay), *failed.NextAttemptAt, time.Second)
+
+	// The retry is not due before the backoff has elapsed
+	attempted, err := webhooks.DeliverDue(context.Background(), now.Add(webhookRetryDelay/2))
+	assert.NoError(t, err)
+	assert.Zero(t, attempted)
+
+	now = now.Add(webhookRetryDelay)
+	_, err = webhooks.DeliverDue(context.Background(), now)
+	assert.NoError(t, err)
+	deliveries, err = webhooks.GetDeliveries(hook.ID, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.WithinDuration(t, now.Add(2*webhookRetryDelay), *deliveries[0].NextAttemptAt, time.Second)
+
+	_, err = webhooks.DeliverDue(context.Background(), now.Add(2*webhookRetryDelay))
+	assert.NoError(t, err)
+	deliveries, err = webhooks.GetDeliveries(hook.ID, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Nil(t, deliveries[0].LastError)
+	assert.Len(t, received(), 3)
+
+	// A receiver that never answers successfully is given up on
+	server.Close()
+	_, err = webhooks.Enqueue(Event{ID: 2, Type: EventWordDeleted})
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = webhooks.DeliverDue(context.Background(), now.Add(time.Duration(i)*webhookMaxRetryDelay))
+		assert.NoError(t, err)
+	}
+	deliveries, err = webhooks.GetDeliveries(hook.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, DeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Nil(t, deliveries[0].LastStatus)
+	assert.Nil(t, deliveries[0].NextAttemptAt)
+
+	assert.Equal(t, time.Hour, retryDelay(20))
+}
+
+func TestWebhookDispatcherDeliversPublishedEvents(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	server, received := webhookReceiver(t)
+	webhooks := NewWebhookService(db)
+	_, err := webhooks.CreateWebhook(nil, server.URL, []string{EventWordCreated}, "")
+	assert.NoError(t, err)
+
+	bus := NewEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go webhooks.RunDispatcher(ctx, bus, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	words := NewWordService(db)
+	words.SetEventBus(bus)
+	// The dispatcher subscribes asynchronously; publish until it is listening
+	created := 0
+	assert.Eventually(t, func() bool {
+		created++
+		term := fmt.Sprintf("amare-%d", created)
+		if _, err := words.CreateWord(nil, WordInput{Term: term, Translation: "to love"}); err != nil {
+			return false
+		}
+		return len(received()) > 0
+	}, 2*time.Second, 20*time.Millisecond)
+
+	request := received()[0]
+	assert.Equal(t, EventWordCreated, request.header.Get(WebhookEventHeader))
+	assert.Contains(t, string(request.body), `"translation":"to love"`)
+}
+
+func TestWebhookDispatcherQueuesWhileDelivering(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	// The receiver hangs until the test ends
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	webhooks := NewWebhookService(db)
+	webhooks.SetDeliveryPolicy(3, time.Minute)
+	_, err := webhooks.CreateWebhook(nil, server.URL, nil, "")
+	assert.NoError(t, err)
+
+	bus := NewEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go webhooks.RunDispatcher(ctx, bus, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	queued := func() int {
+		var n int
+		assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM webhook_deliveries").Scan(&n))
+		return n
+	}
+	// The dispatcher subscribes asynchronously; publish until it is listening
+	published := 0
+	assert.Eventually(t, func() bool {
+		bus.Publish(Event{Type: EventWordCreated})
+		published++
+		return queued() > 0
+	}, 2*time.Second, 20*time.Millisecond)
+
+	// Events keep being queued while a delivery is in flight, however many
+	for i := 0; i < 3*subscriberBuffer; i++ {
+		bus.Publish(Event{Type: EventWordCreated})
+		published++
+	}
+	assert.Eventually(t, func() bool { return queued() == published }, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	assert.Equal(t, "amare", truncateUTF8("amare", 5))
+	assert.Equal(t, "am", truncateUTF8("amare", 2))
+	assert.Equal(t, "rē", truncateUTF8("rēx", 3))
+	assert.Equal(t, "r", truncateUTF8("rēx", 2), "ē is not split")
+}
@@ -2,47 +2,173 @@ package service
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+
+	apperrors "lang-portal/internal/errors"
 )
 
 type WordService struct {
-	db *sql.DB
+	instrumented
+
+	db      *sql.DB
+	mastery MasteryConfig
+	events  *EventBus
 }
 
 type Word struct {
-	ID                int             `json:"id"`
-	LatinWord         string          `json:"latin_word"`
-	EnglishTranslation string         `json:"english_translation"`
-	Parts            string          `json:"parts"`
-	CorrectCount     int             `json:"correct_count"`
-	WrongCount       int             `json:"wrong_count"`
+	ID           int          `json:"id"`
+	Language     string       `json:"language"`
+	Term         string       `json:"term"`
+	Translation  string       `json:"translation"`
+	Reading      *string      `json:"reading,omitempty"`
+	Romanization *string      `json:"romanization,omitempty"`
+	Parts        string       `json:"parts"`
+	CorrectCount int          `json:"correct_count"`
+	WrongCount   int          `json:"wrong_count"`
+	Mastery      MasteryLevel `json:"mastery"`
+	Learned      bool         `json:"learned"`
+	Senses       []Sense      `json:"senses,omitempty"`
+	Examples     []Example    `json:"examples,omitempty"`
+	Audio        []AudioClip  `json:"audio,omitempty"`
+	Images       []WordImage  `json:"images,omitempty"`
+	Tags         []string     `json:"tags,omitempty"`
+}
+
+// WordInput holds the editable fields of a word. An empty Language means
+// DefaultLanguage. When Senses are given the translation is the gloss of the
+// first one; otherwise Translation becomes the only sense. Tags replace those
+// of the word.
+type WordInput struct {
+	Language     string
+	Term         string
+	Translation  string
+	Reading      *string
+	Romanization *string
+	Parts        json.RawMessage
+	Senses       []Sense
+	Examples     []Example
+	Tags         []string
+}
+
+// WordFilter narrows the words returned by GetWords. Zero values match
+// everything; words must carry all of Tags.
+type WordFilter struct {
+	Language string
+	Tags     []string
 }
 
 type WordPagination struct {
-	Items         []Word `json:"items"`
-	CurrentPage   int    `json:"current_page"`
-	TotalPages    int    `json:"total_pages"`
-	TotalItems    int    `json:"total_items"`
-	ItemsPerPage  int    `json:"items_per_page"`
+	Items        []Word `json:"items"`
+	CurrentPage  int    `json:"current_page"`
+	TotalPages   int    `json:"total_pages"`
+	TotalItems   int    `json:"total_items"`
+	ItemsPerPage int    `json:"items_per_page"`
 }
 
 func NewWordService(db *sql.DB) *WordService {
-	return &WordService{db: db}
+	return &WordService{db: db, mastery: DefaultMasteryConfig(), instrumented: instrumented{observer: nopObserver{}}}
+}
+
+// SetMasteryConfig overrides the thresholds used to classify words
+func (s *WordService) SetMasteryConfig(cfg MasteryConfig) {
+	s.mastery = cfg
+}
+
+// SetEventBus publishes word events on bus
+func (s *WordService) SetEventBus(bus *EventBus) {
+	s.events = bus
+}
+
+// applyMastery fills in the mastery level of each word from its review history
+func (s *WordService) applyMastery(words []Word) error {
+	if len(words) == 0 {
+		return nil
+	}
+	ids := make([]int, len(words))
+	for i, w := range words {
+		ids[i] = w.ID
+	}
+	history, err := loadReviewHistory(s.db, ids, "")
+	if err != nil {
+		return err
+	}
+	for i := range words {
+		words[i].Mastery = s.mastery.Level(history[words[i].ID])
+		words[i].Learned = words[i].Mastery.IsLearned()
+	}
+	return nil
+}
+
+// applyTagsAndImages fills in the tags and pictures of each word
+func (s *WordService) applyTagsAndImages(words []Word) error {
+	if len(words) == 0 {
+		return nil
+	}
+	ids := make([]int, len(words))
+	for i, w := range words {
+		ids[i] = w.ID
+	}
+	tags, err := loadTags(s.db, ids)
+	if err != nil {
+		return err
+	}
+	images, err := loadImages(s.db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range words {
+		words[i].Tags = tags[words[i].ID]
+		words[i].Images = images[words[i].ID]
+	}
+	return nil
+}
+
+// wordColumns are the columns scanned by scanWord, followed by the review
+// counts
+const wordColumns = `
+	w.id, w.language_code, w.term, w.translation, w.reading, w.romanization, w.parts,
+	COUNT(CASE WHEN wri.correct = 1 THEN 1 END) as correct_count,
+	COUNT(CASE WHEN wri.correct = 0 THEN 1 END) as wrong_count`
+
+// scanWord scans a row selected with wordColumns
+func scanWord(row interface{ Scan(...interface{}) error }) (Word, error) {
+	var w Word
+	var reading, romanization sql.NullString
+	err := row.Scan(&w.ID, &w.Language, &w.Term, &w.Translation, &reading, &romanization,
+		&w.Parts, &w.CorrectCount, &w.WrongCount)
+	w.Reading = nullStringPtr(reading)
+	w.Romanization = nullStringPtr(romanization)
+	return w, err
 }
 
 // GetWords retrieves a paginated list of words with their study statistics
-func (s *WordService) GetWords(page, itemsPerPage int) (*WordPagination, error) {
+func (s *WordService) GetWords(page, itemsPerPage int, filter WordFilter) (*WordPagination, error) {
+	defer timeQuery(s.observer, "WordService.GetWords")()
+
 	offset := (page - 1) * itemsPerPage
 
+	where := "w.deleted_at IS NULL"
+	var args []interface{}
+	if filter.Language != "" {
+		where += " AND w.language_code = ?"
+		args = append(args, filter.Language)
+	}
+	if len(filter.Tags) > 0 {
+		tagged, tagArgs := taggedWords(filter.Tags)
+		where += " AND w.id IN (" + tagged + ")"
+		args = append(args, tagArgs...)
+	}
+
 	query := `
-		SELECT w.id, w.latin_word, w.english_translation, w.parts,
-			   COUNT(CASE WHEN wri.correct = 1 THEN 1 END) as correct_count,
-			   COUNT(CASE WHEN wri.correct = 0 THEN 1 END) as wrong_count
+		SELECT ` + wordColumns + `
 		FROM words w
 		LEFT JOIN word_review_items wri ON w.id = wri.word_id
+		WHERE ` + where + `
 		GROUP BY w.id
 		LIMIT ? OFFSET ?`
 
-	rows, err := s.db.Query(query, itemsPerPage, offset)
+	rows, err := s.db.Query(query, append(args, itemsPerPage, offset)...)
 	if err != nil {
 		return nil, err
 	}
@@ -50,17 +176,22 @@ func (s *WordService) GetWords(page, itemsPerPage int) (*WordPagination, error)
 
 	var words []Word
 	for rows.Next() {
-		var w Word
-		if err := rows.Scan(&w.ID, &w.LatinWord, &w.EnglishTranslation, &w.Parts,
-			&w.CorrectCount, &w.WrongCount); err != nil {
+		w, err := scanWord(rows)
+		if err != nil {
 			return nil, err
 		}
 		words = append(words, w)
 	}
+	if err := s.applyMastery(words); err != nil {
+		return nil, err
+	}
+	if err := s.applyTagsAndImages(words); err != nil {
+		return nil, err
+	}
 
 	// Get total count
 	var totalItems int
-	err = s.db.QueryRow("SELECT COUNT(*) FROM words").Scan(&totalItems)
+	err = s.db.QueryRow("SELECT COUNT(*) FROM words w WHERE "+where, args...).Scan(&totalItems)
 	if err != nil {
 		return nil, err
 	}
@@ -76,27 +207,186 @@ func (s *WordService) GetWords(page, itemsPerPage int) (*WordPagination, error)
 	}, nil
 }
 
-// GetWordByID retrieves a single word by its ID
+// GetWordByID retrieves a single word by its ID, with its senses, examples,
+// audio, images and tags
 func (s *WordService) GetWordByID(id int) (*Word, error) {
+	defer timeQuery(s.observer, "WordService.GetWordByID")()
+
 	query := `
-		SELECT w.id, w.latin_word, w.english_translation, w.parts,
-			   COUNT(CASE WHEN wri.correct = 1 THEN 1 END) as correct_count,
-			   COUNT(CASE WHEN wri.correct = 0 THEN 1 END) as wrong_count
+		SELECT ` + wordColumns + `
 		FROM words w
 		LEFT JOIN word_review_items wri ON w.id = wri.word_id
-		WHERE w.id = ?
+		WHERE w.id = ? AND w.deleted_at IS NULL
 		GROUP BY w.id`
 
-	var word Word
-	err := s.db.QueryRow(query, id).Scan(
-		&word.ID, &word.LatinWord, &word.EnglishTranslation, &word.Parts,
-		&word.CorrectCount, &word.WrongCount,
-	)
+	word, err := scanWord(s.db.QueryRow(query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &word, nil
+
+	if word.Senses, err = loadSenses(s.db, id); err != nil {
+		return nil, err
+	}
+	if word.Examples, err = loadExamples(s.db, id); err != nil {
+		return nil, err
+	}
+	if word.Audio, err = loadAudio(s.db, id); err != nil {
+		return nil, err
+	}
+	words := []Word{word}
+	if err := s.applyMastery(words); err != nil {
+		return nil, err
+	}
+	if err := s.applyTagsAndImages(words); err != nil {
+		return nil, err
+	}
+	return &words[0], nil
+}
+
+// CreateWord adds a word on behalf of actor
+func (s *WordService) CreateWord(actor *User, input WordInput) (*Word, error) {
+	defer timeQuery(s.observer, "WordService.CreateWord")()
+
+	language := languageOrDefault(input.Language)
+	senses := sensesOrDefault(input)
+	id, err := auditedChange(s.db, actor, AuditEntityWord, AuditCreate, 0, func(tx *sql.Tx) (int, error) {
+		if err := requireLanguage(tx, language); err != nil {
+			return 0, err
+		}
+		result, err := tx.Exec(`
+			INSERT INTO words (language_code, term, translation, reading, romanization, parts)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			language, input.Term, senses[0].Gloss, input.Reading, input.Romanization,
+			partsJSON(input.Parts),
+		)
+		if err != nil {
+			return 0, translateDBError(tx, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		return int(id), writeWordDetails(tx, int(id), senses, input.Examples, input.Tags)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.wordChanged(actor, EventWordCreated, id)
+}
+
+// UpdateWord replaces the editable fields of a word on behalf of actor. It
+// returns nil if the word does not exist or is in the trash.
+func (s *WordService) UpdateWord(actor *User, id int, input WordInput) (*Word, error) {
+	defer timeQuery(s.observer, "WordService.UpdateWord")()
+
+	language := languageOrDefault(input.Language)
+	senses := sensesOrDefault(input)
+	_, err := auditedChange(s.db, actor, AuditEntityWord, AuditUpdate, id, func(tx *sql.Tx) (int, error) {
+		if err := requireLanguage(tx, language); err != nil {
+			return 0, err
+		}
+		if err := requireNoGroupsOutside(tx, id, language); err != nil {
+			return 0, err
+		}
+		updated, err := affectsRow(tx.Exec(`
+			UPDATE words
+			SET language_code = ?, term = ?, translation = ?, reading = ?, romanization = ?, parts = ?
+			WHERE id = ? AND deleted_at IS NULL`,
+			language, input.Term, senses[0].Gloss, input.Reading, input.Romanization,
+			partsJSON(input.Parts), id,
+		))
+		if err != nil {
+			return 0, translateDBError(tx, err)
+		}
+		if !updated {
+			return 0, errNoChange
+		}
+		return id, writeWordDetails(tx, id, senses, input.Examples, input.Tags)
+	})
+	if errors.Is(err, errNoChange) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.wordChanged(actor, EventWordUpdated, id)
+}
+
+// DeleteWord moves a word to the trash on behalf of actor. Its reviews and
+// group memberships are kept so that restoring it loses nothing. It reports
+// false if the word does not exist or is already in the trash.
+func (s *WordService) DeleteWord(actor *User, id int) (bool, error) {
+	defer timeQuery(s.observer, "WordService.DeleteWord")()
+
+	_, err := auditedChange(s.db, actor, AuditEntityWord, AuditDelete, id, func(tx *sql.Tx) (int, error) {
+		deleted, err := softDelete(tx, "words", id)
+		if err == nil && !deleted {
+			err = errNoChange
+		}
+		return id, err
+	})
+	if errors.Is(err, errNoChange) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.events.Publish(Event{Type: EventWordDeleted, UserID: actorUserID(actor), Data: map[string]int{"id": id}})
+	return true, nil
+}
+
+// wordChanged loads a created or updated word and publishes the change
+func (s *WordService) wordChanged(actor *User, eventType string, id int) (*Word, error) {
+	word, err := s.GetWordByID(id)
+	if err != nil || word == nil {
+		return word, err
+	}
+	s.events.Publish(Event{Type: eventType, UserID: actorUserID(actor), Data: word})
+	return word, nil
+}
+
+// writeWordDetails replaces the senses, examples and tags of a word
+func writeWordDetails(q queryer, wordID int, senses []Sense, examples []Example, tags []string) error {
+	if err := replaceSenses(q, wordID, senses); err != nil {
+		return err
+	}
+	if err := replaceExamples(q, wordID, examples); err != nil {
+		return err
+	}
+	return replaceTags(q, wordID, tags)
+}
+
+// partsJSON returns the text stored in words.parts, defaulting to an empty
+// object
+func partsJSON(parts json.RawMessage) string {
+	if len(parts) == 0 {
+		return "{}"
+	}
+	return string(parts)
+}
+
+// requireNoGroupsOutside rejects moving a word to another language while it
+// still belongs to groups of its current one
+func requireNoGroupsOutside(q queryer, wordID int, language string) error {
+	var groupLanguage sql.NullString
+	err := q.QueryRow(`
+		SELECT g.language_code
+		FROM words_groups wg
+		JOIN groups g ON g.id = wg.group_id
+		WHERE wg.word_id = ? AND g.language_code != ?
+		LIMIT 1`, wordID, language,
+	).Scan(&groupLanguage)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return apperrors.New(apperrors.CodeLanguageMismatch).WithData(map[string]string{
+		"word_language":  language,
+		"group_language": groupLanguage.String,
+	})
 }
@@ -5,12 +5,13 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/magefile/mage/mg"
 	"github.com/magefile/mage/sh"
 	_ "github.com/mattn/go-sqlite3"
+	"lang-portal/internal/migrate"
 	"lang-portal/internal/seeder"
+	"lang-portal/internal/service"
 )
 
 // Default target to run when none is specified
@@ -38,7 +39,7 @@ func Clean() error {
 	return nil
 }
 
-// InitDB creates a new SQLite database and runs all migrations
+// InitDB creates a new SQLite database and runs pending migrations
 func InitDB() error {
 	mg.Deps(ensureDir)
 	fmt.Println("Initializing database...")
@@ -49,23 +50,13 @@ func InitDB() error {
 	}
 	defer db.Close()
 
-	// Read and execute migration files
-	migrations, err := filepath.Glob("db/migrations/*.sql")
-	if err != nil {
-		return fmt.Errorf("failed to find migration files: %v", err)
+	// Apply the migrations that have not run against this database yet
+	applied, err := migrate.Apply(db, "db/migrations")
+	for _, name := range applied {
+		fmt.Printf("Applied migration: %s\n", name)
 	}
-
-	for _, migration := range migrations {
-		fmt.Printf("Executing migration: %s\n", migration)
-		content, err := os.ReadFile(migration)
-		if err != nil {
-			return fmt.Errorf("failed to read migration file %s: %v", migration, err)
-		}
-
-		_, err = db.Exec(string(content))
-		if err != nil {
-			return fmt.Errorf("failed to execute migration %s: %v", migration, err)
-		}
+	if err != nil {
+		return err
 	}
 
 	fmt.Println("Database initialization complete")
@@ -101,11 +92,48 @@ func Reset() error {
 	return nil
 }
 
+// Admin creates an admin named name and prints their token. It is how the
+// first admin is created, since only admins may create users through the API.
+func Admin(name string) error {
+	db, err := sql.Open("sqlite3", dbFile)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	user, err := service.NewUserService(db).CreateUser(name, service.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("failed to create admin: %v", err)
+	}
+	fmt.Printf("Token for %s (id %d): %s\n", user.Name, user.ID, user.Token)
+	return nil
+}
+
+// Token issues a new bearer token to the user with the given id, replacing
+// any token they had
+func Token(id int) error {
+	db, err := sql.Open("sqlite3", dbFile)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	user, err := service.NewUserService(db).IssueToken(id)
+	if err != nil {
+		return fmt.Errorf("failed to issue token: %v", err)
+	}
+	if user == nil {
+		return fmt.Errorf("no user with id %d", id)
+	}
+	fmt.Printf("Token for %s: %s\n", user.Name, user.Token)
+	return nil
+}
+
 // Run starts the server
 func Run() error {
 	mg.Deps(InitDB)
 	fmt.Println("Starting server on :8081...")
-	return sh.Run("go", "run", "cmd/server/main.go")
+	return sh.Run("go", "run", "./cmd/server")
 }
 
 // ensureDir ensures required directories exist